@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+//********************************************************************//
+//  							 ANALYTICS							  //
+//********************************************************************//
+
+// getAnalyticsOverview godoc
+// @Summary Помесячные MRR, ARR, новые и ушедшие подписки
+// @Tags analytics
+// @Produce json
+// @Param from query string false "Начало периода (формат: MM-YYYY), по умолчанию 11 месяцев назад" example:"01-2025"
+// @Param to query string false "Конец периода (формат: MM-YYYY), по умолчанию текущий месяц; период не длиннее 120 месяцев" example:"12-2025"
+// @Param service_name query string false "Название сервиса или его псевдоним из каталога" example:"Netflix"
+// @Param user_id query string false "UUID пользователя; совместные подписки учитываются его долей" example:"550e8400-e29b-41d4-a716-446655440000"
+// @Success 200 {array} models.AnalyticsMonth
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /analytics/overview [get]
+func (app *application) getAnalyticsOverview(c *gin.Context) {
+	in, ok := app.analyticsInput(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Analytics(in.subs, in.from, in.to, in.uid))
+}
+
+// getAnalyticsByService godoc
+// @Summary Помесячные MRR, ARR, новые и ушедшие подписки по каждому сервису
+// @Tags analytics
+// @Produce json
+// @Param from query string false "Начало периода (формат: MM-YYYY), по умолчанию 11 месяцев назад" example:"01-2025"
+// @Param to query string false "Конец периода (формат: MM-YYYY), по умолчанию текущий месяц; период не длиннее 120 месяцев" example:"12-2025"
+// @Param service_name query string false "Название сервиса или его псевдоним из каталога" example:"Netflix"
+// @Param user_id query string false "UUID пользователя; совместные подписки учитываются его долей" example:"550e8400-e29b-41d4-a716-446655440000"
+// @Success 200 {array} models.ServiceAnalytics
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /analytics/services [get]
+func (app *application) getAnalyticsByService(c *gin.Context) {
+	in, ok := app.analyticsInput(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AnalyticsByService(in.subs, in.from, in.to, in.uid))
+}
+
+//********************************************************************//
+//  							 HELPERS							  //
+//********************************************************************//
+
+// analyticsRequest — подписки и период аналитики; uid задан, если она
+// считается для одного пользователя.
+type analyticsRequest struct {
+	subs     []*models.Subscription
+	from, to time.Time
+	uid      *uuid.UUID
+}
+
+// analyticsInput разбирает общие параметры аналитики и загружает подписки.
+// При ошибке ответ уже записан и возвращается ok == false.
+func (app *application) analyticsInput(c *gin.Context) (analyticsRequest, bool) {
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if toStr := c.Query("to"); toStr != "" {
+		t, err := time.Parse("01-2006", toStr)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid to date format. Use MM-YYYY")
+			return analyticsRequest{}, false
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, -11, 0)
+	if fromStr := c.Query("from"); fromStr != "" {
+		t, err := time.Parse("01-2006", fromStr)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid from date format. Use MM-YYYY")
+			return analyticsRequest{}, false
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		errorResponse(c, http.StatusBadRequest, "from must not be after to")
+		return analyticsRequest{}, false
+	}
+	if periodTooLong(from, to) {
+		errorResponse(c, http.StatusBadRequest, periodTooLongMessage)
+		return analyticsRequest{}, false
+	}
+
+	in := analyticsRequest{from: from, to: to}
+	userID := c.Query("user_id")
+	if userID != "" {
+		var id uuid.UUID
+		if err := id.Scan(userID); err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid user ID")
+			return analyticsRequest{}, false
+		}
+		in.uid = &id
+	}
+
+	ctx, cancel := app.queryContext(c, reportQuery)
+	defer cancel()
+
+	var err error
+	serviceName := c.Query("service_name")
+	if serviceName != "" {
+		if serviceName, err = app.canonicalServiceName(ctx, serviceName); err != nil {
+			queryFailed(c, ctx, http.StatusInternalServerError, "Failed to resolve service")
+			return analyticsRequest{}, false
+		}
+	}
+
+	switch {
+	case userID != "":
+		in.subs, err = app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{UserID: userID, ServiceName: serviceName})
+	case serviceName != "":
+		in.subs, err = app.allModels.Subscriptions.GetByUserSubscription(ctx, serviceName)
+	default:
+		in.subs, err = app.allModels.Subscriptions.GetAll(ctx)
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to calculate analytics")
+		return analyticsRequest{}, false
+	}
+
+	return in, true
+}
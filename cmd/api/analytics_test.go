@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"subscription-service/internal/config"
+	"testing"
+)
+
+func TestAnalyticsPeriodLimit(t *testing.T) {
+	h, _ := newTestApp(t, config.Default())
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"from=01-2016&to=12-2025", http.StatusOK},
+		{"from=12-2015&to=12-2025", http.StatusBadRequest},
+		{"from=01-0001&to=12-9999", http.StatusBadRequest},
+		{"from=02-2025&to=01-2025", http.StatusBadRequest},
+	}
+	for _, path := range []string{"/api/subscriptions/analytics/overview", "/api/subscriptions/analytics/services"} {
+		for _, tc := range tests {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"?"+tc.query, nil))
+			if rec.Code != tc.want {
+				t.Errorf("%s?%s: want %d, got %d: %s", path, tc.query, tc.want, rec.Code, rec.Body)
+			}
+			if tc.query == "from=01-0001&to=12-9999" && !strings.Contains(rec.Body.String(), "120 months") {
+				t.Errorf("%s?%s: want the period limit in the error, got %s", path, tc.query, rec.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type apiKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// OrganizationID задаёт только ключ платформы; без него создаётся
+	// ключ платформы. Администратор организации создаёт ключи своей.
+	OrganizationID *int `json:"organization_id"`
+}
+
+type apiKeyResponse struct {
+	models.APIKey
+	// Key показывается только в ответе на создание.
+	Key string `json:"key"`
+}
+
+//********************************************************************//
+//  							 API KEYS							  //
+//********************************************************************//
+
+// listAPIKeys godoc
+// @Summary Получить список API-ключей
+// @Description Требует область admin. Администратор организации видит только её ключи, ключ платформы — все. Сами ключи не возвращаются, только их префиксы
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string][]models.APIKey
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /admin/api-keys [get]
+func (app *application) listAPIKeys(c *gin.Context) {
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	keys, err := app.allModels.APIKeys.GetAll(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// createAPIKey godoc
+// @Summary Создать API-ключ для сервисного клиента
+// @Description Требует область admin. Ключ возвращается один раз, в базе хранится только его хеш. Администратор организации создаёт ключи только своей организации; ключ платформы указывает organization_id или создаёт ключ платформы
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param key body apiKeyRequest true "Имя, области (read, write, admin) и срок действия"
+// @Success 201 {object} apiKeyResponse
+// @Failure 400 {object} map[string]string "Неверный запрос или организация не найдена"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin или чужая организация"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /admin/api-keys [post]
+func (app *application) createAPIKey(c *gin.Context) {
+	var req apiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := models.ValidScopes(req.Scopes); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		errorResponse(c, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	org, all, err := models.OrganizationFrom(ctx)
+	if err != nil {
+		errorResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+	switch {
+	case !all && req.OrganizationID != nil && *req.OrganizationID != org:
+		errorResponse(c, http.StatusForbidden, "Cannot create API keys for another organization")
+		return
+	case !all:
+		req.OrganizationID = &org
+	case req.OrganizationID != nil:
+		_, err := app.allModels.Organizations.Get(ctx, *req.OrganizationID)
+		if errors.Is(err, sql.ErrNoRows) {
+			errorResponse(c, http.StatusBadRequest, "organization does not exist")
+			return
+		}
+		if err != nil {
+			queryFailed(c, ctx, http.StatusInternalServerError, "Failed to resolve organization")
+			return
+		}
+	}
+
+	plaintext, key, err := models.NewAPIKey(req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "Failed to generate API key")
+		return
+	}
+	key.OrganizationID = req.OrganizationID
+
+	if err := app.allModels.APIKeys.Insert(ctx, key); err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKeyResponse{APIKey: *key, Key: plaintext})
+}
+
+// revokeAPIKey godoc
+// @Summary Отозвать API-ключ
+// @Description Требует область admin. Ключ перестаёт приниматься сразу, запись сохраняется для аудита. Администратор организации отзывает только её ключи
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "ID ключа"
+// @Success 200 {object} map[string]string "Ключ отозван"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 404 {object} map[string]string "Ключ не найден, уже отозван или принадлежит другой организации"
+// @Failure 500 {object} map[string]string "Ошибка при отзыве"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /admin/api-keys/{id} [delete]
+func (app *application) revokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.APIKeys.Revoke(ctx, id, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(c, http.StatusNotFound, "API key not found or already revoked")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
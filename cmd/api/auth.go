@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// principalContextKey — ключ gin.Context с аутентифицированным вызывающим.
+const principalContextKey = "principal"
+
+// principal — тот, от чьего имени выполняется запрос: пользователь из
+// bearer-токена или сервисный клиент с API-ключом. OrgID — организация
+// вызывающего; nil только у ключей платформы.
+type principal struct {
+	Kind   string
+	ID     string
+	Scopes []string
+	OrgID  *int
+}
+
+func (p principal) caller() string {
+	return p.Kind + ":" + p.ID
+}
+
+// userClaims — содержимое bearer-токена пользователя. scope перечисляет
+// области через пробел; без него пользователь получает read и write.
+// org_id — организация пользователя; без него действует
+// auth.default_organization.
+type userClaims struct {
+	Scope string `json:"scope,omitempty"`
+	OrgID *int   `json:"org_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// authError — отказ в аутентификации или выборе организации. status —
+// HTTP-статус ответа; gRPC переводит его в свой код.
+type authError struct {
+	status  int
+	message string
+}
+
+func (e *authError) Error() string {
+	return e.message
+}
+
+// resolvePrincipal разбирает значение заголовка Authorization: ApiKey
+// или Bearer. Пустой заголовок даёт анонимного вызывающего (nil), если
+// auth.required выключен. Отказ — *authError, прочие ошибки — сбой БД
+// при проверке ключа; ctx ограничивает запрос к БД.
+func (app *application) resolvePrincipal(ctx context.Context, header string) (*principal, error) {
+	if header == "" {
+		if app.config.Auth.Required {
+			return nil, &authError{http.StatusUnauthorized, "Authorization header is required"}
+		}
+		return nil, nil
+	}
+
+	scheme, credentials, _ := strings.Cut(header, " ")
+	credentials = strings.TrimSpace(credentials)
+
+	switch strings.ToLower(scheme) {
+	case "apikey":
+		key, err := app.allModels.APIKeys.Authenticate(ctx, credentials, time.Now())
+		if errors.Is(err, models.ErrInvalidAPIKey) {
+			return nil, &authError{http.StatusUnauthorized, "Invalid API key"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &principal{Kind: "key", ID: strconv.Itoa(key.ID), Scopes: key.Scopes, OrgID: key.OrganizationID}, nil
+
+	case "bearer":
+		claims, err := app.parseBearer(credentials)
+		if err != nil {
+			slog.DebugContext(ctx, "invalid bearer token", "error", err)
+			return nil, &authError{http.StatusUnauthorized, "Invalid bearer token"}
+		}
+		scopes := strings.Fields(claims.Scope)
+		if len(scopes) == 0 {
+			scopes = []string{models.ScopeRead, models.ScopeWrite}
+		}
+		org := claims.OrgID
+		if org == nil {
+			if app.config.Auth.DefaultOrganization == 0 {
+				return nil, &authError{http.StatusUnauthorized, "Bearer token has no org_id"}
+			}
+			org = &app.config.Auth.DefaultOrganization
+		}
+		return &principal{Kind: "user", ID: claims.Subject, Scopes: scopes, OrgID: org}, nil
+
+	default:
+		return nil, &authError{http.StatusUnauthorized, "Unsupported authorization scheme, use Bearer or ApiKey"}
+	}
+}
+
+// authenticate принимает "Authorization: ApiKey <key>" и
+// "Authorization: Bearer <jwt>". Неверные учётные данные — всегда 401;
+// запрос без заголовка проходит анонимно, если auth.required выключен.
+func (app *application) authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := app.queryContext(c, writeQuery)
+		defer cancel()
+
+		p, err := app.resolvePrincipal(ctx, c.GetHeader("Authorization"))
+		var authErr *authError
+		if errors.As(err, &authErr) {
+			unauthorized(c, authErr.message)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "authenticate api key", "error", err)
+			queryFailed(c, ctx, http.StatusInternalServerError, "Failed to verify API key")
+			c.Abort()
+			return
+		}
+
+		if p != nil {
+			c.Set(principalContextKey, *p)
+			c.Set(callerContextKey, p.caller())
+		}
+		c.Next()
+	}
+}
+
+func (app *application) parseBearer(token string) (*userClaims, error) {
+	if app.config.Auth.JWTSecret == "" {
+		return nil, errors.New("bearer tokens are not configured")
+	}
+
+	var claims userClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return []byte(app.config.Auth.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if claims.OrgID != nil && *claims.OrgID <= 0 {
+		return nil, errors.New("token has invalid org_id")
+	}
+	return &claims, nil
+}
+
+// organizationHeader выбирает организацию, от имени которой действует
+// ключ платформы. Без него ключ платформы видит все организации.
+const organizationHeader = "X-Organization-ID"
+
+// resolveTenant выбирает организацию запроса по вызывающему и значению
+// заголовка организации; 0 — все организации, это доступно только ключу
+// платформы. Вызывающий с организацией может указать в заголовке только
+// её. Отказ — *authError, прочие ошибки — сбой БД при проверке
+// организации; ctx ограничивает запрос к БД.
+func (app *application) resolveTenant(ctx context.Context, p *principal, header string) (int, error) {
+	switch {
+	case p == nil:
+		if app.config.Auth.DefaultOrganization == 0 {
+			return 0, &authError{http.StatusUnauthorized, "Authentication is required to select an organization"}
+		}
+		return app.config.Auth.DefaultOrganization, nil
+
+	case p.OrgID != nil:
+		if header != "" && header != strconv.Itoa(*p.OrgID) {
+			return 0, &authError{http.StatusForbidden, "Credentials belong to another organization"}
+		}
+		return *p.OrgID, nil
+
+	case header == "":
+		return 0, nil
+	}
+
+	org, err := strconv.Atoi(header)
+	if err != nil || org <= 0 {
+		return 0, &authError{http.StatusBadRequest, "Invalid " + organizationHeader}
+	}
+	_, err = app.allModels.Organizations.Get(ctx, org)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, &authError{http.StatusNotFound, "Organization not found"}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return org, nil
+}
+
+// withTenant кладёт в ctx организацию, выбранную resolveTenant.
+func withTenant(ctx context.Context, org int) context.Context {
+	if org == 0 {
+		return models.WithAllOrganizations(ctx)
+	}
+	return models.WithOrganization(ctx, org)
+}
+
+// tenant определяет арендатора запроса и кладёт его в контекст запроса:
+// из него арендатора берут все запросы к подпискам.
+func (app *application) tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var p *principal
+		if v, ok := c.Get(principalContextKey); ok {
+			pp := v.(principal)
+			p = &pp
+		}
+
+		qctx, cancel := app.queryContext(c, readQuery)
+		defer cancel()
+
+		org, err := app.resolveTenant(qctx, p, c.GetHeader(organizationHeader))
+		var authErr *authError
+		if errors.As(err, &authErr) {
+			if authErr.status == http.StatusUnauthorized {
+				unauthorized(c, authErr.message)
+				return
+			}
+			errorResponse(c, authErr.status, authErr.message)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			queryFailed(c, qctx, http.StatusInternalServerError, "Failed to resolve organization")
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(withTenant(c.Request.Context(), org))
+		c.Next()
+	}
+}
+
+// authorize проверяет область доступа: чтение требует read, изменения —
+// write. Анонимный запрос сюда доходит, только если auth.required выключен.
+func authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := models.ScopeRead
+		if classify(c) != readRoutes {
+			scope = models.ScopeWrite
+		}
+		checkScope(c, scope, false)
+	}
+}
+
+// requireScope пускает только аутентифицированных вызывающих с областью scope.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checkScope(c, scope, true)
+	}
+}
+
+// requirePlatform пускает только ключи платформы. Им доступно общее для
+// всех организаций: сами организации, каталог сервисов и категории.
+func requirePlatform() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get(principalContextKey)
+		if !ok {
+			unauthorized(c, "Authentication is required")
+			return
+		}
+		if v.(principal).OrgID != nil {
+			errorResponse(c, http.StatusForbidden, "Platform API key is required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func checkScope(c *gin.Context, scope string, authRequired bool) {
+	v, ok := c.Get(principalContextKey)
+	if !ok {
+		if authRequired {
+			unauthorized(c, "Authentication is required")
+			return
+		}
+		c.Next()
+		return
+	}
+
+	if p := v.(principal); !models.HasScope(p.Scopes, scope) {
+		errorResponse(c, http.StatusForbidden, "Missing required scope: "+scope)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer, ApiKey`)
+	errorResponse(c, http.StatusUnauthorized, message)
+	c.Abort()
+}
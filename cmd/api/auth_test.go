@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"subscription-service/internal/config"
+	"subscription-service/internal/metrics"
+	"subscription-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const authTestSecret = "auth-test-secret"
+
+// newAuthTestApp собирает маршруты приложения поверх хранилищ подписок и
+// API-ключей в памяти.
+func newAuthTestApp(t *testing.T, cfg config.Config) (http.Handler, *models.MemoryAPIKeyDB, models.SubscriptionRepository) {
+	t.Helper()
+	keys := models.NewMemoryAPIKeyDB()
+	subs := models.NewMemorySubscriptionDB()
+	app := &application{
+		config: cfg,
+		timeouts: queryTimeouts{
+			readQuery:   time.Second,
+			writeQuery:  time.Second,
+			reportQuery: time.Second,
+		},
+		allModels: models.Models{Subscriptions: subs, APIKeys: keys},
+		metrics:   metrics.New(nil),
+	}
+	return app.routes(), keys, subs
+}
+
+// insertAPIKey выпускает ключ организации org (nil — ключ платформы);
+// edit меняет его до сохранения.
+func insertAPIKey(t *testing.T, keys models.APIKeyRepository, org *int, scopes []string, edit func(*models.APIKey)) (string, *models.APIKey) {
+	t.Helper()
+	plaintext, key, err := models.NewAPIKey("test", scopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.OrganizationID = org
+	if edit != nil {
+		edit(key)
+	}
+	if err := keys.Insert(t.Context(), key); err != nil {
+		t.Fatalf("Insert key: %v", err)
+	}
+	return plaintext, key
+}
+
+func signToken(t *testing.T, claims userClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(authTestSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func serve(h http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Required = true
+	h, keys, _ := newAuthTestApp(t, cfg)
+
+	org := models.DefaultOrganizationID
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	valid, _ := insertAPIKey(t, keys, &org, []string{models.ScopeRead}, nil)
+	notExpired, _ := insertAPIKey(t, keys, &org, []string{models.ScopeRead}, func(k *models.APIKey) { k.ExpiresAt = &future })
+	expired, _ := insertAPIKey(t, keys, &org, []string{models.ScopeRead}, func(k *models.APIKey) { k.ExpiresAt = &past })
+	revoked, revokedKey := insertAPIKey(t, keys, &org, []string{models.ScopeAdmin}, nil)
+	if err := keys.Revoke(models.WithAllOrganizations(t.Context()), revokedKey.ID, time.Now()); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	platform, _ := insertAPIKey(t, keys, nil, []string{models.ScopeRead}, nil)
+
+	// Тот же префикс, другой секрет: ключ находится, но хэш не совпадает.
+	mismatch := valid[:strings.LastIndex(valid, "_")+1] + strings.Repeat("a", 32)
+
+	tests := []struct {
+		name   string
+		method string
+		auth   string
+		org    string
+		want   int
+	}{
+		{"valid key", http.MethodGet, "ApiKey " + valid, "", http.StatusOK},
+		{"scheme is case-insensitive", http.MethodGet, "apikey " + valid, "", http.StatusOK},
+		{"not yet expired", http.MethodGet, "ApiKey " + notExpired, "", http.StatusOK},
+		{"expired", http.MethodGet, "ApiKey " + expired, "", http.StatusUnauthorized},
+		{"revoked", http.MethodGet, "ApiKey " + revoked, "", http.StatusUnauthorized},
+		{"hash mismatch", http.MethodGet, "ApiKey " + mismatch, "", http.StatusUnauthorized},
+		{"unknown prefix", http.MethodGet, "ApiKey sk_unknown_secret", "", http.StatusUnauthorized},
+		{"malformed key", http.MethodGet, "ApiKey " + strings.ReplaceAll(valid, "_", ""), "", http.StatusUnauthorized},
+		{"missing header", http.MethodGet, "", "", http.StatusUnauthorized},
+		{"unsupported scheme", http.MethodGet, "Basic dXNlcjpwYXNz", "", http.StatusUnauthorized},
+		{"read key cannot write", http.MethodDelete, "ApiKey " + valid, "", http.StatusForbidden},
+		{"own organization header", http.MethodGet, "ApiKey " + valid, "1", http.StatusOK},
+		{"other organization header", http.MethodGet, "ApiKey " + valid, "2", http.StatusForbidden},
+		{"platform key sees all organizations", http.MethodGet, "ApiKey " + platform, "", http.StatusOK},
+		{"platform key with bad organization header", http.MethodGet, "ApiKey " + platform, "x", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/subscriptions/all"
+			if tt.method == http.MethodDelete {
+				path = "/api/subscriptions/x"
+			}
+			header := http.Header{}
+			if tt.auth != "" {
+				header.Set("Authorization", tt.auth)
+			}
+			if tt.org != "" {
+				header.Set(organizationHeader, tt.org)
+			}
+			rec := serve(h, tt.method, path, header)
+			if rec.Code != tt.want {
+				t.Fatalf("want %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Fatal("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestAPIKeyLastUsedThrottle(t *testing.T) {
+	h, keys, _ := newAuthTestApp(t, config.Default())
+	org := models.DefaultOrganizationID
+
+	tests := []struct {
+		name     string
+		lastUsed time.Duration // 0 — ключ ещё не использовался
+		touched  bool
+	}{
+		{"never used", 0, true},
+		{"used within a minute", 30 * time.Second, false},
+		{"used a minute ago", time.Minute, true},
+		{"used long ago", time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before *time.Time
+			plaintext, key := insertAPIKey(t, keys, &org, []string{models.ScopeRead}, func(k *models.APIKey) {
+				if tt.lastUsed != 0 {
+					at := time.Now().Add(-tt.lastUsed)
+					k.LastUsedAt, before = &at, &at
+				}
+			})
+
+			start := time.Now()
+			if rec := serve(h, http.MethodGet, "/api/subscriptions/all", http.Header{"Authorization": {"ApiKey " + plaintext}}); rec.Code != http.StatusOK {
+				t.Fatalf("want 200, got %d: %s", rec.Code, rec.Body)
+			}
+
+			after := lastUsedAt(t, keys, key.ID)
+			switch {
+			case tt.touched && (after == nil || after.Before(start)):
+				t.Fatalf("last_used_at must be updated, got %v", after)
+			case !tt.touched && !after.Equal(*before):
+				t.Fatalf("last_used_at must stay %v within a minute, got %v", before, after)
+			}
+		})
+	}
+}
+
+func lastUsedAt(t *testing.T, keys models.APIKeyRepository, id int) *time.Time {
+	t.Helper()
+	all, err := keys.GetAll(models.WithAllOrganizations(t.Context()))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	for _, k := range all {
+		if k.ID == id {
+			return k.LastUsedAt
+		}
+	}
+	t.Fatalf("key %d not found", id)
+	return nil
+}
+
+func TestAuthenticateBearer(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = authTestSecret
+	h, _, subs := newAuthTestApp(t, cfg)
+	insertGraphQLSub(t, subs, 2, "Spotify", 300, graphqlUserA)
+	insertGraphQLSub(t, subs, models.DefaultOrganizationID, "Netflix", 500, graphqlUserA)
+
+	org2, zero := 2, 0
+	user := jwt.RegisteredClaims{Subject: "tester"}
+	expired := jwt.RegisteredClaims{Subject: "tester", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		org      string
+		want     int
+		wantSubs []string // сервисы в ответе GET
+	}{
+		{"org_id scopes the tenant", http.MethodGet, signToken(t, userClaims{OrgID: &org2, RegisteredClaims: user}), "", http.StatusOK, []string{"Spotify"}},
+		{"no org_id falls back to the default organization", http.MethodGet, signToken(t, userClaims{RegisteredClaims: user}), "", http.StatusOK, []string{"Netflix"}},
+		{"header of another organization", http.MethodGet, signToken(t, userClaims{OrgID: &org2, RegisteredClaims: user}), "1", http.StatusForbidden, nil},
+		{"non-positive org_id", http.MethodGet, signToken(t, userClaims{OrgID: &zero, RegisteredClaims: user}), "", http.StatusUnauthorized, nil},
+		{"read scope can read", http.MethodGet, signToken(t, userClaims{Scope: "read", RegisteredClaims: user}), "", http.StatusOK, []string{"Netflix"}},
+		{"read scope cannot write", http.MethodDelete, signToken(t, userClaims{Scope: "read", RegisteredClaims: user}), "", http.StatusForbidden, nil},
+		{"no scope means read and write", http.MethodDelete, signToken(t, userClaims{RegisteredClaims: user}), "", http.StatusBadRequest, nil},
+		{"admin scope implies write", http.MethodDelete, signToken(t, userClaims{Scope: "admin", RegisteredClaims: user}), "", http.StatusBadRequest, nil},
+		{"expired token", http.MethodGet, signToken(t, userClaims{RegisteredClaims: expired}), "", http.StatusUnauthorized, nil},
+		{"no subject", http.MethodGet, signToken(t, userClaims{}), "", http.StatusUnauthorized, nil},
+		{"forged signature", http.MethodGet, signToken(t, userClaims{RegisteredClaims: user}) + "x", "", http.StatusUnauthorized, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/subscriptions/all"
+			if tt.method == http.MethodDelete {
+				// Неверный id отклоняет уже обработчик: 400 вместо 403
+				// значит, что область write есть.
+				path = "/api/subscriptions/x"
+			}
+			header := http.Header{"Authorization": {"Bearer " + tt.token}}
+			if tt.org != "" {
+				header.Set(organizationHeader, tt.org)
+			}
+			rec := serve(h, tt.method, path, header)
+			if rec.Code != tt.want {
+				t.Fatalf("want %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+			if tt.wantSubs == nil {
+				return
+			}
+			var got []models.Subscription
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode %s: %v", rec.Body, err)
+			}
+			var services []string
+			for _, sub := range got {
+				services = append(services, sub.ServiceName)
+			}
+			if strings.Join(services, ",") != strings.Join(tt.wantSubs, ",") {
+				t.Fatalf("want %v, got %v", tt.wantSubs, services)
+			}
+		})
+	}
+
+	t.Run("no org_id without a default organization", func(t *testing.T) {
+		cfg := cfg
+		cfg.Auth.DefaultOrganization = 0
+		h, _, _ := newAuthTestApp(t, cfg)
+		rec := serve(h, http.MethodGet, "/api/subscriptions/all", http.Header{"Authorization": {"Bearer " + signToken(t, userClaims{RegisteredClaims: user})}})
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("want 401, got %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
+
+// serveWithPrincipal пропускает запрос вызывающего p (nil — анонимного)
+// через middleware и возвращает статус ответа.
+func serveWithPrincipal(p *principal, middleware gin.HandlerFunc) int {
+	g := gin.New()
+	g.GET("/", func(c *gin.Context) {
+		if p != nil {
+			c.Set(principalContextKey, *p)
+		}
+		c.Next()
+	}, middleware, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec.Code
+}
+
+func TestRequireScope(t *testing.T) {
+	org := models.DefaultOrganizationID
+	tests := []struct {
+		name     string
+		scopes   []string
+		required string
+		want     int
+	}{
+		{"admin implies write", []string{models.ScopeAdmin}, models.ScopeWrite, http.StatusNoContent},
+		{"admin implies read", []string{models.ScopeAdmin}, models.ScopeRead, http.StatusNoContent},
+		{"write implies read", []string{models.ScopeWrite}, models.ScopeRead, http.StatusNoContent},
+		{"write is not admin", []string{models.ScopeWrite}, models.ScopeAdmin, http.StatusForbidden},
+		{"read is not write", []string{models.ScopeRead}, models.ScopeWrite, http.StatusForbidden},
+		{"any scope of several", []string{models.ScopeRead, models.ScopeAdmin}, models.ScopeAdmin, http.StatusNoContent},
+		{"unknown scope grants nothing", []string{"superuser"}, models.ScopeRead, http.StatusForbidden},
+		{"no scopes", nil, models.ScopeRead, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &principal{Kind: "key", ID: "1", Scopes: tt.scopes, OrgID: &org}
+			if got := serveWithPrincipal(p, requireScope(tt.required)); got != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		if got := serveWithPrincipal(nil, requireScope(models.ScopeRead)); got != http.StatusUnauthorized {
+			t.Fatalf("want 401, got %d", got)
+		}
+	})
+}
+
+func TestRequirePlatform(t *testing.T) {
+	org := models.DefaultOrganizationID
+	tests := []struct {
+		name string
+		p    *principal
+		want int
+	}{
+		{"platform key", &principal{Kind: "key", ID: "1", Scopes: []string{models.ScopeAdmin}}, http.StatusNoContent},
+		{"organization key", &principal{Kind: "key", ID: "2", Scopes: []string{models.ScopeAdmin}, OrgID: &org}, http.StatusForbidden},
+		{"user", &principal{Kind: "user", ID: "tester", Scopes: []string{models.ScopeAdmin}, OrgID: &org}, http.StatusForbidden},
+		{"anonymous", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveWithPrincipal(tt.p, requirePlatform()); got != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type subscriptionResponse struct {
+	models.Subscription
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type budgetRequest struct {
+	Category     string `json:"category"`
+	MonthlyLimit int    `json:"monthly_limit" binding:"min=0"`
+}
+
+//********************************************************************//
+//  							 BUDGET								  //
+//********************************************************************//
+
+// getUserBudget godoc
+// @Summary Получить остаток бюджета пользователя по месяцам
+// @Tags budgets
+// @Produce json
+// @Param id path string true "UUID пользователя"
+// @Param from query string false "Начало периода (формат: MM-YYYY), по умолчанию текущий месяц" example:"01-2025"
+// @Param to query string false "Конец периода (формат: MM-YYYY), по умолчанию через 11 месяцев; период не длиннее 120 месяцев" example:"12-2025"
+// @Success 200 {object} map[string]interface{} "Бюджеты и остаток по месяцам"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /user/{id}/budget [get]
+func (app *application) getUserBudget(c *gin.Context) {
+	var id uuid.UUID
+	if err := id.Scan(c.Param("id")); err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	from := time.Now().UTC()
+	if fromStr := c.Query("from"); fromStr != "" {
+		t, err := time.Parse("01-2006", fromStr)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid from date format. Use MM-YYYY")
+			return
+		}
+		from = t
+	}
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	to := from.AddDate(0, 11, 0)
+	if toStr := c.Query("to"); toStr != "" {
+		t, err := time.Parse("01-2006", toStr)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid to date format. Use MM-YYYY")
+			return
+		}
+		to = t
+	}
+
+	if from.After(to) {
+		errorResponse(c, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+	if periodTooLong(from, to) {
+		errorResponse(c, http.StatusBadRequest, periodTooLongMessage)
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, reportQuery)
+	defer cancel()
+
+	budgets, err := app.allModels.Budgets.GetByUserID(ctx, id)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return budgets")
+		return
+	}
+
+	// Совместные подписки входят в бюджет участника его долей.
+	subs, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{UserID: c.Param("id")})
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return subscriptions")
+		return
+	}
+
+	categories, err := app.allModels.Services.Categories(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return service categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": budgets,
+		"months":  models.EvaluateBudgets(budgets, subs, categories, from, to),
+	})
+}
+
+// setUserBudget godoc
+// @Summary Установить месячный бюджет пользователя
+// @Description Пустая категория задаёт общий лимит по всем подпискам
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID пользователя"
+// @Param budget body budgetRequest true "Категория и месячный лимит"
+// @Success 200 {object} models.Budget
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /user/{id}/budget [put]
+func (app *application) setUserBudget(c *gin.Context) {
+	var id uuid.UUID
+	if err := id.Scan(c.Param("id")); err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req budgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budget := models.Budget{
+		UserID:       id,
+		Category:     req.Category,
+		MonthlyLimit: req.MonthlyLimit,
+	}
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err := app.allModels.Budgets.Upsert(ctx, &budget)
+	if errors.Is(err, models.ErrNoOrganization) {
+		errorResponse(c, http.StatusBadRequest, organizationHeader+" is required to set budgets with a platform key")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// deleteUserBudget godoc
+// @Summary Удалить месячный бюджет пользователя
+// @Tags budgets
+// @Produce json
+// @Param id path string true "UUID пользователя"
+// @Param category query string false "Категория бюджета, по умолчанию общий лимит"
+// @Success 200 {object} map[string]string "Сообщение об успешном удалении"
+// @Failure 400 {object} map[string]string "Неверный UUID"
+// @Failure 404 {object} map[string]string "Бюджет не найден"
+// @Failure 500 {object} map[string]string "Ошибка при удалении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /user/{id}/budget [delete]
+func (app *application) deleteUserBudget(c *gin.Context) {
+	var id uuid.UUID
+	if err := id.Scan(c.Param("id")); err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err := app.allModels.Budgets.Delete(ctx, id, c.Query("category"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(c, http.StatusNotFound, "Budget not found")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to delete budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully deleted budget"})
+}
+
+//********************************************************************//
+//  							 HELPERS							  //
+//********************************************************************//
+
+// budgetWarnings проверяет бюджеты плательщика и участников подписки на
+// всём периоде её действия (для бессрочных — на ближайший год) и сообщает
+// о месяцах, которые именно эта запись вывела за лимит: траты сравниваются
+// с теми, что были до неё, с подпиской в версии before (nil — новой
+// подписки не было). Каждый считается своей долей, поэтому подписка
+// берётся из хранилища вместе с участниками.
+func (app *application) budgetWarnings(c *gin.Context, before *models.Subscription, sub models.Subscription) ([]string, error) {
+	ctx, cancel := app.queryContext(c, reportQuery)
+	defer cancel()
+
+	stored, err := app.allModels.Subscriptions.Get(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := app.allModels.Services.Categories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Дальние месяцы долгих подписок не проверяются: период ограничен, как
+	// у отчёта о бюджете.
+	from := stored.StartDate
+	to := from.AddDate(0, 11, 0)
+	if stored.EndDate != nil {
+		to = *stored.EndDate
+	}
+	if limit := from.AddDate(0, maxForecastMonths-1, 0); to.After(limit) {
+		to = limit
+	}
+
+	var warnings []string
+	for _, share := range stored.Shares(stored.Price) {
+		budgets, err := app.allModels.Budgets.GetByUserID(ctx, share.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if len(budgets) == 0 {
+			continue
+		}
+
+		subs, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{UserID: share.UserID.UUID.String()})
+		if err != nil {
+			return nil, err
+		}
+		previous := make([]*models.Subscription, 0, len(subs))
+		for _, s := range subs {
+			if s.ID != stored.ID {
+				previous = append(previous, s)
+			}
+		}
+		if before != nil && before.IsMember(share.UserID) {
+			previous = append(previous, before)
+		}
+
+		owner := ""
+		if share.UserID.UUID != stored.UserID.UUID {
+			owner = " of member " + share.UserID.UUID.String()
+		}
+		exceeded := models.NewlyExceeded(
+			models.EvaluateBudgets(budgets, previous, categories, from, to),
+			models.EvaluateBudgets(budgets, subs, categories, from, to),
+		)
+		for _, month := range exceeded {
+			budget := &models.Budget{Category: month.Category}
+			if !budget.Covers(stored, categories) {
+				continue
+			}
+
+			name := month.Category
+			if name == "" {
+				name = "total"
+			}
+			warnings = append(warnings, fmt.Sprintf("Budget %q%s exceeded in %s: spent %d of %d",
+				name, owner, month.Month.Format("01-2006"), month.Spent, month.Limit))
+		}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"subscription-service/internal/config"
+	"testing"
+)
+
+// Проверки периода срабатывают до обращения к бюджетам в БД.
+func TestUserBudgetPeriodLimit(t *testing.T) {
+	h, _ := newTestApp(t, config.Default())
+	const path = "/api/subscriptions/user/60601fee-2bf1-4721-ae6f-7636e79a0cba/budget"
+
+	for _, query := range []string{
+		"from=01-2016&to=01-2026",
+		"from=01-0001&to=12-9999",
+		"from=02-2025&to=01-2025",
+		"from=13-2025",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: want 400, got %d: %s", query, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"subscription-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type categoryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *int   `json:"parent_id"`
+}
+
+//********************************************************************//
+//  							 CATEGORIES							  //
+//********************************************************************//
+
+// listCategories godoc
+// @Summary Получить дерево категорий
+// @Description Категории возвращаются плоским списком с путями от корня, упорядоченным по пути
+// @Tags categories
+// @Produce json
+// @Success 200 {object} map[string][]models.Category
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /categories [get]
+func (app *application) listCategories(c *gin.Context) {
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	categories, err := app.allModels.Categories.GetAll(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": categories})
+}
+
+// createCategory godoc
+// @Summary Добавить категорию
+// @Description Требует область admin. Без parent_id категория создаётся в корне
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param category body categoryRequest true "Имя и родительская категория"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} map[string]string "Неверный запрос или родитель не найден"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 409 {object} map[string]string "У родителя уже есть категория с таким именем"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /categories [post]
+func (app *application) createCategory(c *gin.Context) {
+	var req categoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if name == "" || strings.Contains(name, models.CategoryPathSeparator) {
+		errorResponse(c, http.StatusBadRequest, "name must be non-blank and must not contain "+models.CategoryPathSeparator)
+		return
+	}
+	category := &models.Category{Name: name, ParentID: req.ParentID}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err := app.allModels.Categories.Insert(ctx, category)
+	switch {
+	case errors.Is(err, models.ErrUnknownCategory):
+		errorResponse(c, http.StatusBadRequest, "parent category does not exist")
+	case errors.Is(err, models.ErrDuplicateCategory):
+		errorResponse(c, http.StatusConflict, err.Error())
+	case err != nil:
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save category")
+	default:
+		c.JSON(http.StatusCreated, category)
+	}
+}
+
+// deleteCategory godoc
+// @Summary Удалить категорию
+// @Description Требует область admin. Вложенные категории удаляются вместе с ней, подписки остаются без категории
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "ID категории"
+// @Success 200 {object} map[string]string "Категория удалена"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 404 {object} map[string]string "Категория не найдена"
+// @Failure 500 {object} map[string]string "Ошибка при удалении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /categories/{id} [delete]
+func (app *application) deleteCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.Categories.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(c, http.StatusNotFound, "Category not found")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to delete category")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
+}
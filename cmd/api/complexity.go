@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/graph-gophers/graphql-go/ast"
+)
+
+// Оценка сложности GraphQL-запроса до выполнения. Парсер graphql-go
+// внутренний, а выборка, которую он отдаёт резолверам, теряет псевдонимы
+// — и повтор списка под десятком псевдонимов стоил бы как один. Поэтому
+// запрос, уже прошедший валидацию схемы, разбирается здесь ещё раз, ровно
+// настолько, насколько нужно для оценки: поля с псевдонимами, аргумент
+// limit и фрагменты.
+
+//********************************************************************//
+//  							 ESTIMATE							  //
+//********************************************************************//
+
+// complexity оценивает операцию operationName запроса query. Каждое поле
+// стоит 1 и умножается на размер всех списков над ним: списка с limit —
+// на limit, остальных — на graphqlListComplexity. Поле под каждым
+// псевдонимом считается отдельно. Без operationName оценивается самая
+// дорогая операция документа.
+func (q *graphqlQuery) complexity(query, operationName string, variables map[string]any) (int, error) {
+	doc, err := parseGraphQLDocument(query)
+	if err != nil {
+		return 0, err
+	}
+
+	cost := 0
+	for _, op := range doc.operations {
+		if operationName != "" && op.name != operationName {
+			continue
+		}
+		e := &graphqlEstimate{query: q, doc: doc, variables: variables, visiting: map[string]bool{}}
+		cost = max(cost, e.selection("Query", op.selection, 1))
+	}
+	return cost, nil
+}
+
+type graphqlEstimate struct {
+	query     *graphqlQuery
+	doc       *graphqlDocument
+	variables map[string]any
+	// visiting — фрагменты на текущем пути: циклы отсекает валидация, но
+	// оценка не должна зависеть от этого.
+	visiting map[string]bool
+}
+
+func (e *graphqlEstimate) selection(typeName string, set []graphqlSelection, multiplier int) int {
+	cost := 0
+	for _, sel := range set {
+		switch {
+		case sel.spread != "":
+			fragment, ok := e.doc.fragments[sel.spread]
+			if !ok || e.visiting[sel.spread] {
+				continue
+			}
+			e.visiting[sel.spread] = true
+			cost += e.selection(fragment.on, fragment.selection, multiplier)
+			delete(e.visiting, sel.spread)
+
+		case sel.field == "":
+			on := sel.on
+			if on == "" {
+				on = typeName
+			}
+			cost += e.selection(on, sel.selection, multiplier)
+
+		default:
+			// Служебные поля (__typename, __schema) в схеме не описаны и
+			// не стоят ничего.
+			obj, ok := e.query.schema.Types[typeName].(*ast.ObjectTypeDefinition)
+			if !ok {
+				continue
+			}
+			def := obj.Fields.Get(sel.field)
+			if def == nil {
+				continue
+			}
+
+			cost += multiplier
+			if len(sel.selection) == 0 {
+				continue
+			}
+			child := multiplier
+			if isGraphQLList(def.Type) {
+				child *= e.listSize(def, sel)
+			}
+			cost += e.selection(graphQLTypeName(def.Type), sel.selection, child)
+		}
+	}
+	return cost
+}
+
+// listSize — сколько элементов оценка предполагает в списке sel. limit,
+// который не удалось определить (переменная без значения), считается
+// наибольшим допустимым.
+func (e *graphqlEstimate) listSize(def *ast.FieldDefinition, sel graphqlSelection) int {
+	arg := def.Arguments.Get("limit")
+	if arg == nil {
+		return graphqlListComplexity
+	}
+
+	raw, ok := sel.args["limit"]
+	if !ok {
+		if arg.Default == nil {
+			return graphqlMaxLimit
+		}
+		raw = arg.Default.String()
+	}
+
+	var value any = raw
+	if name, isVar := strings.CutPrefix(raw, "$"); isVar {
+		value = e.variables[name]
+	}
+
+	var limit int
+	switch v := value.(type) {
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return graphqlMaxLimit
+		}
+		limit = n
+	case float64:
+		limit = int(v)
+	case int:
+		limit = v
+	case int32:
+		limit = int(v)
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return graphqlMaxLimit
+		}
+		limit = int(n)
+	default:
+		return graphqlMaxLimit
+	}
+	// Недопустимый limit резолвер всё равно отклонит.
+	return min(max(limit, 1), graphqlMaxLimit)
+}
+
+//********************************************************************//
+//  							 PARSER								  //
+//********************************************************************//
+
+// graphqlDocument — операции и фрагменты запроса; из полей сохраняются
+// только имена, простые аргументы и вложенные выборки.
+type graphqlDocument struct {
+	operations []graphqlOperation
+	fragments  map[string]graphqlFragment
+}
+
+type graphqlOperation struct {
+	name      string
+	selection []graphqlSelection
+}
+
+type graphqlFragment struct {
+	on        string
+	selection []graphqlSelection
+}
+
+// graphqlSelection — поле (field), именованный фрагмент (spread) или
+// встроенный фрагмент с необязательным условием типа (on). args хранит
+// аргументы-литералы и переменные ("$name"); списки и объекты опускаются.
+type graphqlSelection struct {
+	field     string
+	args      map[string]string
+	spread    string
+	on        string
+	selection []graphqlSelection
+}
+
+var errGraphQLSyntax = errors.New("cannot estimate query complexity: unexpected syntax")
+
+func parseGraphQLDocument(query string) (*graphqlDocument, error) {
+	tokens, err := lexGraphQL(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &graphqlParser{tokens: tokens}
+	doc := &graphqlDocument{fragments: map[string]graphqlFragment{}}
+
+	for !p.done() && p.err == nil {
+		switch tok := p.peek(); {
+		case tok == "{":
+			doc.operations = append(doc.operations, graphqlOperation{selection: p.selectionSet()})
+		case tok == "fragment":
+			p.next()
+			name := p.next()
+			p.expect("on")
+			on := p.next()
+			p.directives()
+			doc.fragments[name] = graphqlFragment{on: on, selection: p.selectionSet()}
+		case tok == "query" || tok == "mutation" || tok == "subscription":
+			p.next()
+			var op graphqlOperation
+			if isGraphQLName(p.peek()) {
+				op.name = p.next()
+			}
+			if p.peek() == "(" {
+				p.skipBalanced()
+			}
+			p.directives()
+			op.selection = p.selectionSet()
+			doc.operations = append(doc.operations, op)
+		default:
+			p.err = errGraphQLSyntax
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return doc, nil
+}
+
+type graphqlParser struct {
+	tokens []string
+	pos    int
+	err    error
+}
+
+func (p *graphqlParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *graphqlParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *graphqlParser) next() string {
+	if p.done() {
+		p.err = errGraphQLSyntax
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *graphqlParser) expect(tok string) {
+	if p.next() != tok {
+		p.err = errGraphQLSyntax
+	}
+}
+
+func (p *graphqlParser) selectionSet() []graphqlSelection {
+	p.expect("{")
+	var set []graphqlSelection
+	for p.err == nil && p.peek() != "}" {
+		set = append(set, p.selection())
+	}
+	p.expect("}")
+	return set
+}
+
+func (p *graphqlParser) selection() graphqlSelection {
+	var sel graphqlSelection
+
+	if p.peek() == "." {
+		p.expect(".")
+		p.expect(".")
+		p.expect(".")
+		switch tok := p.peek(); {
+		case tok == "on":
+			p.next()
+			sel.on = p.next()
+		case isGraphQLName(tok):
+			sel.spread = p.next()
+			p.directives()
+			return sel
+		}
+		p.directives()
+		sel.selection = p.selectionSet()
+		return sel
+	}
+
+	sel.field = p.next()
+	if p.peek() == ":" {
+		p.next()
+		sel.field = p.next()
+	}
+	if p.peek() == "(" {
+		sel.args = p.arguments()
+	}
+	p.directives()
+	if p.peek() == "{" {
+		sel.selection = p.selectionSet()
+	}
+	return sel
+}
+
+func (p *graphqlParser) arguments() map[string]string {
+	args := map[string]string{}
+	p.expect("(")
+	for p.err == nil && p.peek() != ")" {
+		name := p.next()
+		p.expect(":")
+		switch p.peek() {
+		case "$":
+			p.next()
+			args[name] = "$" + p.next()
+		case "[", "{":
+			p.skipBalanced()
+		case "-":
+			p.next()
+			args[name] = "-" + p.next()
+		default:
+			args[name] = p.next()
+		}
+	}
+	p.expect(")")
+	return args
+}
+
+func (p *graphqlParser) directives() {
+	for p.err == nil && p.peek() == "@" {
+		p.next()
+		p.next()
+		if p.peek() == "(" {
+			p.skipBalanced()
+		}
+	}
+}
+
+// skipBalanced пропускает скобочную группу вместе с вложенными.
+func (p *graphqlParser) skipBalanced() {
+	depth := 0
+	for p.err == nil {
+		switch p.next() {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		}
+		if depth == 0 {
+			return
+		}
+	}
+}
+
+// isGraphQLName сообщает, что лексема — имя. Правило то же, что у
+// text/scanner: буква или подчёркивание в начале.
+func isGraphQLName(tok string) bool {
+	r, _ := utf8.DecodeRuneInString(tok)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// lexGraphQL делит запрос на лексемы ровно так, как это делает лексер
+// graphql-go: тот же text/scanner в том же режиме, запятые и комментарии
+// пропускаются. Поэтому всё, что принимает graphql-go, разбирается и
+// здесь, в том числе его отступления от спецификации: многоточие — три
+// лексемы ".", знак числа — отдельная лексема "-". Ошибка сканера —
+// errGraphQLSyntax.
+func lexGraphQL(src string) ([]string, error) {
+	var err error
+	sc := &scanner.Scanner{
+		Mode: scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings,
+	}
+	sc.Init(strings.NewReader(src))
+	sc.Error = func(_ *scanner.Scanner, msg string) {
+		if err == nil {
+			err = fmt.Errorf("%w: %s", errGraphQLSyntax, msg)
+		}
+	}
+
+	var tokens []string
+	for tok := sc.Scan(); tok != scanner.EOF && err == nil; tok = sc.Scan() {
+		switch tok {
+		case ',':
+		case '#':
+			for {
+				next := sc.Next()
+				if next == '\r' || next == '\n' || next == scanner.EOF {
+					break
+				}
+			}
+		default:
+			tokens = append(tokens, sc.TokenText())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func isGraphQLList(t ast.Type) bool {
+	if nn, ok := t.(*ast.NonNull); ok {
+		t = nn.OfType
+	}
+	_, ok := t.(*ast.List)
+	return ok
+}
+
+func graphQLTypeName(t ast.Type) string {
+	for {
+		switch v := t.(type) {
+		case *ast.NonNull:
+			t = v.OfType
+		case *ast.List:
+			t = v.OfType
+		case ast.NamedType:
+			return v.TypeName()
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type costCenterRequest struct {
+	Code string `json:"code" binding:"required" example:"RND"`
+	Name string `json:"name" binding:"required" example:"Research & Development"`
+}
+
+type allocationsRequest struct {
+	Allocations []models.CostAllocation `json:"allocations"`
+}
+
+type allocationsResponse struct {
+	SubscriptionID int                     `json:"subscription_id"`
+	Allocations    []models.CostAllocation `json:"allocations"`
+}
+
+//********************************************************************//
+//  							 COST CENTERS						  //
+//********************************************************************//
+
+// listCostCenters godoc
+// @Summary Получить центры затрат организации
+// @Tags cost-centers
+// @Produce json
+// @Success 200 {object} map[string][]models.CostCenter
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /cost-centers [get]
+func (app *application) listCostCenters(c *gin.Context) {
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	centers, err := app.allModels.CostCenters.GetAll(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return cost centers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": centers})
+}
+
+// createCostCenter godoc
+// @Summary Добавить центр затрат
+// @Description Требует область admin. Центр создаётся в организации вызывающего; ключ платформы указывает её заголовком X-Organization-ID
+// @Tags cost-centers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param X-Organization-ID header int false "Организация для ключа платформы"
+// @Param center body costCenterRequest true "Код и название"
+// @Success 201 {object} models.CostCenter
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 409 {object} map[string]string "Центр с таким кодом уже есть"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /cost-centers [post]
+func (app *application) createCostCenter(c *gin.Context) {
+	var req costCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	center := &models.CostCenter{
+		Code: strings.TrimSpace(req.Code),
+		Name: strings.Join(strings.Fields(req.Name), " "),
+	}
+	if center.Code == "" || center.Name == "" {
+		errorResponse(c, http.StatusBadRequest, "code and name must not be blank")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err := app.allModels.CostCenters.Insert(ctx, center)
+	switch {
+	case errors.Is(err, models.ErrNoOrganization):
+		errorResponse(c, http.StatusBadRequest, organizationHeader+" is required to create cost centers with a platform key")
+	case errors.Is(err, models.ErrDuplicateCostCenter):
+		errorResponse(c, http.StatusConflict, err.Error())
+	case err != nil:
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save cost center")
+	default:
+		c.JSON(http.StatusCreated, center)
+	}
+}
+
+// deleteCostCenter godoc
+// @Summary Удалить центр затрат
+// @Description Требует область admin. Подписки, у которых была доля на этом центре, теряют распределение целиком и попадают в отчёт как нераспределённые
+// @Tags cost-centers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "ID центра затрат"
+// @Success 200 {object} map[string]string "Центр затрат удалён"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 404 {object} map[string]string "Центр затрат не найден"
+// @Failure 500 {object} map[string]string "Ошибка при удалении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /cost-centers/{id} [delete]
+func (app *application) deleteCostCenter(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid cost center ID")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.CostCenters.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(c, http.StatusNotFound, "Cost center not found")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to delete cost center")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cost center deleted"})
+}
+
+//********************************************************************//
+//  							 ALLOCATIONS						  //
+//********************************************************************//
+
+// getCostAllocations godoc
+// @Summary Получить распределение подписки по центрам затрат
+// @Tags cost-centers
+// @Produce json
+// @Param id path int true "ID записи"
+// @Success 200 {object} allocationsResponse
+// @Failure 400 {object} map[string]string "Неверный формат ID"
+// @Failure 404 {object} map[string]string "Подписка не найдена"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /{id}/cost-centers [get]
+func (app *application) getCostAllocations(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	if _, err := app.allModels.Subscriptions.Get(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			errorResponse(c, http.StatusNotFound, "Subscription not found")
+			return
+		}
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return record")
+		return
+	}
+
+	allocations, err := app.allModels.CostCenters.GetAllocations(ctx, id)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return allocations")
+		return
+	}
+
+	c.JSON(http.StatusOK, allocationsResponse{SubscriptionID: id, Allocations: allocations})
+}
+
+// setCostAllocations godoc
+// @Summary Распределить подписку по центрам затрат
+// @Description Проценты должны давать в сумме 100; у единственного центра процент можно не указывать. Пустой список снимает распределение
+// @Tags cost-centers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID записи"
+// @Param allocations body allocationsRequest true "Центры затрат и их доли в процентах"
+// @Success 200 {object} allocationsResponse
+// @Failure 400 {object} map[string]string "Неверный запрос или центр затрат не найден"
+// @Failure 404 {object} map[string]string "Подписка не найдена"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /{id}/cost-centers [put]
+func (app *application) setCostAllocations(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var req allocationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	allocations, err := models.NormalizeAllocations(req.Allocations)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.CostCenters.SetAllocations(ctx, id, allocations)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		errorResponse(c, http.StatusNotFound, "Subscription not found")
+	case errors.Is(err, models.ErrUnknownCostCenter):
+		errorResponse(c, http.StatusBadRequest, err.Error())
+	case err != nil:
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save allocations")
+	default:
+		c.JSON(http.StatusOK, allocationsResponse{SubscriptionID: id, Allocations: allocations})
+	}
+}
+
+//********************************************************************//
+//  							 CHARGEBACK							  //
+//********************************************************************//
+
+// getChargeback godoc
+// @Summary Отчёт о распределении затрат по центрам затрат за период
+// @Description Стоимость подписок считается как в /summary и делится между центрами по процентам. Подписки без распределения собраны в центр с пустым cost_center_id. С format=csv отчёт отдаётся файлом, по строке на долю подписки
+// @Tags cost-centers
+// @Produce json,text/csv
+// @Param from query string true "Начало периода (формат: MM-YYYY)" example:"01-2025"
+// @Param to query string true "Конец периода (формат: MM-YYYY)" example:"12-2025"
+// @Param format query string false "Формат ответа" Enums(json, csv)
+// @Success 200 {object} map[string]interface{} "total_cost и cost_centers со строками по подпискам"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /reports/chargeback [get]
+func (app *application) getChargeback(c *gin.Context) {
+	from, err := time.Parse("01-2006", c.Query("from"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "from is required in MM-YYYY format")
+		return
+	}
+	to, err := time.Parse("01-2006", c.Query("to"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "to is required in MM-YYYY format")
+		return
+	}
+	if from.After(to) {
+		errorResponse(c, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		errorResponse(c, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, reportQuery)
+	defer cancel()
+
+	subs, _, err := app.allModels.Subscriptions.GetSummary(ctx, from, to, models.SubscriptionFilter{})
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to calculate summary")
+		return
+	}
+	centers, err := app.allModels.CostCenters.GetAll(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return cost centers")
+		return
+	}
+	allocations, err := app.allModels.CostCenters.Allocations(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return allocations")
+		return
+	}
+
+	report, total := models.Chargeback(subs, centers, allocations)
+
+	if format == "csv" {
+		writeChargebackCSV(c, fmt.Sprintf("chargeback-%s-%s.csv", from.Format("2006-01"), to.Format("2006-01")), report)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total_cost":   total,
+		"cost_centers": report,
+	})
+}
+
+// writeChargebackCSV пишет отчёт по строке на долю подписки в центре
+// затрат. Итоги по центрам считаются в таблице суммированием cost.
+func writeChargebackCSV(c *gin.Context, filename string, report []*models.ChargebackCenter) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"cost_center_id", "cost_center_code", "cost_center_name",
+		"subscription_id", "service_name", "user_id",
+		"date_from", "date_to", "percent", "cost", "full_cost",
+	})
+	for _, center := range report {
+		centerID := ""
+		if center.CostCenterID != nil {
+			centerID = strconv.Itoa(*center.CostCenterID)
+		}
+		for _, line := range center.Lines {
+			w.Write([]string{
+				centerID, center.Code, center.Name,
+				strconv.Itoa(line.SubscriptionID), line.ServiceName, line.UserID.UUID.String(),
+				line.DateFrom.Format("01-2006"), line.DateTo.Format("01-2006"),
+				strconv.Itoa(line.Percent), strconv.Itoa(line.Cost), strconv.Itoa(line.FullCost),
+			})
+		}
+	}
+	w.Flush()
+}
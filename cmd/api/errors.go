@@ -0,0 +1,16 @@
+package main
+
+import (
+	"subscription-service/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorResponse пишет ответ об ошибке с ID запроса, чтобы его можно было
+// найти в логах.
+func errorResponse(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{
+		"error":      message,
+		"request_id": logging.RequestID(c.Request.Context()),
+	})
+}
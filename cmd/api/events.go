@@ -1,7 +1,9 @@
 package main
 
 import (
-	"log"
+	"database/sql"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"subscription-service/internal/models"
@@ -20,14 +22,19 @@ import (
 // @Summary Получить все записи подписок
 // @Tags subscriptions, subscriptions-get
 // @Produce json
+// @Param tag query []string false "Только подписки со всеми перечисленными тегами" collectionFormat(multi)
 // @Success 200 {array} models.Subscription
 // @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /all [get]
 func (app *application) getAllRecords(c *gin.Context) {
-	rec, err := app.allModels.Subscriptions.GetAll()
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	rec, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{Tags: c.QueryArray("tag")})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to return all records"})
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return all records")
 		return
 	}
 
@@ -42,18 +49,22 @@ func (app *application) getAllRecords(c *gin.Context) {
 // @Success 200 {object} models.Subscription
 // @Failure 400 {object} map[string]string "Неверный формат ID"
 // @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /{id} [get]
 func (app *application) getRecordByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	sub, err := app.allModels.Subscriptions.Get(id)
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	sub, err := app.allModels.Subscriptions.Get(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to return record"})
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return record")
 		return
 	}
 
@@ -62,27 +73,35 @@ func (app *application) getRecordByID(c *gin.Context) {
 
 // getRecordsByUserID godoc
 // @Summary Получить подписки пользователя по ID
+// @Description Включает совместные подписки, в которых пользователь участвует, но не платит
 // @Tags subscriptions, subscriptions-get
 // @Produce json
 // @Param id path string true "UUID пользователя"
+// @Param tag query []string false "Только подписки со всеми перечисленными тегами" collectionFormat(multi)
 // @Success 200 {array} models.Subscription
 // @Failure 400 {object} map[string]string "Неверный UUID"
 // @Failure 404 {object} map[string]string "Подписки не найдены"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /user/{id} [get]
 func (app *application) getRecordsByUserID(c *gin.Context) {
 	idStr := c.Param("id")
-	//log.Println(idStr)
 	var id uuid.UUID
 	if err := id.Scan(idStr); err != nil {
-		log.Println(id)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		slog.DebugContext(c.Request.Context(), "invalid user id", "user_id", idStr, "error", err)
+		errorResponse(c, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
-	sub, err := app.allModels.Subscriptions.GetByUserID(id)
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	sub, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{
+		UserID: idStr,
+		Tags:   c.QueryArray("tag"),
+	})
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		queryFailed(c, ctx, http.StatusNotFound, "Subscription not found")
 		return
 	}
 
@@ -93,17 +112,31 @@ func (app *application) getRecordsByUserID(c *gin.Context) {
 // @Summary Получить подписки по имени сервиса
 // @Tags subscriptions, subscriptions-get
 // @Produce json
-// @Param name path string true "Название сервиса"
+// @Param name path string true "Название сервиса или его псевдоним из каталога"
+// @Param tag query []string false "Только подписки со всеми перечисленными тегами" collectionFormat(multi)
 // @Success 200 {array} models.Subscription
 // @Failure 404 {object} map[string]string "Подписки не найдены"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /service/{name} [get]
 func (app *application) getRecordsByServiceName(c *gin.Context) {
 	serviceName := c.Param("name")
-	log.Println(serviceName)
-	sub, err := app.allModels.Subscriptions.GetByUserSubscription(serviceName)
+	slog.DebugContext(c.Request.Context(), "get subscriptions by service", "service_name", serviceName)
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	serviceName, err := app.canonicalServiceName(ctx, serviceName)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to resolve service")
+		return
+	}
+
+	sub, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{
+		ServiceName: serviceName,
+		Tags:        c.QueryArray("tag"),
+	})
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		queryFailed(c, ctx, http.StatusNotFound, "Subscription not found")
 		return
 	}
 
@@ -116,34 +149,65 @@ func (app *application) getRecordsByServiceName(c *gin.Context) {
 
 // createRecord godoc
 // @Summary Создать новую запись подписки
+// @Description Имя сервиса сводится к каноническому по каталогу. Если цена не указана, берётся цена сервиса по умолчанию. Подписка создаётся в организации вызывающего; ключ платформы указывает её заголовком X-Organization-ID
 // @Tags subscriptions, subscriptions-post
 // @Accept json
 // @Produce json
 // @Param subscription body models.MidwaySub true "Данные подписки"
+// @Param X-Organization-ID header int false "Организация для ключа платформы"
 // @Success 201 {object} models.Subscription
 // @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 409 {object} map[string]string "Подписка на этот сервис уже есть"
 // @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /newrecord [post]
 func (app *application) createRecord(c *gin.Context) {
 	var mid models.MidwaySub
 
 	if err := c.ShouldBindJSON(&mid); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	sub, err := mid.FromMidwaySub()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	if err := app.applyCatalog(ctx, &sub); err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to resolve service")
 		return
 	}
 
-	err = app.allModels.Subscriptions.Insert(&sub)
+	err = app.allModels.Subscriptions.Insert(ctx, &sub)
+	if errors.Is(err, models.ErrNoOrganization) {
+		errorResponse(c, http.StatusBadRequest, organizationHeader+" is required to create subscriptions with a platform key")
+		return
+	}
+	if errors.Is(err, models.ErrDuplicateSubscription) {
+		errorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, models.ErrUnknownCategory) {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		queryFailed(c, ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Successfully created record about the subscription"})
+
+	resp := gin.H{"message": "Successfully created record about the subscription"}
+	if warnings, err := app.budgetWarnings(c, nil, sub); err != nil {
+		slog.WarnContext(c.Request.Context(), "budget check failed", "subscription_id", sub.ID, "error", err)
+	} else if len(warnings) > 0 {
+		resp["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 //********************************************************************//
@@ -158,18 +222,22 @@ func (app *application) createRecord(c *gin.Context) {
 // @Success 200 {object} map[string]string "Сообщение об успешном удалении"
 // @Failure 400 {object} map[string]string "Неверный ID"
 // @Failure 500 {object} map[string]string "Ошибка при удалении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /{id} [delete]
 func (app *application) deleteRecordByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = app.allModels.Subscriptions.Delete(id)
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.Subscriptions.Delete(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete record about the subscription"})
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to delete record about the subscription")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully deleted record about the subscription"})
@@ -183,19 +251,23 @@ func (app *application) deleteRecordByID(c *gin.Context) {
 // @Success 200 {object} map[string]string "Сообщение об успешном удалении"
 // @Failure 400 {object} map[string]string "Неверный UUID"
 // @Failure 404 {object} map[string]string "Подписки не найдены"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /user/{id} [delete]
 func (app *application) deleteRecordByUserID(c *gin.Context) {
 	idStr := c.Param("id")
 	var id uuid.UUID
 	if err := id.Scan(idStr); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		errorResponse(c, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
-	err := app.allModels.Subscriptions.DeleteByUserID(id)
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err := app.allModels.Subscriptions.DeleteByUserID(ctx, id)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		queryFailed(c, ctx, http.StatusNotFound, "Subscription not found")
 		return
 	}
 
@@ -206,17 +278,27 @@ func (app *application) deleteRecordByUserID(c *gin.Context) {
 // @Summary Удалить все записи подписок по имени сервиса
 // @Tags subscriptions, subscriptions-delete
 // @Produce json
-// @Param name path string true "Название сервиса"
+// @Param name path string true "Название сервиса или его псевдоним из каталога"
 // @Success 200 {object} map[string]string "Сообщение об успешном удалении"
 // @Failure 404 {object} map[string]string "Подписки не найдены"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /service/{name} [delete]
 func (app *application) deleteRecordsByServiceName(c *gin.Context) {
 	serviseName := c.Param("name")
 
-	err := app.allModels.Subscriptions.DeleteByServiceName(serviseName)
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	serviseName, err := app.canonicalServiceName(ctx, serviseName)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to resolve service")
+		return
+	}
+
+	err = app.allModels.Subscriptions.DeleteByServiceName(ctx, serviseName)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		queryFailed(c, ctx, http.StatusNotFound, "Subscription not found")
 		return
 	}
 
@@ -234,39 +316,70 @@ func (app *application) deleteRecordsByServiceName(c *gin.Context) {
 // @Produce json
 // @Param id path int true "ID записи"
 // @Param subscription body models.MidwaySub true "Данные для обновления"
-// @Success 200 {object} models.Subscription
+// @Success 200 {object} subscriptionResponse "Обновлённая подписка и предупреждения о превышении бюджета"
 // @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 409 {object} map[string]string "Подписка на этот сервис уже есть"
 // @Failure 500 {object} map[string]string "Ошибка при обновлении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /{id} [put]
 func (app *application) updateRecordByID(c *gin.Context) {
 	var sub models.Subscription
 
 	var mid models.MidwaySub
 	if err := c.ShouldBindJSON(&mid); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	sub, err := mid.FromMidwaySub()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	sub.ID = id
 
-	err = app.allModels.Subscriptions.Update(sub)
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	if err := app.applyCatalog(ctx, &sub); err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to resolve service")
+		return
+	}
+
+	// Прежняя версия нужна, чтобы предупреждать только о месяцах, которые
+	// вывело за бюджет само изменение.
+	before, err := app.allModels.Subscriptions.Get(ctx, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return record")
+		return
+	}
+
+	err = app.allModels.Subscriptions.Update(ctx, sub)
+	if errors.Is(err, models.ErrDuplicateSubscription) {
+		errorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, models.ErrUnknownCategory) {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update record about the subscription"})
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to update record about the subscription")
 		return
 	}
-	c.JSON(http.StatusOK, sub)
+
+	resp := subscriptionResponse{Subscription: sub}
+	if resp.Warnings, err = app.budgetWarnings(c, before, sub); err != nil {
+		slog.WarnContext(c.Request.Context(), "budget check failed", "subscription_id", sub.ID, "error", err)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 //********************************************************************//
@@ -279,43 +392,79 @@ func (app *application) updateRecordByID(c *gin.Context) {
 // @Produce json
 // @Param from query string true "Начало периода (формат: MM-YYYY)" example:"01-2023"
 // @Param to query string true "Конец периода (формат: MM-YYYY)" example:"12-2023"
-// @Param user_id query string false "UUID пользователя" example:"550e8400-e29b-41d4-a716-446655440000"
-// @Param service_name query string false "Название сервиса" example:"Netflix"
-// @Success 200 {object} map[string]interface{} "total_cost и список подписок"
+// @Param user_id query string false "UUID пользователя; совместные подписки учитываются его долей" example:"550e8400-e29b-41d4-a716-446655440000"
+// @Param service_name query string false "Название сервиса или его псевдоним из каталога" example:"Netflix"
+// @Param tag query []string false "Только подписки со всеми перечисленными тегами" collectionFormat(multi)
+// @Param group_by query string false "Разбить траты по группам; стоимость категории включает вложенные" Enums(category, tag)
+// @Success 200 {object} map[string]interface{} "total_cost, список подписок и, если задан group_by, groups"
 // @Failure 400 {object} map[string]string "Неверные параметры"
 // @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
 // @Router /summary [get]
 func (app *application) getRecordsByFilter(c *gin.Context) {
 	fromStr := c.Query("from")
 	toStr := c.Query("to")
 	userID := c.Query("user_id")
 	serviceName := c.Query("service_name")
+	groupBy := c.Query("group_by")
 
 	if fromStr == "" || toStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to parameters are required"})
+		errorResponse(c, http.StatusBadRequest, "from and to parameters are required")
 		return
 	}
 
 	from, err := time.Parse("01-2006", fromStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format. Use MM-YYYY"})
+		errorResponse(c, http.StatusBadRequest, "Invalid from date format. Use MM-YYYY")
 		return
 	}
 
 	to, err := time.Parse("01-2006", toStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format. Use MM-YYYY"})
+		errorResponse(c, http.StatusBadRequest, "Invalid to date format. Use MM-YYYY")
 		return
 	}
 
-	subscriptions, totalCost, err := app.allModels.Subscriptions.GetSummary(from, to, userID, serviceName)
+	switch groupBy {
+	case "", models.GroupByCategory, models.GroupByTag:
+	default:
+		errorResponse(c, http.StatusBadRequest, "group_by must be category or tag")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, reportQuery)
+	defer cancel()
+
+	if serviceName != "" {
+		if serviceName, err = app.canonicalServiceName(ctx, serviceName); err != nil {
+			queryFailed(c, ctx, http.StatusInternalServerError, "Failed to resolve service")
+			return
+		}
+	}
+
+	subscriptions, totalCost, err := app.allModels.Subscriptions.GetSummary(ctx, from, to, models.SubscriptionFilter{
+		UserID:      userID,
+		ServiceName: serviceName,
+		Tags:        c.QueryArray("tag"),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate summary"})
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to calculate summary")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"total_cost":    totalCost,
 		"subscriptions": subscriptions,
-	})
+	}
+	if groupBy != "" {
+		var categories []*models.Category
+		if groupBy == models.GroupByCategory {
+			if categories, err = app.allModels.Categories.GetAll(ctx); err != nil {
+				queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return categories")
+				return
+			}
+		}
+		resp["groups"] = models.GroupCosts(subscriptions, groupBy, categories)
+	}
+	c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// maxForecastMonths ограничивает горизонт прогноза и длину периода
+// помесячных отчётов: каждый месяц — проход по всем подпискам.
+const maxForecastMonths = 120
+
+// periodTooLong сообщает, что период [from, to] длиннее maxForecastMonths
+// месяцев; обработчик тогда отвечает 400 с periodTooLongMessage.
+func periodTooLong(from, to time.Time) bool {
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	return months > maxForecastMonths
+}
+
+var periodTooLongMessage = fmt.Sprintf("period must not exceed %d months", maxForecastMonths)
+
+type priceChangeRequest struct {
+	EffectiveDate string `json:"effective_date" binding:"required" example:"01-2026"`
+	Price         int    `json:"price" binding:"min=0"`
+}
+
+//********************************************************************//
+//  							 FORECAST							  //
+//********************************************************************//
+
+// getForecast godoc
+// @Summary Прогноз трат на будущие месяцы
+// @Description Помесячный прогноз по активным подпискам с учётом дат окончания и запланированных смен цены
+// @Tags forecast
+// @Produce json
+// @Param months query int false "Горизонт прогноза в месяцах (1-120), по умолчанию 12" example:"12"
+// @Param from query string false "Первый месяц прогноза (формат: MM-YYYY), по умолчанию текущий" example:"01-2026"
+// @Param user_id query string false "UUID пользователя; совместные подписки учитываются его долей" example:"550e8400-e29b-41d4-a716-446655440000"
+// @Success 200 {object} map[string]interface{} "Помесячный ряд и накопленный итог"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /forecast [get]
+func (app *application) getForecast(c *gin.Context) {
+	months := 12
+	if monthsStr := c.Query("months"); monthsStr != "" {
+		n, err := strconv.Atoi(monthsStr)
+		if err != nil || n < 1 || n > maxForecastMonths {
+			errorResponse(c, http.StatusBadRequest, "months must be an integer between 1 and 120")
+			return
+		}
+		months = n
+	}
+
+	from := time.Now().UTC()
+	if fromStr := c.Query("from"); fromStr != "" {
+		t, err := time.Parse("01-2006", fromStr)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid from date format. Use MM-YYYY")
+			return
+		}
+		from = t
+	}
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	ctx, cancel := app.queryContext(c, reportQuery)
+	defer cancel()
+
+	var (
+		subs    []*models.Subscription
+		changes []*models.PriceChange
+		uid     *uuid.UUID
+		err     error
+	)
+	if userID := c.Query("user_id"); userID != "" {
+		var id uuid.UUID
+		if err := id.Scan(userID); err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		uid = &id
+
+		// Совместные подписки входят в прогноз участника его долей.
+		subs, err = app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{UserID: userID})
+		if err == nil {
+			changes, err = app.allModels.PriceChanges.GetByUserID(ctx, id)
+		}
+	} else {
+		subs, err = app.allModels.Subscriptions.GetActive(ctx, from)
+		if err == nil {
+			changes, err = app.allModels.PriceChanges.GetAll(ctx)
+		}
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to calculate forecast")
+		return
+	}
+
+	series, total := models.Forecast(subs, changes, from, months, uid)
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       from.Format("01-2006"),
+		"months":     series,
+		"total_cost": total,
+	})
+}
+
+//********************************************************************//
+//  							 PRICE CHANGES						  //
+//********************************************************************//
+
+// getPriceChanges godoc
+// @Summary Получить запланированные смены цены подписки
+// @Tags forecast
+// @Produce json
+// @Param id path int true "ID записи"
+// @Success 200 {array} models.PriceChange
+// @Failure 400 {object} map[string]string "Неверный формат ID"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /{id}/price-changes [get]
+func (app *application) getPriceChanges(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	changes, err := app.allModels.PriceChanges.GetBySubscriptionID(ctx, id)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return price changes")
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// createPriceChange godoc
+// @Summary Запланировать смену цены подписки
+// @Tags forecast
+// @Accept json
+// @Produce json
+// @Param id path int true "ID записи"
+// @Param change body priceChangeRequest true "Месяц вступления в силу (MM-YYYY) и новая цена"
+// @Success 201 {object} models.PriceChange
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 404 {object} map[string]string "Подписка не найдена"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /{id}/price-changes [post]
+func (app *application) createPriceChange(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req priceChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	effective, err := time.Parse("01-2006", req.EffectiveDate)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid effective_date format. Use MM-YYYY")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	if _, err := app.allModels.Subscriptions.Get(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			errorResponse(c, http.StatusNotFound, "Subscription not found")
+			return
+		}
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return record")
+		return
+	}
+
+	pc := models.PriceChange{
+		SubscriptionID: id,
+		EffectiveDate:  effective,
+		Price:          req.Price,
+	}
+	if err := app.allModels.PriceChanges.Upsert(ctx, &pc); err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save price change")
+		return
+	}
+
+	c.JSON(http.StatusCreated, pc)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed schema.graphql
+var graphqlSchemaSource string
+
+const (
+	// graphqlListComplexity — сколько элементов оценка сложности
+	// предполагает во вложенном списке, у которого нет limit.
+	graphqlListComplexity = 10
+	// graphqlMaxLimit — наибольший limit корневых списков.
+	graphqlMaxLimit = 500
+)
+
+// graphqlRequestBody — тело POST /graphql.
+type graphqlRequestBody struct {
+	Query         string         `json:"query" binding:"required" example:"{ user(id: \"550e8400-e29b-41d4-a716-446655440000\") { subscriptions { serviceName price } } }"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// graphqlRequest — состояние одного GraphQL-запроса. Резолверы берут его
+// из контекста.
+type graphqlRequest struct {
+	loaders *graphqlLoaders
+}
+
+type graphqlRequestKey struct{}
+
+func graphqlRequestFrom(ctx context.Context) *graphqlRequest {
+	return ctx.Value(graphqlRequestKey{}).(*graphqlRequest)
+}
+
+//********************************************************************//
+//  							 HANDLER							  //
+//********************************************************************//
+
+// graphqlHandler разбирает схему один раз при сборке маршрутов: ошибка
+// в схеме или несоответствие резолверам — паника при старте, а не при
+// первом запросе.
+func (app *application) graphqlHandler() gin.HandlerFunc {
+	query := &graphqlQuery{app: app}
+	schema := graphql.MustParseSchema(graphqlSchemaSource, &graphqlRoot{query},
+		graphql.MaxDepth(app.config.GraphQL.MaxDepth),
+		graphql.PanicHandler(graphqlPanicHandler{}),
+	)
+	query.schema = schema.AST()
+
+	return app.graphql(schema, query)
+}
+
+// graphql godoc
+// @Summary Выполнить GraphQL-запрос
+// @Description Пользователи, подписки, сервисы каталога и сводки одним запросом; схема — cmd/api/schema.graphql, доступна и через интроспекцию. Только чтение. Связанные данные загружаются пачками, без запроса на каждый элемент списка. Запрос глубже graphql.max_depth или с оценкой сложности больше graphql.max_complexity отклоняется до выполнения; поле под каждым псевдонимом оценивается отдельно. Ошибки выполнения, как принято в GraphQL, приходят в errors при статусе 200
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body graphqlRequestBody true "GraphQL-запрос"
+// @Param X-Organization-ID header int false "Организация для ключа платформы"
+// @Success 200 {object} map[string]interface{} "data и errors"
+// @Failure 400 {object} map[string]string "Неверное тело запроса"
+// @Router /graphql [post]
+func (app *application) graphql(schema *graphql.Schema, query *graphqlQuery) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body graphqlRequestBody
+		if err := c.ShouldBindJSON(&body); err != nil {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Сложность оценивается только у запроса, прошедшего валидацию;
+		// невалидный отклоняется здесь же, до Exec.
+		if errs := schema.ValidateWithVariables(body.Query, body.Variables); len(errs) > 0 {
+			c.JSON(http.StatusOK, &graphql.Response{Errors: errs})
+			return
+		}
+		if err := app.checkComplexity(query, body); err != nil {
+			if errors.Is(err, errGraphQLSyntax) {
+				// Валидный для graphql-go, но не разобранный оценкой запрос —
+				// расхождение парсеров: отклоняется, но заметно в логах.
+				slog.WarnContext(c.Request.Context(), "graphql complexity estimate failed", "error", err)
+			}
+			c.JSON(http.StatusOK, &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}})
+			return
+		}
+
+		ctx, cancel := app.queryContext(c, reportQuery)
+		defer cancel()
+
+		req := &graphqlRequest{loaders: app.newGraphQLLoaders(ctx)}
+		ctx = context.WithValue(ctx, graphqlRequestKey{}, req)
+
+		resp := schema.Exec(ctx, body.Query, body.OperationName, body.Variables)
+		if errors.Is(ctx.Err(), context.Canceled) {
+			c.Abort()
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// graphqlRoot отдаёт резолвер Query отдельно: иначе graphql-go принял бы
+// поле subscription корневого типа за резолвер операций subscription.
+type graphqlRoot struct {
+	query *graphqlQuery
+}
+
+func (r *graphqlRoot) Query() *graphqlQuery {
+	return r.query
+}
+
+// checkComplexity отклоняет запрос, оценка сложности которого больше
+// graphql.max_complexity. Запрос, который не удалось оценить, тоже
+// отклоняется: иначе ограничение можно было бы обойти.
+func (app *application) checkComplexity(query *graphqlQuery, body graphqlRequestBody) error {
+	cost, err := query.complexity(body.Query, body.OperationName, body.Variables)
+	if err != nil {
+		return err
+	}
+	if limit := app.config.GraphQL.MaxComplexity; cost > limit {
+		return fmt.Errorf("query is too complex: estimated %d fields, the limit is %d", cost, limit)
+	}
+	return nil
+}
+
+type graphqlPanicHandler struct{}
+
+// MakePanicError пишет панику резолвера в лог, а клиенту отвечает без
+// подробностей.
+func (graphqlPanicHandler) MakePanicError(ctx context.Context, value any) *gqlerrors.QueryError {
+	slog.ErrorContext(ctx, "graphql resolver panic", "panic", value)
+	return gqlerrors.Errorf("Internal error")
+}
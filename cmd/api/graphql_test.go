@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"subscription-service/internal/config"
+	"subscription-service/internal/metrics"
+	"subscription-service/internal/models"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/graph-gophers/graphql-go"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+const (
+	graphqlUserA = "11111111-1111-1111-1111-111111111111"
+	graphqlUserB = "22222222-2222-2222-2222-222222222222"
+)
+
+// countingRepo считает обращения к хранилищу, через которые GraphQL
+// загружает списки.
+type countingRepo struct {
+	models.SubscriptionRepository
+	finds   atomic.Int32
+	userIDs atomic.Int32
+}
+
+func (r *countingRepo) Find(ctx context.Context, filter models.SubscriptionFilter) ([]*models.Subscription, error) {
+	r.finds.Add(1)
+	return r.SubscriptionRepository.Find(ctx, filter)
+}
+
+func (r *countingRepo) UserIDs(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	r.userIDs.Add(1)
+	return r.SubscriptionRepository.UserIDs(ctx, limit)
+}
+
+// newTestApp собирает маршруты приложения поверх хранилища подписок в
+// памяти; остальные модели в тестах не используются.
+func newTestApp(t *testing.T, cfg config.Config) (http.Handler, *countingRepo) {
+	t.Helper()
+	repo := &countingRepo{SubscriptionRepository: models.NewMemorySubscriptionDB()}
+	app := &application{
+		config: cfg,
+		timeouts: queryTimeouts{
+			readQuery:   time.Second,
+			writeQuery:  time.Second,
+			reportQuery: time.Second,
+		},
+		allModels: models.Models{Subscriptions: repo},
+		metrics:   metrics.New(nil),
+	}
+	return app.routes(), repo
+}
+
+func insertGraphQLSub(t *testing.T, repo models.SubscriptionRepository, org int, service string, price int, user string) *models.Subscription {
+	t.Helper()
+	var uid uuid.UUID
+	if err := uid.Scan(user); err != nil {
+		t.Fatalf("parse uuid %q: %v", user, err)
+	}
+	sub := &models.Subscription{
+		ServiceName: service,
+		Price:       price,
+		UserID:      uid,
+		StartDate:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := repo.Insert(models.WithOrganization(t.Context(), org), sub); err != nil {
+		t.Fatalf("Insert %s for %s: %v", service, user, err)
+	}
+	return sub
+}
+
+type graphqlTestResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, h http.Handler, header http.Header, query string, variables map[string]any) (int, graphqlTestResponse) {
+	t.Helper()
+	body, err := json.Marshal(graphqlRequestBody{Query: query, Variables: variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp graphqlTestResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func aliasedSubscriptions(n int) string {
+	var b strings.Builder
+	b.WriteString("{ users(limit: 10) { id ")
+	for i := range n {
+		fmt.Fprintf(&b, "s%d: subscriptions { id } ", i)
+	}
+	b.WriteString("} }")
+	return b.String()
+}
+
+func TestGraphQLComplexityCountsAliases(t *testing.T) {
+	cfg := config.Default()
+	// users(limit: 10) стоит 1 + 10 за id, каждый псевдоним subscriptions
+	// — ещё 10 + 10*10: один проходит, десять — нет.
+	cfg.GraphQL.MaxComplexity = 500
+	h, repo := newTestApp(t, cfg)
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Netflix", 500, graphqlUserA)
+
+	_, resp := postGraphQL(t, h, nil, aliasedSubscriptions(1), nil)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("single selection: unexpected errors %v", resp.Errors)
+	}
+
+	repo.finds.Store(0)
+	repo.userIDs.Store(0)
+	code, resp := postGraphQL(t, h, nil, aliasedSubscriptions(10), nil)
+	if code != http.StatusOK {
+		t.Fatalf("aliased fan-out: want status 200, got %d", code)
+	}
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "too complex") {
+		t.Fatalf("aliased fan-out: want complexity error, got %v", resp.Errors)
+	}
+	if resp.Data != nil {
+		t.Fatalf("aliased fan-out: want no data, got %v", resp.Data)
+	}
+	if n := repo.finds.Load() + repo.userIDs.Load(); n != 0 {
+		t.Fatalf("aliased fan-out must be rejected before execution, got %d repository calls", n)
+	}
+}
+
+func TestGraphQLComplexityEstimate(t *testing.T) {
+	q := &graphqlQuery{app: &application{config: config.Default()}}
+	q.schema = graphql.MustParseSchema(graphqlSchemaSource, &graphqlRoot{q}).AST()
+
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables map[string]any
+		want      int
+	}{
+		{"scalar root", `{ user(id: "x") { id } }`, "", nil, 2},
+		{"default limit", `{ subscriptions { id } }`, "", nil, 1 + 50},
+		{"literal limit", `{ subscriptions(limit: 3) { id price } }`, "", nil, 1 + 2*3},
+		{"variable limit", `query Q($n: Int) { subscriptions(limit: $n) { id } }`, "", map[string]any{"n": float64(7)}, 1 + 7},
+		{"missing variable", `query Q($n: Int = 5) { subscriptions(limit: $n) { id } }`, "", nil, 1 + graphqlMaxLimit},
+		{"nested list", `{ user(id: "x") { subscriptions { id } } }`, "", nil, 1 + 1 + 10},
+		{"root aliases", `{ a: subscriptions(limit: 2) { id } b: subscriptions(limit: 2) { id } }`, "", nil, 2 * (1 + 2)},
+		{"fragment", `{ subscriptions(limit: 2) { ...F } } fragment F on Subscription { id price }`, "", nil, 1 + 2*2},
+		{"inline fragment", `{ subscriptions(limit: 2) { ... on Subscription { id } ... @include(if: true) { price } } }`, "", nil, 1 + 2*2},
+		{"typename is free", `{ __typename subscriptions(limit: 2) { __typename id } }`, "", nil, 1 + 2},
+		{"operation name", `query A { services { id } } query B { subscriptions(limit: 1) { id } }`, "B", nil, 1 + 1},
+		{"strings and comments", "{ # comment, }\n user(id: \"}\\\"{\") { id } summary(from: \"01-2025\", to: \"01-2025\") { totalCost } }", "", nil, 2 + 2},
+		{"negative limit", `{ subscriptions(limit: -5) { id } }`, "", nil, 1 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := q.complexity(tt.query, tt.operation, tt.variables)
+			if err != nil {
+				t.Fatalf("complexity: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("complexity: want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// graphqlParserSeeds — документы, на которых разбор по спецификации
+// разошёлся бы с graphql-go: числа с экспонентой и знаком отдельной
+// лексемой, многоточие с пробелами, экранирование в строках, BOM,
+// запятые и комментарии в неожиданных местах.
+var graphqlParserSeeds = []string{
+	`{ user(id: "x") { id } }`,
+	`{ summary(from: "\x30\u0031-2025", to: "01-\"2025") { totalCost } }`,
+	`query Q($n: Int = -1, $id: ID = "x") { subscriptions(limit: $n) { id } user(id: $id) { id } }`,
+	`query Q($ids: [ID!] = ["a", "b"]) { users(ids: $ids, limit: - 5) { id } }`,
+	`{ subscriptions(limit: 10) { . . . on Subscription { id } } }`,
+	"\ufeff{ __typename }",
+	"{,,user(id:\"x\"),{,id,},}#}\r",
+	`query A { services { id } } query B { ...F @include(if: true) } fragment F on Query { services { name } }`,
+	`{ subscriptions(limit: 2) { ... @skip(if: false) { id } ... on Subscription @include(if: true) { price } } }`,
+	`{ сервисы: services { id } b: user(id: "_x-1") { __typename } }`,
+}
+
+// FuzzGraphQLComplexity проверяет свойство, на котором держится оценка
+// сложности: любой документ, который graphql-go принимает, разбирается и
+// здесь. Иначе валидный запрос отклонялся бы как неоцениваемый.
+func FuzzGraphQLComplexity(f *testing.F) {
+	q := &graphqlQuery{app: &application{config: config.Default()}}
+	schema := graphql.MustParseSchema(graphqlSchemaSource, &graphqlRoot{q})
+	q.schema = schema.AST()
+
+	for _, seed := range graphqlParserSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		if errs := schema.Validate(query); len(errs) > 0 {
+			return
+		}
+		if _, err := q.complexity(query, "", nil); err != nil {
+			t.Fatalf("graphql-go accepts %q, the estimate does not: %v", query, err)
+		}
+	})
+}
+
+func TestGraphQLComplexitySyntax(t *testing.T) {
+	q := &graphqlQuery{app: &application{config: config.Default()}}
+	schema := graphql.MustParseSchema(graphqlSchemaSource, &graphqlRoot{q})
+	q.schema = schema.AST()
+
+	for _, query := range graphqlParserSeeds {
+		if errs := schema.Validate(query); len(errs) > 0 {
+			t.Fatalf("seed %q must be valid: %v", query, errs)
+		}
+	}
+
+	// Числа — одна лексема, знак — отдельная, как у graphql-go.
+	tokens, err := lexGraphQL("-1e+5 1.5E-3 0x10 . . .")
+	if want := []string{"-", "1e+5", "1.5E-3", "0x10", ".", ".", "."}; err != nil || !slices.Equal(tokens, want) {
+		t.Fatalf("lex numbers: want %q, got %q, %v", want, tokens, err)
+	}
+
+	// То, что не разбирает graphql-go, — errGraphQLSyntax, а не догадка.
+	for _, query := range []string{
+		`{ user(id: "x) { id } }`,
+		`{ summary(from: "\q", to: "01-2025") { totalCost } }`,
+		`{ subscriptions(limit: 1e) { id } }`,
+		`{ subscriptions(limit: 09) { id } }`,
+		`{ subscriptions { .. on Subscription { id } } }`,
+		`{ user(id: "x") { id } } fragment`,
+		`{ subscriptions { id }`,
+		"{ user(id: \"a\nb\") { id } }",
+	} {
+		if _, err := q.complexity(query, "", nil); !errors.Is(err, errGraphQLSyntax) {
+			t.Errorf("%q: want errGraphQLSyntax, got %v", query, err)
+		}
+	}
+}
+
+func TestGraphQLRejectsBeforeExecution(t *testing.T) {
+	h, repo := newTestApp(t, config.Default())
+
+	code, resp := postGraphQL(t, h, nil, `{ subscriptions { id }`, nil)
+	if code != http.StatusOK || len(resp.Errors) == 0 || resp.Data != nil {
+		t.Fatalf("syntax error: want errors and no data, got %d %v %v", code, resp.Errors, resp.Data)
+	}
+	if n := repo.finds.Load(); n != 0 {
+		t.Fatalf("invalid query must be rejected before execution, got %d Find calls", n)
+	}
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	h, repo := newTestApp(t, config.Default())
+	sub := insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Netflix", 500, graphqlUserA)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions/graphql", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("request without query: want status 400, got %d", rec.Code)
+	}
+
+	code, resp := postGraphQL(t, h, nil, `query($id: Int!) { subscription(id: $id) { serviceName price userId } }`,
+		map[string]any{"id": sub.ID})
+	if code != http.StatusOK || len(resp.Errors) != 0 {
+		t.Fatalf("subscription: want status 200 without errors, got %d %v", code, resp.Errors)
+	}
+	got := resp.Data["subscription"].(map[string]any)
+	if got["serviceName"] != "Netflix" || got["price"] != float64(500) || got["userId"] != graphqlUserA {
+		t.Fatalf("subscription: unexpected data %v", got)
+	}
+
+	_, resp = postGraphQL(t, h, nil, `{ subscription(id: 987654) { id } }`, nil)
+	if len(resp.Errors) != 0 || resp.Data["subscription"] != nil {
+		t.Fatalf("missing subscription: want null without errors, got %v %v", resp.Data, resp.Errors)
+	}
+
+	_, resp = postGraphQL(t, h, nil, `{ subscriptions(limit: 0) { id } }`, nil)
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "limit") {
+		t.Fatalf("invalid limit: want limit error, got %v", resp.Errors)
+	}
+
+	_, resp = postGraphQL(t, h, nil, `{ missing }`, nil)
+	if len(resp.Errors) == 0 {
+		t.Fatal("unknown field: want validation error")
+	}
+}
+
+func TestGraphQLLoadersBatchAndCache(t *testing.T) {
+	h, repo := newTestApp(t, config.Default())
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Netflix", 500, graphqlUserA)
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Spotify", 300, graphqlUserA)
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Netflix", 500, graphqlUserB)
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Apple", 200, "33333333-3333-3333-3333-333333333333")
+
+	_, resp := postGraphQL(t, h, nil, `{ users { id subscriptions { serviceName } summary(from: "01-2025", to: "03-2025") { totalCost } } }`, nil)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("users: unexpected errors %v", resp.Errors)
+	}
+	if users := resp.Data["users"].([]any); len(users) != 3 {
+		t.Fatalf("users: want 3, got %d", len(users))
+	}
+	if n := repo.finds.Load(); n != 1 {
+		t.Fatalf("subscriptions of 3 users: want 1 batched Find, got %d", n)
+	}
+
+	// Повтор того же пользователя берётся из кэша загрузчика.
+	repo.finds.Store(0)
+	query := `query($id: ID!) { a: user(id: $id) { subscriptions { id } } b: user(id: $id) { subscriptions { price } } }`
+	_, resp = postGraphQL(t, h, nil, query, map[string]any{"id": graphqlUserA})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("repeated user: unexpected errors %v", resp.Errors)
+	}
+	if n := repo.finds.Load(); n != 1 {
+		t.Fatalf("repeated user: want 1 Find, got %d", n)
+	}
+	a := resp.Data["a"].(map[string]any)["subscriptions"].([]any)
+	b := resp.Data["b"].(map[string]any)["subscriptions"].([]any)
+	if len(a) != 2 || len(b) != 2 {
+		t.Fatalf("repeated user: want 2 subscriptions under each alias, got %d and %d", len(a), len(b))
+	}
+}
+
+func TestGraphQLDepthLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.GraphQL.MaxDepth = 3
+	h, repo := newTestApp(t, cfg)
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Netflix", 500, graphqlUserA)
+
+	query := `query($id: ID!) { user(id: $id) { subscriptions { user { subscriptions { id } } } } }`
+	code, resp := postGraphQL(t, h, nil, query, map[string]any{"id": graphqlUserA})
+	if code != http.StatusOK || len(resp.Errors) == 0 {
+		t.Fatalf("deep query: want errors with status 200, got %d %v", code, resp.Errors)
+	}
+	if resp.Data != nil {
+		t.Fatalf("deep query: want no data, got %v", resp.Data)
+	}
+	if n := repo.finds.Load(); n != 0 {
+		t.Fatalf("deep query must be rejected before execution, got %d Find calls", n)
+	}
+}
+
+func TestGraphQLTenantScoping(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "graphql-test-secret"
+	h, repo := newTestApp(t, cfg)
+	own := insertGraphQLSub(t, repo, 2, "Spotify", 300, graphqlUserA)
+	foreign := insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Netflix", 500, graphqlUserA)
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Apple", 200, graphqlUserB)
+
+	org := 2
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims{
+		OrgID:            &org,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "tester"},
+	}).SignedString([]byte(cfg.Auth.JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}}
+
+	_, resp := postGraphQL(t, h, header, `{ users { id subscriptions { id organizationId } } }`, nil)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("users: unexpected errors %v", resp.Errors)
+	}
+	users := resp.Data["users"].([]any)
+	if len(users) != 1 || users[0].(map[string]any)["id"] != graphqlUserA {
+		t.Fatalf("users: want only %s of organization 2, got %v", graphqlUserA, users)
+	}
+	subs := users[0].(map[string]any)["subscriptions"].([]any)
+	if len(subs) != 1 || subs[0].(map[string]any)["id"] != float64(own.ID) {
+		t.Fatalf("user subscriptions through the loader: want only %d, got %v", own.ID, subs)
+	}
+
+	_, resp = postGraphQL(t, h, header, `query($id: Int!) { subscription(id: $id) { id } }`, map[string]any{"id": foreign.ID})
+	if len(resp.Errors) != 0 || resp.Data["subscription"] != nil {
+		t.Fatalf("foreign subscription: want null, got %v %v", resp.Data, resp.Errors)
+	}
+
+	// Без токена действует организация по умолчанию.
+	_, resp = postGraphQL(t, h, nil, `query($id: ID!) { user(id: $id) { subscriptions { id } } }`, map[string]any{"id": graphqlUserA})
+	subs = resp.Data["user"].(map[string]any)["subscriptions"].([]any)
+	if len(subs) != 1 || subs[0].(map[string]any)["id"] != float64(foreign.ID) {
+		t.Fatalf("default organization: want only %d, got %v", foreign.ID, subs)
+	}
+}
+
+func TestGraphQLSummaryChecks(t *testing.T) {
+	h, repo := newTestApp(t, config.Default())
+	insertGraphQLSub(t, repo, models.DefaultOrganizationID, "Netflix", 1_000_000_000, graphqlUserA)
+
+	tests := []struct {
+		name, query, want string
+	}{
+		{"reversed period", `{ summary(from: "02-2025", to: "01-2025") { totalCost } }`, "from must not be after to"},
+		{"reversed user period", `{ users(ids: ["` + graphqlUserA + `"]) { summary(from: "02-2025", to: "01-2025") { totalCost } } }`, "from must not be after to"},
+		{"total overflow", `{ summary(from: "01-2025", to: "12-2027") { totalCost } }`, "totalCost 36000000000 does not fit in Int"},
+		{"cost overflow", `{ summary(from: "01-2025", to: "12-2027") { subscriptions { cost } } }`, "cost 36000000000 does not fit in Int"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, resp := postGraphQL(t, h, nil, tc.query, nil)
+			if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tc.want) {
+				t.Fatalf("want error %q, got %+v", tc.want, resp.Errors)
+			}
+		})
+	}
+
+	_, resp := postGraphQL(t, h, nil, `{ summary(from: "01-2025", to: "01-2025") { totalCost } }`, nil)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("one month: unexpected errors %+v", resp.Errors)
+	}
+	if got := resp.Data["summary"].(map[string]any)["totalCost"]; got != float64(1_000_000_000) {
+		t.Fatalf("one month: want totalCost 1000000000, got %v", got)
+	}
+}
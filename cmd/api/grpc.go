@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"subscription-service/internal/models"
+	subscriptionv1 "subscription-service/proto/subscription/v1"
+	"time"
+
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// grpcScopes — область доступа, которую требует каждый метод
+// SubscriptionService. Методы вне списка (health и reflection, если она
+// включена) открыты.
+var grpcScopes = map[string]string{
+	subscriptionv1.SubscriptionService_GetSubscription_FullMethodName:    models.ScopeRead,
+	subscriptionv1.SubscriptionService_ListSubscriptions_FullMethodName:  models.ScopeRead,
+	subscriptionv1.SubscriptionService_GetSummary_FullMethodName:         models.ScopeRead,
+	subscriptionv1.SubscriptionService_CreateSubscription_FullMethodName: models.ScopeWrite,
+	subscriptionv1.SubscriptionService_UpdateSubscription_FullMethodName: models.ScopeWrite,
+	subscriptionv1.SubscriptionService_DeleteSubscription_FullMethodName: models.ScopeWrite,
+}
+
+// grpcService — gRPC API подписок поверх тех же моделей, что и REST.
+type grpcService struct {
+	subscriptionv1.UnimplementedSubscriptionServiceServer
+	app *application
+}
+
+// grpcServer собирает gRPC-сервер с SubscriptionService и стандартным
+// health-сервисом; reflection — только при grpc.reflection. health
+// отдаётся отдельно, чтобы при остановке перевести его в NOT_SERVING.
+func (app *application) grpcServer() (*grpc.Server, *health.Server) {
+	interceptors := []grpc.UnaryServerInterceptor{
+		app.metrics.UnaryServerInterceptor(),
+		grpcAccessLog,
+	}
+	if app.limiter != nil {
+		interceptors = append(interceptors, app.grpcRateLimitByIP, app.grpcAuthenticate, app.grpcRateLimit)
+	} else {
+		interceptors = append(interceptors, app.grpcAuthenticate)
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+
+	subscriptionv1.RegisterSubscriptionServiceServer(server, &grpcService{app: app})
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	healthServer.SetServingStatus(subscriptionv1.SubscriptionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	if app.config.GRPC.Reflection {
+		reflection.Register(server)
+	}
+	return server, healthServer
+}
+
+// stopGRPC дожидается завершения начатых вызовов, но не дольше ctx, и
+// затем обрывает оставшиеся.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+//********************************************************************//
+//  							 AUTH								  //
+//********************************************************************//
+
+// grpcAuthenticate проверяет учётные данные из метаданных authorization,
+// область доступа метода и кладёт в контекст организацию — так же, как
+// authenticate, tenant и authorize для REST.
+func (app *application) grpcAuthenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	scope, ok := grpcScopes[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	p, err := app.grpcPrincipal(ctx, first("authorization"))
+	if err != nil {
+		return nil, err
+	}
+	org, err := app.grpcTenant(ctx, p, first(strings.ToLower(organizationHeader)))
+	if err != nil {
+		return nil, err
+	}
+
+	if p != nil && !models.HasScope(p.Scopes, scope) {
+		return nil, status.Error(codes.PermissionDenied, "Missing required scope: "+scope)
+	}
+	if p != nil {
+		ctx = context.WithValue(ctx, grpcCallerKey{}, p.caller())
+	}
+	return handler(withTenant(ctx, org), req)
+}
+
+// grpcCallerKey — ключ контекста, под которым grpcAuthenticate оставляет
+// идентификатор вызывающего для лимитера, как callerContextKey в REST.
+type grpcCallerKey struct{}
+
+func (app *application) grpcPrincipal(ctx context.Context, header string) (*principal, error) {
+	qctx, cancel := app.budgetContext(ctx, writeQuery)
+	defer cancel()
+
+	p, err := app.resolvePrincipal(qctx, header)
+	if err != nil {
+		var authErr *authError
+		if !errors.As(err, &authErr) {
+			slog.ErrorContext(ctx, "authenticate api key", "error", err)
+		}
+		return nil, grpcError(qctx, err, "Failed to verify API key")
+	}
+	return p, nil
+}
+
+func (app *application) grpcTenant(ctx context.Context, p *principal, header string) (int, error) {
+	qctx, cancel := app.budgetContext(ctx, readQuery)
+	defer cancel()
+
+	org, err := app.resolveTenant(qctx, p, header)
+	if err != nil {
+		return 0, grpcError(qctx, err, "Failed to resolve organization")
+	}
+	return org, nil
+}
+
+// grpcError переводит ошибку в статус gRPC по тем же правилам, что
+// queryFailed: истёкший бюджет запроса — DEADLINE_EXCEEDED, отказ в
+// доступе — код, соответствующий HTTP-статусу, остальное — INTERNAL с
+// сообщением message.
+func grpcError(ctx context.Context, err error, message string) error {
+	var authErr *authError
+	switch {
+	case errors.As(err, &authErr):
+		code := codes.InvalidArgument
+		switch authErr.status {
+		case http.StatusUnauthorized:
+			code = codes.Unauthenticated
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusNotFound:
+			code = codes.NotFound
+		}
+		return status.Error(code, authErr.message)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		budget, _ := ctx.Value(queryBudgetKey{}).(queryBudget)
+		return status.Errorf(codes.DeadlineExceeded, "Database query exceeded its %s deadline of %s", budget.kind, budget.timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return status.FromContextError(ctx.Err()).Err()
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "Subscription not found")
+	case errors.Is(err, models.ErrDuplicateSubscription):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, models.ErrUnknownCategory):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrNoOrganization):
+		return status.Error(codes.InvalidArgument, "x-organization-id is required to create subscriptions with a platform key")
+	default:
+		return status.Error(codes.Internal, message)
+	}
+}
+
+//********************************************************************//
+//  							 LIMITS								  //
+//********************************************************************//
+
+// grpcRateLimit ограничивает вызовы SubscriptionService теми же ведрами,
+// что и REST: методы с областью read — класс чтения, остальные — записи.
+// Ключ — вызывающий или, без аутентификации, IP клиента. Заголовки
+// RateLimit-* уходят в метаданные ответа, при превышении —
+// RESOURCE_EXHAUSTED с retry-after.
+func (app *application) grpcRateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	scope, ok := grpcScopes[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+	class := writeRoutes
+	if scope == models.ScopeRead {
+		class = readRoutes
+	}
+	return app.grpcLimit(ctx, req, handler, class, grpcCallerKeyFrom(ctx))
+}
+
+// grpcRateLimitByIP, как rateLimitByIP, ограничивает вызовы с одного IP до
+// проверки учётных данных.
+func (app *application) grpcRateLimitByIP(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if _, ok := grpcScopes[info.FullMethod]; !ok {
+		return handler(ctx, req)
+	}
+	return app.grpcLimit(ctx, req, handler, ipRoutes, "ip:"+grpcClientIP(ctx))
+}
+
+func (app *application) grpcLimit(ctx context.Context, req any, handler grpc.UnaryHandler, class routeClass, key string) (any, error) {
+	res, headers, ok := app.takeRateLimit(ctx, class, key)
+	if !ok {
+		return handler(ctx, req)
+	}
+	// Метаданные ответа, в отличие от заголовков HTTP, повторный SetHeader
+	// дополняет, а не заменяет. Ведро по IP сообщает о себе только при
+	// отказе, иначе ответ несёт состояние ведра вызывающего.
+	if class != ipRoutes || !res.Allowed {
+		md := metadata.MD{}
+		for name, value := range headers {
+			md.Set(name, value)
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			slog.WarnContext(ctx, "set rate limit metadata", "error", err)
+		}
+	}
+
+	if !res.Allowed {
+		return nil, status.Error(codes.ResourceExhausted, "Rate limit exceeded")
+	}
+	return handler(ctx, req)
+}
+
+func grpcCallerKeyFrom(ctx context.Context) string {
+	if caller, _ := ctx.Value(grpcCallerKey{}).(string); caller != "" {
+		return caller
+	}
+	return "ip:" + grpcClientIP(ctx)
+}
+
+// grpcClientIP — адрес клиента без порта, как ClientIP в gin.
+func grpcClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// grpcAccessLog пишет структурированную запись на каждый unary-вызов с
+// теми же уровнями, что accessLog: ошибки сервера — Error, отказы
+// клиенту — Warn.
+func grpcAccessLog(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable, codes.DeadlineExceeded, codes.Unimplemented:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	slog.LogAttrs(ctx, level, "grpc request",
+		slog.String("method", info.FullMethod),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("client_ip", grpcClientIP(ctx)),
+	)
+	return resp, err
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+func (s *grpcService) GetSubscription(ctx context.Context, req *subscriptionv1.GetSubscriptionRequest) (*subscriptionv1.Subscription, error) {
+	ctx, cancel := s.app.budgetContext(ctx, readQuery)
+	defer cancel()
+
+	sub, err := s.app.allModels.Subscriptions.Get(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(ctx, err, "Failed to get subscription")
+	}
+	return subscriptionToProto(sub), nil
+}
+
+func (s *grpcService) ListSubscriptions(ctx context.Context, req *subscriptionv1.ListSubscriptionsRequest) (*subscriptionv1.ListSubscriptionsResponse, error) {
+	ctx, cancel := s.app.budgetContext(ctx, readQuery)
+	defer cancel()
+
+	if err := validUserID(req.GetUserId()); err != nil {
+		return nil, err
+	}
+	serviceName, err := s.serviceName(ctx, req.GetServiceName())
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{
+		UserID:      req.GetUserId(),
+		ServiceName: serviceName,
+		Tags:        req.GetTags(),
+	})
+	if err != nil {
+		return nil, grpcError(ctx, err, "Failed to return subscriptions")
+	}
+
+	resp := &subscriptionv1.ListSubscriptionsResponse{
+		Subscriptions: make([]*subscriptionv1.Subscription, 0, len(subs)),
+	}
+	for _, sub := range subs {
+		resp.Subscriptions = append(resp.Subscriptions, subscriptionToProto(sub))
+	}
+	return resp, nil
+}
+
+func (s *grpcService) GetSummary(ctx context.Context, req *subscriptionv1.GetSummaryRequest) (*subscriptionv1.GetSummaryResponse, error) {
+	from, err := time.Parse("01-2006", req.GetFrom())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid from date format. Use MM-YYYY")
+	}
+	to, err := time.Parse("01-2006", req.GetTo())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid to date format. Use MM-YYYY")
+	}
+	if err := validUserID(req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.app.budgetContext(ctx, reportQuery)
+	defer cancel()
+
+	serviceName, err := s.serviceName(ctx, req.GetServiceName())
+	if err != nil {
+		return nil, err
+	}
+
+	subs, total, err := s.app.allModels.Subscriptions.GetSummary(ctx, from, to, models.SubscriptionFilter{
+		UserID:      req.GetUserId(),
+		ServiceName: serviceName,
+		Tags:        req.GetTags(),
+	})
+	if err != nil {
+		return nil, grpcError(ctx, err, "Failed to calculate summary")
+	}
+
+	resp := &subscriptionv1.GetSummaryResponse{
+		TotalCost:     int64(total),
+		Subscriptions: make([]*subscriptionv1.SubscriptionCost, 0, len(subs)),
+	}
+	for _, sub := range subs {
+		resp.Subscriptions = append(resp.Subscriptions, &subscriptionv1.SubscriptionCost{
+			Subscription: subscriptionToProto(&sub.Subscription),
+			DateFrom:     sub.DateFrom.Format("01-2006"),
+			DateTo:       sub.DateTo.Format("01-2006"),
+			Cost:         int64(sub.Cost),
+			FullCost:     int64(sub.FullCost),
+		})
+	}
+	return resp, nil
+}
+
+//********************************************************************//
+//  							 WRITE								  //
+//********************************************************************//
+
+func (s *grpcService) CreateSubscription(ctx context.Context, req *subscriptionv1.CreateSubscriptionRequest) (*subscriptionv1.Subscription, error) {
+	sub, err := subscriptionFromProto(req.GetSubscription())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.app.budgetContext(ctx, writeQuery)
+	defer cancel()
+
+	if err := s.app.applyCatalog(ctx, &sub); err != nil {
+		return nil, grpcError(ctx, err, "Failed to resolve service")
+	}
+	if err := s.app.allModels.Subscriptions.Insert(ctx, &sub); err != nil {
+		return nil, grpcError(ctx, err, "Failed to create subscription")
+	}
+	return subscriptionToProto(&sub), nil
+}
+
+func (s *grpcService) UpdateSubscription(ctx context.Context, req *subscriptionv1.UpdateSubscriptionRequest) (*subscriptionv1.Subscription, error) {
+	sub, err := subscriptionFromProto(req.GetSubscription())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.app.budgetContext(ctx, writeQuery)
+	defer cancel()
+
+	if err := s.app.applyCatalog(ctx, &sub); err != nil {
+		return nil, grpcError(ctx, err, "Failed to resolve service")
+	}
+	if err := s.app.allModels.Subscriptions.Update(ctx, sub); err != nil {
+		return nil, grpcError(ctx, err, "Failed to update subscription")
+	}
+
+	// Участники и организация в запросе не передаются, поэтому отвечаем
+	// подпиской в том виде, в каком она теперь хранится.
+	updated, err := s.app.allModels.Subscriptions.Get(ctx, sub.ID)
+	if err != nil {
+		return nil, grpcError(ctx, err, "Failed to get subscription")
+	}
+	return subscriptionToProto(updated), nil
+}
+
+func (s *grpcService) DeleteSubscription(ctx context.Context, req *subscriptionv1.DeleteSubscriptionRequest) (*subscriptionv1.DeleteSubscriptionResponse, error) {
+	ctx, cancel := s.app.budgetContext(ctx, writeQuery)
+	defer cancel()
+
+	if err := s.app.allModels.Subscriptions.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, grpcError(ctx, err, "Failed to delete subscription")
+	}
+	return &subscriptionv1.DeleteSubscriptionResponse{}, nil
+}
+
+//********************************************************************//
+//  							 MAPPING							  //
+//********************************************************************//
+
+// serviceName сводит имя сервиса из запроса к каноническому; пустое
+// имя не ограничивает выборку.
+func (s *grpcService) serviceName(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	name, err := s.app.canonicalServiceName(ctx, name)
+	if err != nil {
+		return "", grpcError(ctx, err, "Failed to resolve service")
+	}
+	return name, nil
+}
+
+func validUserID(id string) error {
+	if id == "" {
+		return nil
+	}
+	var uid uuid.UUID
+	if err := uid.Scan(id); err != nil {
+		return status.Error(codes.InvalidArgument, "Invalid user_id")
+	}
+	return nil
+}
+
+// subscriptionFromProto разбирает подписку из запроса через MidwaySub,
+// чтобы даты и теги проверялись так же, как в REST.
+func subscriptionFromProto(pb *subscriptionv1.Subscription) (models.Subscription, error) {
+	if pb == nil {
+		return models.Subscription{}, status.Error(codes.InvalidArgument, "subscription is required")
+	}
+
+	mid := models.MidwaySub{
+		ID:          int(pb.GetId()),
+		ServiceName: pb.GetServiceName(),
+		Price:       int(pb.GetPrice()),
+		StartDate:   pb.GetStartDate(),
+		EndDate:     pb.GetEndDate(),
+		Tags:        pb.GetTags(),
+	}
+	if err := mid.UserID.Scan(pb.GetUserId()); err != nil {
+		return models.Subscription{}, status.Error(codes.InvalidArgument, "Invalid user_id")
+	}
+	if pb.CategoryId != nil {
+		id := int(pb.GetCategoryId())
+		mid.CategoryID = &id
+	}
+
+	sub, err := mid.FromMidwaySub()
+	if err != nil {
+		return models.Subscription{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return sub, nil
+}
+
+func subscriptionToProto(sub *models.Subscription) *subscriptionv1.Subscription {
+	pb := &subscriptionv1.Subscription{
+		Id:             int64(sub.ID),
+		ServiceName:    sub.ServiceName,
+		Price:          int64(sub.Price),
+		UserId:         uuidString(sub.UserID),
+		StartDate:      sub.StartDate.Format("01-2006"),
+		Tags:           sub.Tags,
+		OrganizationId: int64(sub.OrganizationID),
+	}
+	if sub.EndDate != nil {
+		pb.EndDate = sub.EndDate.Format("01-2006")
+	}
+	if sub.CategoryID != nil {
+		id := int64(*sub.CategoryID)
+		pb.CategoryId = &id
+	}
+	for _, m := range sub.Members {
+		pb.Members = append(pb.Members, &subscriptionv1.Member{
+			UserId: uuidString(m.UserID),
+			Weight: int32(m.Weight),
+		})
+	}
+	return pb
+}
+
+func uuidString(id uuid.UUID) string {
+	if id.Status != pgtype.Present {
+		return ""
+	}
+	return id.UUID.String()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net"
+	"subscription-service/internal/config"
+	"subscription-service/internal/metrics"
+	"subscription-service/internal/models"
+	subscriptionv1 "subscription-service/proto/subscription/v1"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCTestClient поднимает gRPC-сервер приложения поверх bufconn.
+func newGRPCTestClient(t *testing.T, app *application) subscriptionv1.SubscriptionServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server, _ := app.grpcServer()
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return subscriptionv1.NewSubscriptionServiceClient(conn)
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "grpc-test-secret"
+	cfg.RateLimit.ReadPerMinute, cfg.RateLimit.ReadBurst = 1, 2
+	cfg.RateLimit.WritePerMinute, cfg.RateLimit.WriteBurst = 1, 1
+
+	app := &application{
+		config: cfg,
+		timeouts: queryTimeouts{
+			readQuery:   time.Second,
+			writeQuery:  time.Second,
+			reportQuery: time.Second,
+		},
+		allModels: models.Models{Subscriptions: models.NewMemorySubscriptionDB()},
+		metrics:   metrics.New(nil),
+		limiter:   newRateLimiter(models.NewMemoryRateLimitStore(), cfg.RateLimit),
+	}
+	client := newGRPCTestClient(t, app)
+
+	list := func(ctx context.Context) (metadata.MD, error) {
+		var header metadata.MD
+		_, err := client.ListSubscriptions(ctx, &subscriptionv1.ListSubscriptionsRequest{}, grpc.Header(&header))
+		return header, err
+	}
+
+	// Анонимные вызовы делят ведро чтения по IP.
+	for i := range 2 {
+		header, err := list(t.Context())
+		if err != nil {
+			t.Fatalf("anonymous call %d: %v", i+1, err)
+		}
+		if got := header.Get("ratelimit-limit"); len(got) != 1 || got[0] != "2" {
+			t.Fatalf("anonymous call %d: ratelimit-limit = %v, want [2]", i+1, got)
+		}
+	}
+	header, err := list(t.Context())
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("third anonymous call: want RESOURCE_EXHAUSTED, got %v", err)
+	}
+	if len(header.Get("retry-after")) != 1 {
+		t.Fatalf("third anonymous call: missing retry-after, got %v", header)
+	}
+
+	// Аутентифицированный вызывающий получает собственное ведро.
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "tester"},
+	}).SignedString([]byte(cfg.Auth.JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authed := metadata.AppendToOutgoingContext(t.Context(), "authorization", "Bearer "+token)
+	if _, err := list(authed); err != nil {
+		t.Fatalf("authenticated call: %v", err)
+	}
+
+	// Запись считается отдельно от чтения.
+	remove := func() error {
+		_, err := client.DeleteSubscription(authed, &subscriptionv1.DeleteSubscriptionRequest{Id: 999})
+		return err
+	}
+	if err := remove(); status.Code(err) != codes.NotFound {
+		t.Fatalf("first write: want NOT_FOUND from the handler, got %v", err)
+	}
+	if err := remove(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second write: want RESOURCE_EXHAUSTED, got %v", err)
+	}
+}
+
+func TestGRPCReflectionIsOptIn(t *testing.T) {
+	for _, reflection := range []bool{false, true} {
+		cfg := config.Default()
+		cfg.GRPC.Reflection = reflection
+		app := &application{config: cfg, metrics: metrics.New(nil)}
+
+		server, _ := app.grpcServer()
+		_, registered := server.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]
+		if registered != reflection {
+			t.Errorf("grpc.reflection=%v: reflection registered=%v", reflection, registered)
+		}
+	}
+}
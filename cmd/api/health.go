@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const healthCheckTimeout = 2 * time.Second
+
+type healthCheck struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+//********************************************************************//
+//  							 HEALTH								  //
+//********************************************************************//
+
+// healthz — проверка живости: отвечает 200, пока процесс обслуживает запросы.
+func (app *application) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz — проверка готовности: сервер не останавливается, Postgres доступен
+// и версия миграций совпадает с models.SchemaVersion. Состояние фоновых
+// воркеров сообщается, но на готовность не влияет.
+func (app *application) readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	checks := []healthCheck{
+		runCheck("shutdown", func() error {
+			if !app.ready.Load() {
+				return fmt.Errorf("server is shutting down")
+			}
+			return nil
+		}),
+		runCheck("postgres", func() error {
+			return app.allModels.Schema.Ping(ctx)
+		}),
+		runCheck("migrations", func() error {
+			version, dirty, err := app.allModels.Schema.Version(ctx)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return fmt.Errorf("schema version %d is dirty", version)
+			}
+			if version != models.SchemaVersion {
+				return fmt.Errorf("schema version %d, expected %d", version, models.SchemaVersion)
+			}
+			return nil
+		}),
+	}
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, code = "not_ready", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.JSON(code, gin.H{
+		"status":  status,
+		"checks":  checks,
+		"workers": app.workers.status(),
+	})
+}
+
+func runCheck(name string, check func() error) healthCheck {
+	start := time.Now()
+	err := check()
+
+	result := healthCheck{
+		Name:      name,
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"subscription-service/internal/models"
+	"sync"
+	"time"
+)
+
+// loaderWait — сколько загрузчик копит ключи перед запросом. Резолверы
+// соседних элементов списка выполняются параллельно и успевают попасть в
+// одну пачку.
+const loaderWait = 2 * time.Millisecond
+
+// loader собирает ключи, запрошенные резолверами за loaderWait, и
+// загружает их одним вызовом fetch — вместо запроса на каждый элемент
+// списка. Результаты кэшируются до конца GraphQL-запроса. Ключ, которого
+// нет в ответе fetch, получает нулевое значение.
+type loader[K comparable, V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	cache   map[K]*loaderCall[V]
+	pending map[K]*loaderCall[V]
+}
+
+type loaderCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// newLoader создаёт загрузчик, который обращается к БД с контекстом ctx —
+// общим для всего GraphQL-запроса, с его арендатором и бюджетом времени.
+func newLoader[K comparable, V any](ctx context.Context, fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		ctx:     ctx,
+		fetch:   fetch,
+		cache:   make(map[K]*loaderCall[V]),
+		pending: make(map[K]*loaderCall[V]),
+	}
+}
+
+func (l *loader[K, V]) load(key K) (V, error) {
+	l.mu.Lock()
+	call, ok := l.cache[key]
+	if !ok {
+		call = &loaderCall[V]{done: make(chan struct{})}
+		l.cache[key] = call
+		l.pending[key] = call
+		if len(l.pending) == 1 {
+			time.AfterFunc(loaderWait, l.dispatch)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-l.ctx.Done():
+		var zero V
+		return zero, l.ctx.Err()
+	}
+}
+
+// dispatch загружает накопленные ключи одной пачкой.
+func (l *loader[K, V]) dispatch() {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = make(map[K]*loaderCall[V])
+	l.mu.Unlock()
+
+	keys := make([]K, 0, len(batch))
+	for key := range batch {
+		keys = append(keys, key)
+	}
+
+	values, err := l.fetch(l.ctx, keys)
+	for key, call := range batch {
+		call.value, call.err = values[key], err
+		close(call.done)
+	}
+}
+
+// graphqlLoaders — загрузчики одного GraphQL-запроса.
+type graphqlLoaders struct {
+	// subscriptionsByUser — подписки, где пользователь плательщик или
+	// участник, по UUID пользователя.
+	subscriptionsByUser *loader[string, []*models.Subscription]
+	// subscriptionsByService — подписки по каноническому имени сервиса.
+	subscriptionsByService *loader[string, []*models.Subscription]
+	// services — сервисы каталога по нормализованному имени или псевдониму.
+	// Каталог невелик и загружается целиком один раз.
+	services *loader[string, *models.Service]
+}
+
+func (app *application) newGraphQLLoaders(ctx context.Context) *graphqlLoaders {
+	var (
+		catalogOnce sync.Once
+		catalog     map[string]*models.Service
+		catalogErr  error
+	)
+
+	return &graphqlLoaders{
+		subscriptionsByUser: newLoader(ctx, func(ctx context.Context, uids []string) (map[string][]*models.Subscription, error) {
+			subs, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{UserIDs: uids})
+			if err != nil {
+				return nil, err
+			}
+			byUser := make(map[string][]*models.Subscription, len(uids))
+			for _, sub := range subs {
+				byUser[sub.UserID.UUID.String()] = append(byUser[sub.UserID.UUID.String()], sub)
+				for _, m := range sub.Members {
+					if m.UserID.UUID != sub.UserID.UUID {
+						byUser[m.UserID.UUID.String()] = append(byUser[m.UserID.UUID.String()], sub)
+					}
+				}
+			}
+			return byUser, nil
+		}),
+
+		subscriptionsByService: newLoader(ctx, func(ctx context.Context, names []string) (map[string][]*models.Subscription, error) {
+			subs, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{ServiceNames: names})
+			if err != nil {
+				return nil, err
+			}
+			byService := make(map[string][]*models.Subscription, len(names))
+			for _, sub := range subs {
+				byService[sub.ServiceName] = append(byService[sub.ServiceName], sub)
+			}
+			return byService, nil
+		}),
+
+		services: newLoader(ctx, func(ctx context.Context, names []string) (map[string]*models.Service, error) {
+			catalogOnce.Do(func() {
+				var services []*models.Service
+				if services, catalogErr = app.allModels.Services.GetAll(ctx); catalogErr != nil {
+					return
+				}
+				catalog = make(map[string]*models.Service)
+				for _, s := range services {
+					catalog[models.NormalizeServiceName(s.Name)] = s
+					for _, alias := range s.Aliases {
+						catalog[alias] = s
+					}
+				}
+			})
+			return catalog, catalogErr
+		}),
+	}
+}
+
+// service возвращает сервис каталога по имени или псевдониму; nil, если
+// сервиса нет в каталоге.
+func (l *graphqlLoaders) service(name string) (*models.Service, error) {
+	return l.services.load(models.NormalizeServiceName(name))
+}
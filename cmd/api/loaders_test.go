@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoaderBatchesAndCaches(t *testing.T) {
+	var calls atomic.Int32
+	l := newLoader(t.Context(), func(ctx context.Context, keys []int) (map[int]string, error) {
+		calls.Add(1)
+		values := make(map[int]string, len(keys))
+		for _, k := range keys {
+			if k != 0 {
+				values[k] = string(rune('a' + k))
+			}
+		}
+		return values, nil
+	})
+
+	var wg sync.WaitGroup
+	got := make([]string, 4)
+	for i := range got {
+		wg.Go(func() {
+			v, err := l.load(i)
+			if err != nil {
+				t.Errorf("load(%d): %v", i, err)
+			}
+			got[i] = v
+		})
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("concurrent loads: want 1 fetch, got %d", n)
+	}
+	if got[0] != "" || got[1] != "b" || got[3] != "d" {
+		t.Fatalf("concurrent loads: unexpected values %q", got)
+	}
+
+	if v, err := l.load(3); err != nil || v != "d" {
+		t.Fatalf("cached load: want %q, got %q, %v", "d", v, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("cached load: want no new fetch, got %d fetches", n)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	l := newLoader(t.Context(), func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, errFetch
+	})
+	if _, err := l.load("a"); !errors.Is(err, errFetch) {
+		t.Fatalf("load: want fetch error, got %v", err)
+	}
+}
+
+func TestLoaderContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	l := newLoader(ctx, func(ctx context.Context, keys []string) (map[string]int, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if _, err := l.load("a"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("load with canceled context: want context.Canceled, got %v", err)
+	}
+}
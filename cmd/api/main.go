@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
 	_ "subscription-service/docs"
-	"subscription-service/internal/env"
+	"subscription-service/internal/config"
+	"subscription-service/internal/logging"
+	"subscription-service/internal/metrics"
+	"subscription-service/internal/migrations"
 	"subscription-service/internal/models"
+	"subscription-service/internal/tracing"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/jackc/pgx/v4/stdlib"
 	_ "github.com/joho/godotenv/autoload"
@@ -16,29 +27,117 @@ import (
 // @description API для управления подписками пользователей
 // @host localhost:8080
 // @BasePath /api/subscriptions
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description "ApiKey <ключ>" для сервисных клиентов или "Bearer <JWT>" для пользователей. Данные видны только организации ключа или claim org_id токена
+
+const serviceName = "subscription-service"
 
 type application struct {
-	port      int
-	jwtSecret string
+	config    config.Config
+	timeouts  queryTimeouts
 	allModels models.Models
+	metrics   *metrics.Metrics
+	limiter   *rateLimiter
+	workers   *workers
+	ready     atomic.Bool
 }
 
 func main() {
-	db, err := sql.Open("pgx", env.GetPostgresDSN())
+	cfg, opts, err := config.Load("subscription-api", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if opts.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	logger, err := logging.NewFromConfig(os.Stdout, cfg.Log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	db, err := sql.Open("pgx", cfg.DB.DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Migrate.OnStart {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Migrate.LockTimeout)
+		err := migrations.Up(ctx, db, cfg.DB)
+		cancel()
+		if err != nil {
+			slog.Error("migrate on start", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.ServiceName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
-	createdModels := models.NewModels(db)
+	appMetrics := metrics.New(db)
+	createdModels := models.NewModels(db, migrations.Tables(cfg.DB))
+	createdModels.Subscriptions = appMetrics.InstrumentSubscriptions(
+		tracing.InstrumentSubscriptions(createdModels.Subscriptions),
+	)
+
 	app := &application{
-		port:      env.GetEnvInt("PORT", 8080),
-		jwtSecret: env.GetEnvString("JWT_SECRET", ""),
+		config: cfg,
+		timeouts: queryTimeouts{
+			readQuery:   cfg.Timeouts.Read,
+			writeQuery:  cfg.Timeouts.Write,
+			reportQuery: cfg.Timeouts.Report,
+		},
 		allModels: createdModels,
+		metrics:   appMetrics,
+		workers:   newWorkers(),
 	}
 
-	if err := app.serve(); err != nil {
-		log.Fatal(err)
+	app.workers.start("business-metrics", appMetrics.BusinessRefresher(
+		createdModels.Subscriptions,
+		cfg.Metrics.RefreshInterval,
+		app.timeouts[reportQuery],
+	))
+
+	if cfg.RateLimit.Enabled {
+		var store models.RateLimitStore = models.NewMemoryRateLimitStore()
+		if cfg.RateLimit.Store == "postgres" {
+			store = &createdModels.RateLimits
+			app.workers.start("rate-limit-prune", app.pruneRateLimits)
+		}
+		app.limiter = newRateLimiter(store, cfg.RateLimit)
+	}
+
+	if cfg.Notifier.Enabled {
+		app.startNotifier()
 	}
 
+	err = app.serve()
+
+	// Пул соединений закрываем только после того, как сервер и воркеры
+	// остановились, чтобы не оборвать незавершённые запросы.
+	if cerr := db.Close(); cerr != nil {
+		slog.Error("closing database pool", "error", cerr)
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if terr := shutdownTracing(flushCtx); terr != nil {
+		slog.Error("flushing traces", "error", terr)
+	}
+
+	if err != nil {
+		slog.Error("server stopped with error", "error", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type membersRequest struct {
+	Members []models.SubscriptionMember `json:"members"`
+}
+
+type membersResponse struct {
+	SubscriptionID int                  `json:"subscription_id"`
+	Shares         []models.MemberShare `json:"shares"`
+}
+
+//********************************************************************//
+//  							 MEMBERS							  //
+//********************************************************************//
+
+// setMembers godoc
+// @Summary Задать участников совместной подписки
+// @Description Стоимость делится между участниками пропорционально weight (по умолчанию 1 — поровну). Плательщик участвует в разделе, только если указан среди участников. Пустой список возвращает всю стоимость плательщику
+// @Tags subscriptions, subscriptions-put
+// @Accept json
+// @Produce json
+// @Param id path int true "ID записи"
+// @Param members body membersRequest true "Участники и их веса"
+// @Success 200 {object} membersResponse "Доли участников в текущей месячной цене"
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 404 {object} map[string]string "Подписка не найдена"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /{id}/members [put]
+func (app *application) setMembers(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var req membersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	members, err := models.NormalizeMembers(req.Members)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.Subscriptions.SetMembers(ctx, id, members)
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(c, http.StatusNotFound, "Subscription not found")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save members")
+		return
+	}
+
+	sub, err := app.allModels.Subscriptions.Get(ctx, id)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return record")
+		return
+	}
+	c.JSON(http.StatusOK, membersResponse{SubscriptionID: id, Shares: sub.Shares(sub.Price)})
+}
+
+// getSettlement godoc
+// @Summary Взаиморасчёты по совместным подпискам за период
+// @Description Для каждой пары участник — плательщик показывает, сколько участник должен за свою долю. С user_id — только долги этого пользователя и долги ему
+// @Tags subscriptions, subscriptions-filter
+// @Produce json
+// @Param from query string true "Начало периода (формат: MM-YYYY)" example:"01-2025"
+// @Param to query string true "Конец периода (формат: MM-YYYY)" example:"12-2025"
+// @Param user_id query string false "UUID пользователя" example:"550e8400-e29b-41d4-a716-446655440000"
+// @Success 200 {object} map[string][]models.Debt
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /settlement [get]
+func (app *application) getSettlement(c *gin.Context) {
+	from, err := time.Parse("01-2006", c.Query("from"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "from is required in MM-YYYY format")
+		return
+	}
+	to, err := time.Parse("01-2006", c.Query("to"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "to is required in MM-YYYY format")
+		return
+	}
+	if from.After(to) {
+		errorResponse(c, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	userID := c.Query("user_id")
+	var uid uuid.UUID
+	if userID != "" {
+		if err := uid.Scan(userID); err != nil {
+			errorResponse(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+	}
+
+	ctx, cancel := app.queryContext(c, reportQuery)
+	defer cancel()
+
+	subs, err := app.allModels.Subscriptions.Find(ctx, models.SubscriptionFilter{UserID: userID})
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return subscriptions")
+		return
+	}
+
+	debts := models.Settle(subs, from, to)
+	if userID != "" {
+		mine := debts[:0]
+		for _, d := range debts {
+			if d.PayerID.UUID == uid.UUID || d.MemberID.UUID == uid.UUID {
+				mine = append(mine, d)
+			}
+		}
+		debts = mine
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": debts})
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"log/slog"
+	"subscription-service/internal/notify"
+)
+
+// startNotifier запускает планировщик напоминаний о продлении внутри
+// процесса API — альтернатива отдельному бинарнику cmd/notifier.
+func (app *application) startNotifier() {
+	scheduler, err := notify.NewScheduler(app.allModels, app.config.Notifier)
+	if err != nil {
+		slog.Warn("notifier disabled", "error", err)
+		return
+	}
+	app.workers.start("notifier", scheduler.Run)
+}
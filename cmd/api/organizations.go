@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"subscription-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type organizationRequest struct {
+	Slug string `json:"slug" binding:"required" example:"acme"`
+	Name string `json:"name" binding:"required" example:"Acme Corp"`
+}
+
+//********************************************************************//
+//  							 ORGANIZATIONS						  //
+//********************************************************************//
+
+// listOrganizations godoc
+// @Summary Получить список организаций
+// @Description Требует область admin и ключ платформы (без организации)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string][]models.Organization
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin или ключ принадлежит организации"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /admin/organizations [get]
+func (app *application) listOrganizations(c *gin.Context) {
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	organizations, err := app.allModels.Organizations.GetAll(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return organizations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": organizations})
+}
+
+// createOrganization godoc
+// @Summary Добавить организацию
+// @Description Требует область admin и ключ платформы. Первый ключ организации выпускается через POST /admin/api-keys с её organization_id; дальше ключами организации управляет её администратор
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param organization body organizationRequest true "Slug и название"
+// @Success 201 {object} models.Organization
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin или ключ принадлежит организации"
+// @Failure 409 {object} map[string]string "Организация с таким slug уже есть"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /admin/organizations [post]
+func (app *application) createOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := models.ValidOrganizationSlug(req.Slug); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if name == "" {
+		errorResponse(c, http.StatusBadRequest, "name must not be blank")
+		return
+	}
+	organization := &models.Organization{Slug: req.Slug, Name: name}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err := app.allModels.Organizations.Insert(ctx, organization)
+	switch {
+	case errors.Is(err, models.ErrDuplicateOrganization):
+		errorResponse(c, http.StatusConflict, err.Error())
+	case err != nil:
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save organization")
+	default:
+		c.JSON(http.StatusCreated, organization)
+	}
+}
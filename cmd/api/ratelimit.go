@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"subscription-service/internal/config"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeClass — класс маршрутов со своим бюджетом запросов.
+type routeClass string
+
+const (
+	readRoutes  routeClass = "read"
+	writeRoutes routeClass = "write"
+	bulkRoutes  routeClass = "bulk"
+	// ipRoutes — общее ведро всех запросов с одного IP, которое
+	// проверяется до аутентификации.
+	ipRoutes routeClass = "ip"
+)
+
+// callerContextKey — ключ gin.Context, под которым аутентификация
+// оставляет идентификатор вызывающего. Без него лимит считается по IP.
+const callerContextKey = "caller"
+
+type rateLimiter struct {
+	store  models.RateLimitStore
+	limits map[routeClass]models.RateLimit
+}
+
+func newRateLimiter(store models.RateLimitStore, cfg config.RateLimitConfig) *rateLimiter {
+	perMinute := func(n, burst int) models.RateLimit {
+		return models.RateLimit{Rate: float64(n) / 60, Burst: burst}
+	}
+	return &rateLimiter{
+		store: store,
+		limits: map[routeClass]models.RateLimit{
+			readRoutes:  perMinute(cfg.ReadPerMinute, cfg.ReadBurst),
+			writeRoutes: perMinute(cfg.WritePerMinute, cfg.WriteBurst),
+			bulkRoutes:  perMinute(cfg.BulkPerMinute, cfg.BulkBurst),
+			ipRoutes:    perMinute(cfg.IPPerMinute, cfg.IPBurst),
+		},
+	}
+}
+
+// classify относит маршрут к классу: массовые удаления считаются отдельно
+// от обычной записи, всё, что не меняет данные, — чтением.
+func classify(c *gin.Context) routeClass {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return readRoutes
+	case http.MethodPost:
+		// GraphQL только читает, хотя запросы приходят POST'ом.
+		if c.FullPath() == "/api/subscriptions/graphql" {
+			return readRoutes
+		}
+	case http.MethodDelete:
+		switch c.FullPath() {
+		case "/api/subscriptions/user/:id", "/api/subscriptions/service/:name":
+			return bulkRoutes
+		}
+	}
+	return writeRoutes
+}
+
+func callerKey(c *gin.Context) string {
+	if caller := c.GetString(callerContextKey); caller != "" {
+		return caller
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimit ограничивает частоту запросов token bucket'ом на пару
+// (класс маршрута, вызывающий). Ответ несёт заголовки RateLimit-*, а при
+// превышении — 429 с Retry-After. Если хранилище недоступно, запрос
+// пропускается: лимитер не должен ронять API вместе с базой.
+func (app *application) rateLimit() gin.HandlerFunc {
+	return app.limitBy(func(c *gin.Context) (routeClass, string) {
+		return classify(c), callerKey(c)
+	})
+}
+
+// rateLimitByIP стоит перед authenticate: проверка API-ключа идёт в базу,
+// и без лимита по IP поток неверных учётных данных обходил бы rateLimit,
+// который знает вызывающего только после аутентификации.
+func (app *application) rateLimitByIP() gin.HandlerFunc {
+	return app.limitBy(func(c *gin.Context) (routeClass, string) {
+		return ipRoutes, "ip:" + c.ClientIP()
+	})
+}
+
+func (app *application) limitBy(bucket func(c *gin.Context) (routeClass, string)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		class, key := bucket(c)
+		res, headers, ok := app.takeRateLimit(c.Request.Context(), class, key)
+		if !ok {
+			c.Next()
+			return
+		}
+		for name, value := range headers {
+			c.Header(name, value)
+		}
+
+		if !res.Allowed {
+			errorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// takeRateLimit списывает токен из ведра класса class для вызывающего key
+// и возвращает заголовки RateLimit-* (и Retry-After при отказе). ok ложно,
+// если хранилище недоступно и запрос нужно пропустить без лимита.
+func (app *application) takeRateLimit(ctx context.Context, class routeClass, key string) (models.RateLimitResult, map[string]string, bool) {
+	limit := app.limiter.limits[class]
+
+	qctx, cancel := context.WithTimeout(ctx, app.timeouts[writeQuery])
+	res, err := app.limiter.store.Take(qctx, string(class)+":"+key, limit, time.Now())
+	cancel()
+	if err != nil {
+		slog.WarnContext(ctx, "rate limit store unavailable, allowing request", "error", err)
+		return res, nil, false
+	}
+
+	window := int(math.Ceil(float64(limit.Burst) / limit.Rate))
+	headers := map[string]string{
+		"RateLimit-Policy":    fmt.Sprintf("%d;w=%d", limit.Burst, window),
+		"RateLimit-Limit":     strconv.Itoa(limit.Burst),
+		"RateLimit-Remaining": strconv.Itoa(res.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(ceilSeconds(res.Reset)),
+	}
+	if !res.Allowed {
+		headers["Retry-After"] = strconv.Itoa(ceilSeconds(res.RetryAfter))
+	}
+	return res, headers, true
+}
+
+// pruneRateLimits — фоновая очистка ведер в Postgres, которые давно
+// наполнились и больше ничего не ограничивают.
+func (app *application) pruneRateLimits(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			qctx, cancel := context.WithTimeout(ctx, app.timeouts[writeQuery])
+			n, err := app.allModels.RateLimits.Prune(qctx, time.Now().Add(-time.Hour))
+			cancel()
+			if err != nil {
+				slog.Error("prune rate limits", "error", err)
+				continue
+			}
+			slog.Debug("pruned rate limits", "rows", n)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"subscription-service/internal/config"
+	"subscription-service/internal/metrics"
+	"subscription-service/internal/models"
+	"testing"
+	"time"
+)
+
+func newRateLimitTestApp(cfg config.Config) http.Handler {
+	app := &application{
+		config: cfg,
+		timeouts: queryTimeouts{
+			readQuery:   time.Second,
+			writeQuery:  time.Second,
+			reportQuery: time.Second,
+		},
+		allModels: models.Models{Subscriptions: models.NewMemorySubscriptionDB()},
+		metrics:   metrics.New(nil),
+		limiter:   newRateLimiter(models.NewMemoryRateLimitStore(), cfg.RateLimit),
+	}
+	return app.routes()
+}
+
+func TestRateLimitByIPBeforeAuthentication(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "ratelimit-test-secret"
+	cfg.RateLimit.IPPerMinute, cfg.RateLimit.IPBurst = 1, 2
+	h := newRateLimitTestApp(cfg)
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/subscriptions/all", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer forged")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range 2 {
+		if rec := get("203.0.113.7:1234"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: want 401, got %d", i+1, rec.Code)
+		}
+	}
+	rec := get("203.0.113.7:5678")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third attempt: want 429 before authentication, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("third attempt: missing Retry-After")
+	}
+
+	if rec := get("198.51.100.1:1234"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("other IP: want its own bucket and 401, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitPerCaller(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.ReadPerMinute, cfg.RateLimit.ReadBurst = 1, 1
+	h := newRateLimitTestApp(cfg)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/subscriptions/all", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first read: want 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "1" {
+		t.Fatalf("first read: RateLimit-Limit = %q, want the read bucket's 1", got)
+	}
+	if rec := get(); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second read: want 429, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"subscription-service/internal/models"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/ast"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// graphqlQuery — корневой резолвер схемы schema.graphql. Состояние
+// конкретного запроса резолверы берут из контекста (graphqlRequestFrom).
+type graphqlQuery struct {
+	app    *application
+	schema *ast.Schema
+}
+
+type subscriptionFilterInput struct {
+	UserID      *graphql.ID
+	ServiceName *string
+	Tags        *[]string
+}
+
+// resolverError скрывает от клиента подробности ошибки БД так же, как
+// queryFailed: истёкший бюджет называется, остальное пишется в лог.
+func resolverError(ctx context.Context, err error, message string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		budget, _ := ctx.Value(queryBudgetKey{}).(queryBudget)
+		return fmt.Errorf("Database query exceeded its %s deadline of %s", budget.kind, budget.timeout)
+	}
+	slog.ErrorContext(ctx, "graphql resolver failed", "error", err)
+	return errors.New(message)
+}
+
+// parseUserID проверяет UUID пользователя и приводит его к каноническому
+// виду — по нему загрузчики сопоставляют ключи.
+func parseUserID(id graphql.ID) (uuid.UUID, error) {
+	var uid uuid.UUID
+	if err := uid.Scan(string(id)); err != nil {
+		return uid, fmt.Errorf("invalid user id %q", id)
+	}
+	return uid, nil
+}
+
+func parseMonth(name, value string) (time.Time, error) {
+	t, err := time.Parse("01-2006", value)
+	if err != nil {
+		return t, fmt.Errorf("invalid %s date format, use MM-YYYY", name)
+	}
+	return t, nil
+}
+
+// parsePeriod разбирает период сводки так же, как /summary: from не
+// позже to.
+func parsePeriod(from, to string) (time.Time, time.Time, error) {
+	f, err := parseMonth("from", from)
+	if err != nil {
+		return f, f, err
+	}
+	t, err := parseMonth("to", to)
+	if err != nil {
+		return f, t, err
+	}
+	if f.After(t) {
+		return f, t, errors.New("from must not be after to")
+	}
+	return f, t, nil
+}
+
+// graphqlInt переводит сумму в Int GraphQL, у которого 32 бита. Сумма за
+// много лет может не поместиться — тогда запрос получает ошибку, а не
+// отрицательное число.
+func graphqlInt(field string, v int) (int32, error) {
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, fmt.Errorf("%s %d does not fit in Int, narrow the period", field, v)
+	}
+	return int32(v), nil
+}
+
+func checkLimit(limit int32) error {
+	if limit < 1 || limit > graphqlMaxLimit {
+		return fmt.Errorf("limit must be between 1 and %d", graphqlMaxLimit)
+	}
+	return nil
+}
+
+// filter переводит фильтр запроса в фильтр моделей; имя сервиса
+// сводится к каноническому по каталогу.
+func (q *graphqlQuery) filter(ctx context.Context, in *subscriptionFilterInput) (models.SubscriptionFilter, error) {
+	var filter models.SubscriptionFilter
+	if in == nil {
+		return filter, nil
+	}
+	if in.UserID != nil {
+		uid, err := parseUserID(*in.UserID)
+		if err != nil {
+			return filter, err
+		}
+		filter.UserID = uid.UUID.String()
+	}
+	if in.ServiceName != nil && *in.ServiceName != "" {
+		name, err := q.app.canonicalServiceName(ctx, *in.ServiceName)
+		if err != nil {
+			return filter, resolverError(ctx, err, "Failed to resolve service")
+		}
+		filter.ServiceName = name
+	}
+	if in.Tags != nil {
+		filter.Tags = *in.Tags
+	}
+	return filter, nil
+}
+
+//********************************************************************//
+//  							 QUERY								  //
+//********************************************************************//
+
+func (q *graphqlQuery) Subscription(ctx context.Context, args struct{ ID int32 }) (*subscriptionResolver, error) {
+	sub, err := q.app.allModels.Subscriptions.Get(ctx, int(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to get subscription")
+	}
+	return &subscriptionResolver{sub}, nil
+}
+
+func (q *graphqlQuery) Subscriptions(ctx context.Context, args struct {
+	Filter *subscriptionFilterInput
+	Limit  int32
+}) ([]*subscriptionResolver, error) {
+	if err := checkLimit(args.Limit); err != nil {
+		return nil, err
+	}
+
+	filter, err := q.filter(ctx, args.Filter)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = int(args.Limit)
+	subs, err := q.app.allModels.Subscriptions.Find(ctx, filter)
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to return subscriptions")
+	}
+	return subscriptionResolvers(subs), nil
+}
+
+func (q *graphqlQuery) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	uid, err := parseUserID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{uid}, nil
+}
+
+func (q *graphqlQuery) Users(ctx context.Context, args struct {
+	IDs   *[]graphql.ID
+	Limit int32
+}) ([]*userResolver, error) {
+	if err := checkLimit(args.Limit); err != nil {
+		return nil, err
+	}
+
+	var uids []uuid.UUID
+	if args.IDs != nil {
+		for _, id := range *args.IDs {
+			uid, err := parseUserID(id)
+			if err != nil {
+				return nil, err
+			}
+			uids = append(uids, uid)
+		}
+		if len(uids) > int(args.Limit) {
+			uids = uids[:args.Limit]
+		}
+	} else {
+		var err error
+		uids, err = q.app.allModels.Subscriptions.UserIDs(ctx, int(args.Limit))
+		if err != nil {
+			return nil, resolverError(ctx, err, "Failed to return users")
+		}
+	}
+
+	users := make([]*userResolver, 0, len(uids))
+	for _, uid := range uids {
+		users = append(users, &userResolver{uid})
+	}
+	return users, nil
+}
+
+func (q *graphqlQuery) Service(ctx context.Context, args struct{ Name string }) (*serviceResolver, error) {
+
+	service, err := q.app.resolveService(ctx, args.Name)
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to resolve service")
+	}
+	if service == nil {
+		return nil, nil
+	}
+	return &serviceResolver{service}, nil
+}
+
+func (q *graphqlQuery) Services(ctx context.Context) ([]*serviceResolver, error) {
+
+	services, err := q.app.allModels.Services.GetAll(ctx)
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to return services")
+	}
+	resolvers := make([]*serviceResolver, 0, len(services))
+	for _, s := range services {
+		resolvers = append(resolvers, &serviceResolver{s})
+	}
+	return resolvers, nil
+}
+
+func (q *graphqlQuery) Summary(ctx context.Context, args struct {
+	From   string
+	To     string
+	Filter *subscriptionFilterInput
+}) (*summaryResolver, error) {
+	from, to, err := parsePeriod(args.From, args.To)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := q.filter(ctx, args.Filter)
+	if err != nil {
+		return nil, err
+	}
+	subs, total, err := q.app.allModels.Subscriptions.GetSummary(ctx, from, to, filter)
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to calculate summary")
+	}
+	return &summaryResolver{subs, total}, nil
+}
+
+//********************************************************************//
+//  							 SUBSCRIPTION						  //
+//********************************************************************//
+
+type subscriptionResolver struct {
+	sub *models.Subscription
+}
+
+func subscriptionResolvers(subs []*models.Subscription) []*subscriptionResolver {
+	resolvers := make([]*subscriptionResolver, 0, len(subs))
+	for _, sub := range subs {
+		resolvers = append(resolvers, &subscriptionResolver{sub})
+	}
+	return resolvers
+}
+
+func (r *subscriptionResolver) ID() int32             { return int32(r.sub.ID) }
+func (r *subscriptionResolver) ServiceName() string   { return r.sub.ServiceName }
+func (r *subscriptionResolver) Price() (int32, error) { return graphqlInt("price", r.sub.Price) }
+func (r *subscriptionResolver) UserID() graphql.ID    { return graphql.ID(r.sub.UserID.UUID.String()) }
+func (r *subscriptionResolver) StartDate() string     { return r.sub.StartDate.Format("01-2006") }
+func (r *subscriptionResolver) Tags() []string        { return r.sub.Tags }
+func (r *subscriptionResolver) OrganizationID() int32 { return int32(r.sub.OrganizationID) }
+func (r *subscriptionResolver) User() *userResolver   { return &userResolver{r.sub.UserID} }
+
+func (r *subscriptionResolver) EndDate() *string {
+	if r.sub.EndDate == nil {
+		return nil
+	}
+	end := r.sub.EndDate.Format("01-2006")
+	return &end
+}
+
+func (r *subscriptionResolver) CategoryID() *int32 {
+	if r.sub.CategoryID == nil {
+		return nil
+	}
+	id := int32(*r.sub.CategoryID)
+	return &id
+}
+
+func (r *subscriptionResolver) Members() []*memberResolver {
+	members := make([]*memberResolver, 0, len(r.sub.Members))
+	for _, m := range r.sub.Members {
+		members = append(members, &memberResolver{m})
+	}
+	return members
+}
+
+func (r *subscriptionResolver) Service(ctx context.Context) (*serviceResolver, error) {
+	service, err := graphqlRequestFrom(ctx).loaders.service(r.sub.ServiceName)
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to return services")
+	}
+	if service == nil {
+		return nil, nil
+	}
+	return &serviceResolver{service}, nil
+}
+
+type memberResolver struct {
+	m models.SubscriptionMember
+}
+
+func (r *memberResolver) UserID() graphql.ID  { return graphql.ID(r.m.UserID.UUID.String()) }
+func (r *memberResolver) Weight() int32       { return int32(r.m.Weight) }
+func (r *memberResolver) User() *userResolver { return &userResolver{r.m.UserID} }
+
+//********************************************************************//
+//  							 USER								  //
+//********************************************************************//
+
+type userResolver struct {
+	uid uuid.UUID
+}
+
+func (r *userResolver) ID() graphql.ID { return graphql.ID(r.uid.UUID.String()) }
+
+func (r *userResolver) subscriptions(ctx context.Context) ([]*models.Subscription, error) {
+	subs, err := graphqlRequestFrom(ctx).loaders.subscriptionsByUser.load(r.uid.UUID.String())
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to return subscriptions")
+	}
+	return subs, nil
+}
+
+func (r *userResolver) Subscriptions(ctx context.Context) ([]*subscriptionResolver, error) {
+	subs, err := r.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscriptionResolvers(subs), nil
+}
+
+func (r *userResolver) Summary(ctx context.Context, args struct{ From, To string }) (*summaryResolver, error) {
+	from, to, err := parsePeriod(args.From, args.To)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := r.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	costs, total := models.Summarize(ctx, subs, from, to, &r.uid)
+	return &summaryResolver{costs, total}, nil
+}
+
+//********************************************************************//
+//  							 SERVICE							  //
+//********************************************************************//
+
+type serviceResolver struct {
+	s *models.Service
+}
+
+func (r *serviceResolver) ID() int32         { return int32(r.s.ID) }
+func (r *serviceResolver) Name() string      { return r.s.Name }
+func (r *serviceResolver) Aliases() []string { return r.s.Aliases }
+func (r *serviceResolver) Category() string  { return r.s.Category }
+
+func (r *serviceResolver) DefaultPrice() *int32 {
+	if r.s.DefaultPrice == nil {
+		return nil
+	}
+	price := int32(*r.s.DefaultPrice)
+	return &price
+}
+
+func (r *serviceResolver) Website() *string {
+	if r.s.Website == "" {
+		return nil
+	}
+	return &r.s.Website
+}
+
+func (r *serviceResolver) Subscriptions(ctx context.Context) ([]*subscriptionResolver, error) {
+	subs, err := graphqlRequestFrom(ctx).loaders.subscriptionsByService.load(r.s.Name)
+	if err != nil {
+		return nil, resolverError(ctx, err, "Failed to return subscriptions")
+	}
+	return subscriptionResolvers(subs), nil
+}
+
+//********************************************************************//
+//  							 SUMMARY							  //
+//********************************************************************//
+
+type summaryResolver struct {
+	subs  []*models.SubscriptionWithCost
+	total int
+}
+
+func (r *summaryResolver) TotalCost() (int32, error) { return graphqlInt("totalCost", r.total) }
+
+func (r *summaryResolver) Subscriptions() []*subscriptionCostResolver {
+	costs := make([]*subscriptionCostResolver, 0, len(r.subs))
+	for _, c := range r.subs {
+		costs = append(costs, &subscriptionCostResolver{c})
+	}
+	return costs
+}
+
+type subscriptionCostResolver struct {
+	c *models.SubscriptionWithCost
+}
+
+func (r *subscriptionCostResolver) Subscription() *subscriptionResolver {
+	return &subscriptionResolver{&r.c.Subscription}
+}
+
+func (r *subscriptionCostResolver) DateFrom() string     { return r.c.DateFrom.Format("01-2006") }
+func (r *subscriptionCostResolver) DateTo() string       { return r.c.DateTo.Format("01-2006") }
+func (r *subscriptionCostResolver) Cost() (int32, error) { return graphqlInt("cost", r.c.Cost) }
+
+func (r *subscriptionCostResolver) FullCost() (*int32, error) {
+	if r.c.FullCost == 0 {
+		return nil, nil
+	}
+	cost, err := graphqlInt("fullCost", r.c.FullCost)
+	if err != nil {
+		return nil, err
+	}
+	return &cost, nil
+}
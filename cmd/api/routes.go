@@ -2,16 +2,32 @@ package main
 
 import (
 	"net/http"
+	"subscription-service/internal/models"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func (app *application) routes() http.Handler {
-	g := gin.Default()
+	g := gin.New()
+	g.Use(
+		gin.Recovery(),
+		requestID(),
+		accessLog(),
+		otelgin.Middleware(serviceName, otelgin.WithFilter(traceable)),
+		app.metrics.Middleware(),
+	)
 
 	r := g.Group("/api/subscriptions")
+	if app.limiter != nil {
+		r.Use(app.rateLimitByIP())
+	}
+	r.Use(app.authenticate(), app.tenant(), authorize())
+	if app.limiter != nil {
+		r.Use(app.rateLimit())
+	}
 	{
 		r.GET("/all", app.getAllRecords)
 		r.GET("/:id", app.getRecordByID)
@@ -24,8 +40,51 @@ func (app *application) routes() http.Handler {
 		r.POST("/newrecord", app.createRecord)
 
 		r.GET("/summary", app.getRecordsByFilter)
+		r.GET("/settlement", app.getSettlement)
+		r.PUT("/:id/members", app.setMembers)
+
+		r.GET("/user/:id/budget", app.getUserBudget)
+		r.PUT("/user/:id/budget", app.setUserBudget)
+		r.DELETE("/user/:id/budget", app.deleteUserBudget)
+
+		r.GET("/forecast", app.getForecast)
+		r.GET("/:id/price-changes", app.getPriceChanges)
+		r.POST("/:id/price-changes", app.createPriceChange)
+
+		r.GET("/analytics/overview", app.getAnalyticsOverview)
+		r.GET("/analytics/services", app.getAnalyticsByService)
+
+		r.GET("/services", app.listServices)
+		r.GET("/services/:id", app.getService)
+		r.POST("/services", requireScope(models.ScopeAdmin), requirePlatform(), app.createService)
+		r.PUT("/services/:id", requireScope(models.ScopeAdmin), requirePlatform(), app.updateService)
+		r.DELETE("/services/:id", requireScope(models.ScopeAdmin), requirePlatform(), app.deleteService)
+
+		r.GET("/cost-centers", app.listCostCenters)
+		r.POST("/cost-centers", requireScope(models.ScopeAdmin), app.createCostCenter)
+		r.DELETE("/cost-centers/:id", requireScope(models.ScopeAdmin), app.deleteCostCenter)
+		r.GET("/:id/cost-centers", app.getCostAllocations)
+		r.PUT("/:id/cost-centers", app.setCostAllocations)
+		r.GET("/reports/chargeback", app.getChargeback)
+
+		r.POST("/graphql", app.graphqlHandler())
+
+		r.GET("/categories", app.listCategories)
+		r.POST("/categories", requireScope(models.ScopeAdmin), requirePlatform(), app.createCategory)
+		r.DELETE("/categories/:id", requireScope(models.ScopeAdmin), requirePlatform(), app.deleteCategory)
+
+		admin := r.Group("/admin", requireScope(models.ScopeAdmin))
+		admin.GET("/api-keys", app.listAPIKeys)
+		admin.POST("/api-keys", app.createAPIKey)
+		admin.DELETE("/api-keys/:id", app.revokeAPIKey)
+		admin.GET("/organizations", requirePlatform(), app.listOrganizations)
+		admin.POST("/organizations", requirePlatform(), app.createOrganization)
 	}
 
+	g.GET("/healthz", app.healthz)
+	g.GET("/readyz", app.readyz)
+	g.GET("/metrics", gin.WrapH(app.metrics.Handler()))
+
 	g.GET("/swagger/*any", func(c *gin.Context) {
 		if c.Request.RequestURI == "/swagger/" {
 			c.Redirect(http.StatusFound, "/swagger/index.html")
@@ -36,3 +95,13 @@ func (app *application) routes() http.Handler {
 
 	return g
 }
+
+// traceable исключает из трассировки служебные маршруты, которые
+// опрашиваются по расписанию и только зашумляют трассы.
+func traceable(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
@@ -1,22 +1,103 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
 )
 
 func (app *application) serve() error {
 	server := http.Server{
-		Addr:         fmt.Sprintf(":%d", app.port),
+		Addr:         fmt.Sprintf(":%d", app.config.HTTP.Port),
 		Handler:      app.routes(),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
 
-	log.Printf("Sterting server on port %d", app.port)
+	// gRPC слушает свой порт рядом с HTTP. Порт занимаем до старта HTTP,
+	// чтобы ошибка конфигурации остановила процесс сразу.
+	var (
+		grpcServer *grpc.Server
+		grpcHealth *health.Server
+		grpcErr    = make(chan error, 1)
+	)
+	if app.config.GRPC.Enabled {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", app.config.GRPC.Port))
+		if err != nil {
+			return err
+		}
+		grpcServer, grpcHealth = app.grpcServer()
+
+		slog.Info("starting gRPC server", "port", app.config.GRPC.Port)
+		go func() {
+			grpcErr <- grpcServer.Serve(lis)
+		}()
+	}
+
+	shutdownErr := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		// Сначала перестаём быть готовыми и даём балансировщику время это
+		// заметить, и только потом перестаём принимать соединения.
+		slog.Info("caught signal, marking server as not ready", "signal", s.String())
+		app.ready.Store(false)
+		if grpcHealth != nil {
+			grpcHealth.Shutdown()
+		}
+		time.Sleep(app.config.Shutdown.ReadinessDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.Shutdown.GracePeriod)
+		defer cancel()
+
+		// Ошибка одного шага не отменяет остальные: gRPC и фоновые задачи
+		// останавливаются, даже если HTTP не уложился в отведённое время.
+		slog.Info("draining in-flight requests", "grace_period", app.config.Shutdown.GracePeriod.String())
+		httpErr := server.Shutdown(ctx)
+		if grpcServer != nil {
+			slog.Info("draining in-flight gRPC calls")
+			stopGRPC(ctx, grpcServer)
+		}
+
+		slog.Info("stopping background workers")
+		shutdownErr <- errors.Join(httpErr, app.workers.stop(ctx))
+	}()
+
+	slog.Info("starting server", "port", app.config.HTTP.Port)
+	app.ready.Store(true)
+
+	err := server.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// gRPC останавливается при любом исходе остановки HTTP, поэтому его
+	// результат дожидаемся всегда.
+	err = <-shutdownErr
+	if grpcServer != nil {
+		err = errors.Join(err, <-grpcErr)
+	}
+	if err != nil {
+		return err
+	}
 
-	return server.ListenAndServe()
+	if grpcServer != nil {
+		slog.Info("stopped gRPC server", "port", app.config.GRPC.Port)
+	}
+	slog.Info("stopped server", "port", app.config.HTTP.Port)
+	return nil
 }
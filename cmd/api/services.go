@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"subscription-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type serviceRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Aliases      []string `json:"aliases"`
+	Category     string   `json:"category"`
+	DefaultPrice *int     `json:"default_price"`
+	Website      string   `json:"website"`
+}
+
+func (req serviceRequest) service() (*models.Service, error) {
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if name == "" {
+		return nil, errors.New("name must not be blank")
+	}
+	if req.DefaultPrice != nil && *req.DefaultPrice < 0 {
+		return nil, errors.New("default_price must not be negative")
+	}
+	return &models.Service{
+		Name:         name,
+		Aliases:      req.Aliases,
+		Category:     strings.TrimSpace(req.Category),
+		DefaultPrice: req.DefaultPrice,
+		Website:      strings.TrimSpace(req.Website),
+	}, nil
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+// listServices godoc
+// @Summary Получить каталог сервисов
+// @Tags services
+// @Produce json
+// @Success 200 {object} map[string][]models.Service
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /services [get]
+func (app *application) listServices(c *gin.Context) {
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	services, err := app.allModels.Services.GetAll(ctx)
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return services")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": services})
+}
+
+// getService godoc
+// @Summary Получить сервис каталога по ID
+// @Tags services
+// @Produce json
+// @Param id path int true "ID сервиса"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 404 {object} map[string]string "Сервис не найден"
+// @Failure 500 {object} map[string]string "Ошибка при получении данных"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /services/{id} [get]
+func (app *application) getService(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, readQuery)
+	defer cancel()
+
+	service, err := app.allModels.Services.Get(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(c, http.StatusNotFound, "Service not found")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to return service")
+		return
+	}
+
+	c.JSON(http.StatusOK, service)
+}
+
+//********************************************************************//
+//  							 CREATE								  //
+//********************************************************************//
+
+// createService godoc
+// @Summary Добавить сервис в каталог
+// @Description Требует область admin. Каноническое имя автоматически становится псевдонимом
+// @Tags services
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param service body serviceRequest true "Имя, псевдонимы, категория, цена по умолчанию и сайт"
+// @Success 201 {object} models.Service
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 409 {object} map[string]string "Имя или псевдоним занят другим сервисом"
+// @Failure 500 {object} map[string]string "Ошибка при сохранении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /services [post]
+func (app *application) createService(c *gin.Context) {
+	var req serviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	service, err := req.service()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.Services.Insert(ctx, service)
+	if errors.Is(err, models.ErrDuplicateService) {
+		errorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to save service")
+		return
+	}
+
+	c.JSON(http.StatusCreated, service)
+}
+
+//********************************************************************//
+//  							 UPDATE								  //
+//********************************************************************//
+
+// updateService godoc
+// @Summary Обновить сервис каталога
+// @Description Требует область admin. Псевдонимы заменяются целиком. При смене имени подписки переименовываются, а старое имя остаётся псевдонимом
+// @Tags services
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "ID сервиса"
+// @Param service body serviceRequest true "Новые данные сервиса"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 404 {object} map[string]string "Сервис не найден"
+// @Failure 409 {object} map[string]string "Имя или псевдоним занят, либо у пользователя уже есть подписка с новым именем"
+// @Failure 500 {object} map[string]string "Ошибка при обновлении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /services/{id} [put]
+func (app *application) updateService(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+
+	var req serviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	service, err := req.service()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	service.ID = id
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.Services.Update(ctx, service)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		errorResponse(c, http.StatusNotFound, "Service not found")
+	case errors.Is(err, models.ErrDuplicateService), errors.Is(err, models.ErrDuplicateSubscription):
+		errorResponse(c, http.StatusConflict, err.Error())
+	case err != nil:
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to update service")
+	default:
+		c.JSON(http.StatusOK, service)
+	}
+}
+
+//********************************************************************//
+//  							 DELETE								  //
+//********************************************************************//
+
+// deleteService godoc
+// @Summary Удалить сервис из каталога
+// @Description Требует область admin. Подписки сохраняются под своим текущим именем
+// @Tags services
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "ID сервиса"
+// @Success 200 {object} map[string]string "Сервис удалён"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Нет учётных данных"
+// @Failure 403 {object} map[string]string "Нет области admin"
+// @Failure 404 {object} map[string]string "Сервис не найден"
+// @Failure 500 {object} map[string]string "Ошибка при удалении"
+// @Failure 504 {object} map[string]string "Истёк дедлайн запроса к БД"
+// @Router /services/{id} [delete]
+func (app *application) deleteService(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+
+	ctx, cancel := app.queryContext(c, writeQuery)
+	defer cancel()
+
+	err = app.allModels.Services.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(c, http.StatusNotFound, "Service not found")
+		return
+	}
+	if err != nil {
+		queryFailed(c, ctx, http.StatusInternalServerError, "Failed to delete service")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service deleted"})
+}
+
+//********************************************************************//
+//  							 HELPERS							  //
+//********************************************************************//
+
+// resolveService сводит имя сервиса из запроса к записи каталога. Для
+// имени, которого нет в каталоге, возвращает nil без ошибки: такие
+// подписки по-прежнему разрешены и хранятся под именем как есть.
+func (app *application) resolveService(ctx context.Context, name string) (*models.Service, error) {
+	service, err := app.allModels.Services.Resolve(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return service, err
+}
+
+// canonicalServiceName возвращает каноническое имя сервиса или, если
+// сервиса нет в каталоге, имя без лишних пробелов.
+func (app *application) canonicalServiceName(ctx context.Context, name string) (string, error) {
+	service, err := app.resolveService(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if service == nil {
+		return strings.TrimSpace(name), nil
+	}
+	return service.Name, nil
+}
+
+// applyCatalog приводит имя сервиса подписки к каноническому и, если цена
+// не указана, подставляет цену сервиса по умолчанию.
+func (app *application) applyCatalog(ctx context.Context, sub *models.Subscription) error {
+	service, err := app.resolveService(ctx, sub.ServiceName)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		sub.ServiceName = strings.TrimSpace(sub.ServiceName)
+		return nil
+	}
+
+	sub.ServiceName = service.Name
+	if sub.Price == 0 && service.DefaultPrice != nil {
+		sub.Price = *service.DefaultPrice
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"subscription-service/internal/logging"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryKind — класс запросов к БД со своим бюджетом времени.
+type queryKind string
+
+const (
+	readQuery   queryKind = "read"
+	writeQuery  queryKind = "write"
+	reportQuery queryKind = "report"
+)
+
+type queryTimeouts map[queryKind]time.Duration
+
+type queryBudget struct {
+	kind    queryKind
+	timeout time.Duration
+}
+
+type queryBudgetKey struct{}
+
+// queryContext выводит контекст запроса к БД из контекста HTTP-запроса:
+// отключение клиента отменяет запрос, а бюджет kind ограничивает его по времени.
+func (app *application) queryContext(c *gin.Context, kind queryKind) (context.Context, context.CancelFunc) {
+	return app.budgetContext(c.Request.Context(), kind)
+}
+
+// budgetContext — то же, что queryContext, для запросов не из gin: gRPC и
+// фоновых задач.
+func (app *application) budgetContext(parent context.Context, kind queryKind) (context.Context, context.CancelFunc) {
+	budget := queryBudget{kind: kind, timeout: app.timeouts[kind]}
+	ctx := context.WithValue(parent, queryBudgetKey{}, budget)
+	return context.WithTimeout(ctx, budget.timeout)
+}
+
+// queryFailed пишет ответ об ошибке запроса к БД. Если истёк бюджет
+// времени, отвечает 504 и называет сработавший дедлайн; если клиент
+// отключился, ответ не пишется.
+func queryFailed(c *gin.Context, ctx context.Context, status int, message string) {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		budget, _ := ctx.Value(queryBudgetKey{}).(queryBudget)
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error":      "Database query exceeded its deadline",
+			"deadline":   string(budget.kind),
+			"timeout":    budget.timeout.String(),
+			"request_id": logging.RequestID(c.Request.Context()),
+		})
+	case errors.Is(ctx.Err(), context.Canceled):
+		c.Abort()
+	default:
+		errorResponse(c, status, message)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+type workerStatus struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// workers — фоновые задачи процесса API. Все они получают общий контекст,
+// который отменяется при остановке сервера.
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	statuses map[string]*workerStatus
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{
+		ctx:      ctx,
+		cancel:   cancel,
+		statuses: make(map[string]*workerStatus),
+	}
+}
+
+func (w *workers) start(name string, run func(ctx context.Context) error) {
+	w.mu.Lock()
+	w.statuses[name] = &workerStatus{Name: name, State: "running", StartedAt: time.Now()}
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		slog.Info("starting background worker", "worker", name)
+		err := run(w.ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		w.finish(name, err)
+
+		if err != nil {
+			slog.Error("background worker failed", "worker", name, "error", err)
+			return
+		}
+		slog.Info("background worker stopped", "worker", name)
+	}()
+}
+
+func (w *workers) finish(name string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	st := w.statuses[name]
+	st.StoppedAt = &now
+	st.State = "stopped"
+	if err != nil {
+		st.State = "failed"
+		st.Error = err.Error()
+	}
+}
+
+// status возвращает состояние всех воркеров, упорядоченное по имени.
+func (w *workers) status() []workerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	result := make([]workerStatus, 0, len(w.statuses))
+	for _, st := range w.statuses {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// stop отменяет контекст воркеров и ждёт их завершения, но не дольше ctx.
+func (w *workers) stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"subscription-service/internal/config"
+	"subscription-service/internal/migrations"
+	"subscription-service/internal/models"
+	"time"
+
+	_ "github.com/jackc/pgx/v4/stdlib"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// Утилита выпускает API-ключ напрямую в базе. Нужна, чтобы получить
+// первый ключ с областью admin: дальше ключами управляют через
+// /api/subscriptions/admin/api-keys. Без --org выпускается ключ платформы.
+const usage = `Usage: apikey [config flags] create --name NAME [--scopes read,write,admin] [--ttl 720h] [--org ID]`
+
+func main() {
+	cfg, opts, err := config.Load("apikey", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, usage)
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	if len(opts.Args) == 0 || opts.Args[0] != "create" {
+		log.Fatal(usage)
+	}
+
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	name := fs.String("name", "", "client name")
+	scopes := fs.String("scopes", models.ScopeAdmin, "comma-separated scopes: read, write, admin")
+	ttl := fs.Duration("ttl", 0, "key lifetime, 0 means no expiry")
+	org := fs.Int("org", 0, "organization ID, 0 means a platform key")
+	fs.Parse(opts.Args[1:])
+
+	if *name == "" || *org < 0 {
+		log.Fatal(usage)
+	}
+	scopeList := strings.Split(*scopes, ",")
+	if err := models.ValidScopes(scopeList); err != nil {
+		log.Fatal(err)
+	}
+
+	var expiresAt *time.Time
+	if *ttl > 0 {
+		t := time.Now().Add(*ttl)
+		expiresAt = &t
+	}
+
+	plaintext, key, err := models.NewAPIKey(*name, scopeList, expiresAt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *org > 0 {
+		key.OrganizationID = org
+	}
+
+	db, err := sql.Open("pgx", cfg.DB.DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Write)
+	defer cancel()
+
+	m := models.NewModels(db, migrations.Tables(cfg.DB))
+	if err := m.APIKeys.Insert(ctx, key); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "created key %d (%s) with scopes %s; it is shown only once:\n", key.ID, key.Name, strings.Join(key.Scopes, ","))
+	fmt.Println(plaintext)
+}
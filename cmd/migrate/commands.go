@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"subscription-service/internal/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+type command struct {
+	name string
+	args []string
+	yes  bool
+	dir  string
+}
+
+// parseCommand разбирает команду и её аргументы вручную: стандартный flag
+// принял бы отрицательное число в "steps -2" за неизвестный флаг.
+func parseCommand(args []string) (command, error) {
+	cmd := command{dir: filepath.Join("internal", "migrations", "sql")}
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--yes" || arg == "-yes" || arg == "-y":
+			cmd.yes = true
+		case arg == "--dir" || arg == "-dir":
+			if i+1 >= len(args) {
+				return cmd, errors.New("--dir requires a path")
+			}
+			i++
+			cmd.dir = args[i]
+		case strings.HasPrefix(arg, "--dir="):
+			cmd.dir = strings.TrimPrefix(arg, "--dir=")
+		case cmd.name == "":
+			cmd.name = arg
+		default:
+			cmd.args = append(cmd.args, arg)
+		}
+	}
+
+	if cmd.name == "" {
+		return cmd, errors.New("please provide a command")
+	}
+
+	want := map[string]int{
+		"up": 0, "down": 0, "version": 0, "status": 0,
+		"steps": 1, "goto": 1, "force": 1, "create": 1,
+	}
+	n, ok := want[cmd.name]
+	if !ok {
+		return cmd, fmt.Errorf("unknown command %q", cmd.name)
+	}
+	if len(cmd.args) != n {
+		return cmd, fmt.Errorf("%s expects %d argument(s), got %d", cmd.name, n, len(cmd.args))
+	}
+	return cmd, nil
+}
+
+func run(m *migrate.Migrate, cmd command) error {
+	switch cmd.name {
+	case "up":
+		return ignoreNoChange(m.Up())
+
+	case "down":
+		if err := confirm(cmd, "roll back ALL migrations and drop every service table"); err != nil {
+			return err
+		}
+		return ignoreNoChange(m.Down())
+
+	case "steps":
+		n, err := strconv.Atoi(cmd.args[0])
+		if err != nil || n == 0 {
+			return fmt.Errorf("invalid step count %q", cmd.args[0])
+		}
+		if n < 0 {
+			if err := confirm(cmd, fmt.Sprintf("roll back %d migration(s)", -n)); err != nil {
+				return err
+			}
+		}
+		return ignoreNoChange(m.Steps(n))
+
+	case "goto":
+		target, err := parseVersion(cmd.args[0])
+		if err != nil {
+			return err
+		}
+		current, _, err := m.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return err
+		}
+		if target < current {
+			if err := confirm(cmd, fmt.Sprintf("roll back from version %d to %d", current, target)); err != nil {
+				return err
+			}
+		}
+		return ignoreNoChange(m.Migrate(target))
+
+	case "force":
+		version, err := parseVersion(cmd.args[0])
+		if err != nil {
+			return err
+		}
+		if err := confirm(cmd, fmt.Sprintf("force version %d without running any SQL", version)); err != nil {
+			return err
+		}
+		if err := m.Force(int(version)); err != nil {
+			return err
+		}
+		log.Printf("Database dirty state cleared. Forced version: %d\n", version)
+		return nil
+
+	case "version":
+		version, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migrations applied")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version %d, dirty %t\n", version, dirty)
+		return nil
+
+	case "status":
+		return status(m)
+	}
+	return fmt.Errorf("unknown command %q", cmd.name)
+}
+
+// status печатает встроенные миграции с отметкой о применении. golang-migrate
+// хранит только текущую версию, поэтому применёнными считаются все версии
+// не выше неё.
+func status(m *migrate.Migrate) error {
+	available, err := migrations.Available()
+	if err != nil {
+		return err
+	}
+
+	current, dirty, err := m.Version()
+	applied := true
+	if errors.Is(err, migrate.ErrNilVersion) {
+		applied = false
+	} else if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, mig := range available {
+		state := "applied"
+		switch {
+		case !applied || mig.Version > current:
+			state = "pending"
+			pending++
+		case mig.Version == current && dirty:
+			state = "dirty"
+		}
+		fmt.Printf("%-8s %d %s\n", state, mig.Version, mig.Name)
+	}
+
+	if applied {
+		fmt.Printf("\ncurrent version %d, dirty %t, %d pending\n", current, dirty, pending)
+	} else {
+		fmt.Printf("\nno migrations applied, %d pending\n", pending)
+	}
+	return nil
+}
+
+var (
+	migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+	migrationFile = regexp.MustCompile(`^([0-9]+)_.*\.(up|down)\.sql$`)
+)
+
+// create создаёт пару пустых файлов со следующим порядковым номером вида
+// 000014. Готовность API сравнивает версию базы с models.SchemaVersion,
+// поэтому номера идут подряд, а константу нужно поднять вместе с новой
+// миграцией.
+func create(cmd command) error {
+	name := cmd.args[0]
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("migration name %q must contain only lowercase letters, digits and underscores", name)
+	}
+
+	last, err := lastVersion(cmd.dir)
+	if err != nil {
+		return err
+	}
+	version := fmt.Sprintf("%06d", last+1)
+	header := "-- Имена таблиц и ограничений пишутся шаблоном, чтобы работали\n" +
+		"-- настройки db.schema и db.table_prefix:\n" +
+		"--   {{.Table \"subscriptions\"}}  {{.Ident \"unique_service_user\"}}\n"
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(cmd.dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteString(header); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	fmt.Fprintf(os.Stderr, "remember to set models.SchemaVersion to %d\n", last+1)
+	return nil
+}
+
+// lastVersion — наибольший номер миграции в dir, 0 для пустого каталога.
+func lastVersion(dir string) (uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var last uint64
+	for _, e := range entries {
+		m := migrationFile.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		last = max(last, version)
+	}
+	return last, nil
+}
+
+// confirm спрашивает подтверждение у оператора, если не передан --yes.
+func confirm(cmd command, action string) error {
+	if cmd.yes {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "This will %s. Type \"yes\" to continue: ", action)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return errors.New("aborted: no confirmation (pass --yes to skip)")
+	}
+	if strings.TrimSpace(answer) != "yes" {
+		return errors.New("aborted")
+	}
+	return nil
+}
+
+func parseVersion(s string) (uint, error) {
+	version, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version number %q", s)
+	}
+	return uint(version), nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
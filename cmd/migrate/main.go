@@ -1,69 +1,74 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"subscription-service/internal/env"
-
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/source/file"
+	"subscription-service/internal/config"
+	"subscription-service/internal/migrations"
 )
 
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Please provide a migration direction^ 'up' or 'down'")
-	}
+const usage = `Usage: migrate [config flags] <command> [--yes] [args]
+
+Commands:
+  up              apply all pending migrations
+  down            roll back every migration, including the table drop in 000001 (destructive)
+  steps N         apply N migrations, or roll back |N| when N is negative (destructive when N < 0)
+  goto V          migrate up or down to version V (destructive when V is below the current version)
+  force V         set version V and clear the dirty flag without running SQL (destructive)
+  version         print the current version and dirty flag
+  status          list applied and pending migrations
+  create NAME     scaffold up/down files with the next sequential version in --dir (default internal/migrations/sql)
 
-	direction := os.Args[1]
+Destructive commands ask for confirmation unless --yes is passed.`
 
-	db, err := sql.Open("postgres", env.GetPostgresDSN())
+func main() {
+	cfg, opts, err := config.Load("migrate", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, usage)
+		return
+	}
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if opts.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	defer db.Close()
-
-	instance, err := postgres.WithInstance(db, &postgres.Config{})
+	cmd, err := parseCommand(opts.Args)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("%v\n\n%s", err, usage)
+	}
+
+	// create работает с файлами и не требует базы.
+	if cmd.name == "create" {
+		if err := create(cmd); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	fSrc, err := (&file.File{}).Open("cmd/migrate/migrations")
+	db, err := sql.Open("postgres", cfg.DB.DSN())
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	m, err := migrate.NewWithInstance("file", fSrc, "postgres", instance)
+	defer db.Close()
+
+	m, err := migrations.New(context.Background(), db, cfg.DB)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer m.Close()
 
-	switch direction {
-	case "up":
-		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
-		}
-	case "down":
-		if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
-		}
-	case "force":
-		if len(os.Args) < 3 {
-			log.Fatal("Please provide a version number to force")
-		}
-		var version uint
-		_, err := fmt.Sscan(os.Args[2], &version)
-		if err != nil {
-			log.Fatal("Invalid version number")
-		}
-		if err := m.Force(int(version)); err != nil {
-			log.Fatal(err)
-		}
-		log.Printf("Database dirty state cleared. Forced version: %d\n", version)
-	default:
-		log.Fatal("Please provide a migration direction: 'up', 'down' or 'force'")
+	if err := run(m, cmd); err != nil {
+		log.Fatal(err)
 	}
 }
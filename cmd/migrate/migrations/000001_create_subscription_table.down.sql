@@ -1 +0,0 @@
-DROP TABLE IF EXISTS infosub.subscriptions CASCADE;
\ No newline at end of file
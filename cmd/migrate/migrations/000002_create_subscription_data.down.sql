@@ -1 +0,0 @@
-TRUNCATE TABLE infosub.subscriptions CASCADE;
@@ -1,7 +0,0 @@
-INSERT INTO infosub.subscriptions (service_name, price, user_id, start_date, end_date)
-VALUES
-    ('Yandex Plus', 400, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '2025-07-01', '2025-12-31'),
-    ('Spotify Premium', 299, 'a12f4d3b-8c77-4b2f-9c3e-123456789abc', '2025-06-01', NULL),
-    ('Netflix', 500, 'b45e6f7a-1d23-4e6f-8c9d-987654321def', '2025-05-01', '2025-10-31'),
-    ('Apple Music', 199, 'c78a9b0d-3c45-6f7e-8d9f-234567890abc', '2025-08-01', NULL),
-    ('Amazon Prime', 350, 'd90a1b2c-4e56-7f8d-9b0c-345678901def', '2025-09-01', NULL);
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"subscription-service/internal/config"
+	"subscription-service/internal/logging"
+	"subscription-service/internal/migrations"
+	"subscription-service/internal/models"
+	"subscription-service/internal/notify"
+	"syscall"
+
+	_ "github.com/jackc/pgx/v4/stdlib"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+func main() {
+	cfg, _, err := config.Load("subscription-notifier", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	logger, err := logging.NewFromConfig(os.Stdout, cfg.Log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	db, err := sql.Open("pgx", cfg.DB.DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	scheduler, err := notify.NewScheduler(models.NewModels(db, migrations.Tables(cfg.DB)), cfg.Notifier)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("starting notifier", "channels", len(scheduler.Channels), "days_ahead", scheduler.DaysAhead)
+
+	if err := scheduler.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"subscription-service/internal/config"
+	"subscription-service/internal/logging"
+	"subscription-service/internal/migrations"
+	"subscription-service/internal/models"
+	"subscription-service/internal/seed"
+	"syscall"
+	"time"
+
+	_ "github.com/jackc/pgx/v4/stdlib"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+const usage = `Usage: seed [config flags] <command> [args]
+
+Commands:
+  list                       list built-in fixture sets
+  fixture <name|file>        load a built-in set or a .yaml/.yml/.json file
+  generate [flags]           create synthetic users with subscription histories
+      --users N              number of users (default 100)
+      --months N             how far back histories start (default 24)
+      --seed N               random seed, same seed gives the same data (default 1)
+
+Data is loaded into the organization auth.default_organization.
+Seeding is refused when the environment is production.`
+
+func main() {
+	cfg, opts, err := config.Load("seed", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, usage)
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	if len(opts.Args) == 0 {
+		log.Fatalf("please provide a command\n\n%s", usage)
+	}
+
+	var fixture seed.Fixture
+	switch cmd, args := opts.Args[0], opts.Args[1:]; cmd {
+	case "list":
+		names, err := seed.Names()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+
+	case "fixture":
+		if len(args) != 1 {
+			log.Fatalf("fixture expects a set name or file\n\n%s", usage)
+		}
+		fixture, err = seed.Load(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "generate":
+		fs := flag.NewFlagSet("generate", flag.ExitOnError)
+		users := fs.Int("users", 100, "number of users")
+		months := fs.Int("months", 24, "how far back histories start")
+		rngSeed := fs.Uint64("seed", 1, "random seed")
+		fs.Parse(args)
+		if *users <= 0 || *months < 0 {
+			log.Fatal("--users must be positive and --months must not be negative")
+		}
+		fixture = seed.Generate(seed.GenerateOptions{
+			Users:  *users,
+			Months: *months,
+			Seed:   *rngSeed,
+			Now:    time.Now(),
+		})
+
+	default:
+		log.Fatalf("unknown command %q\n\n%s", cmd, usage)
+	}
+
+	// Проверяем до подключения к базе: в production сидирование запрещено
+	// целиком, без флагов-исключений.
+	if cfg.Environment == "production" {
+		log.Fatalf("refusing to seed: environment is %q", cfg.Environment)
+	}
+
+	logger, err := logging.NewFromConfig(os.Stdout, cfg.Log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	db, err := sql.Open("pgx", cfg.DB.DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if cfg.Auth.DefaultOrganization == 0 {
+		log.Fatal("refusing to seed: auth.default_organization is not set")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = models.WithOrganization(ctx, cfg.Auth.DefaultOrganization)
+
+	stats, err := seed.Apply(ctx, models.NewModels(db, migrations.Tables(cfg.DB)), fixture)
+	if err != nil {
+		slog.Error("seeding failed", "error", err, "users", stats.Users, "subscriptions", stats.Subscriptions)
+		os.Exit(1)
+	}
+
+	slog.Info("seeded",
+		"environment", cfg.Environment,
+		"organization", cfg.Auth.DefaultOrganization,
+		"users", stats.Users,
+		"subscriptions", stats.Subscriptions,
+		"skipped_existing", stats.Skipped,
+		"price_changes", stats.PriceChanges,
+		"budgets", stats.Budgets,
+	)
+}
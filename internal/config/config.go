@@ -0,0 +1,236 @@
+// Package config загружает настройки сервиса из значений по умолчанию,
+// YAML-файла, переменных окружения и флагов командной строки — именно в
+// таком порядке, так что флаг переопределяет переменную, а переменная —
+// файл. Некорректное значение на любом уровне — ошибка запуска, а не
+// тихий откат к значению по умолчанию.
+//
+// У каждого поля есть теги:
+//
+//	yaml   — ключ в файле;
+//	env    — переменная окружения;
+//	flag   — флаг командной строки;
+//	secret — значение скрывается в --print-config.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Environment string `yaml:"environment" env:"APP_ENV" flag:"env" usage:"deployment environment: development, test, staging, demo or production"`
+
+	HTTP      HTTPConfig      `yaml:"http"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	GraphQL   GraphQLConfig   `yaml:"graphql"`
+	Auth      AuthConfig      `yaml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	DB        DBConfig        `yaml:"db"`
+	Migrate   MigrateConfig   `yaml:"migrate"`
+	Timeouts  TimeoutsConfig  `yaml:"timeouts"`
+	Shutdown  ShutdownConfig  `yaml:"shutdown"`
+	Log       LogConfig       `yaml:"log"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Notifier  NotifierConfig  `yaml:"notifier"`
+}
+
+type HTTPConfig struct {
+	Port int `yaml:"port" env:"PORT" flag:"port" usage:"HTTP listen port"`
+}
+
+// GRPCConfig управляет gRPC API, которое слушает отдельный порт рядом
+// с HTTP и работает с теми же моделями. По умолчанию выключено.
+// Reflection раскрывает схему API без аутентификации, поэтому допустимо
+// только в окружениях development и test.
+type GRPCConfig struct {
+	Enabled    bool `yaml:"enabled" env:"GRPC_ENABLED" flag:"grpc" usage:"serve the gRPC API"`
+	Port       int  `yaml:"port" env:"GRPC_PORT" flag:"grpc-port" usage:"gRPC listen port"`
+	Reflection bool `yaml:"reflection" env:"GRPC_REFLECTION" flag:"grpc-reflection" usage:"register gRPC server reflection, development and test only"`
+}
+
+// GraphQLConfig ограничивает запросы к /graphql, которые отклоняются до
+// выполнения: MaxDepth — вложенность выборки, MaxComplexity — оценка
+// числа полей, которые запрос может вернуть, с полем под каждым
+// псевдонимом отдельно.
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH" flag:"graphql-max-depth" usage:"maximum selection depth of a GraphQL query"`
+	MaxComplexity int `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" flag:"graphql-max-complexity" usage:"maximum estimated number of fields a GraphQL query may resolve"`
+}
+
+type AuthConfig struct {
+	Required  bool   `yaml:"required" env:"AUTH_REQUIRED" flag:"auth-required" usage:"reject requests without an Authorization header"`
+	JWTSecret string `yaml:"jwt_secret" env:"JWT_SECRET" flag:"jwt-secret" secret:"true" usage:"HMAC secret for bearer tokens"`
+	// DefaultOrganization обслуживает анонимные запросы и bearer-токены
+	// без org_id; 0 — такие запросы отклоняются.
+	DefaultOrganization int `yaml:"default_organization" env:"AUTH_DEFAULT_ORGANIZATION" flag:"auth-default-organization" usage:"organization for anonymous requests and tokens without org_id, 0 rejects them"`
+}
+
+// RateLimitConfig задаёт token bucket для каждого класса маршрутов:
+// PerMinute — скорость пополнения, Burst — ёмкость ведра. Методы gRPC
+// расходуют те же ведра, что и REST-маршруты того же класса.
+type RateLimitConfig struct {
+	Enabled        bool   `yaml:"enabled" env:"RATE_LIMIT_ENABLED" flag:"rate-limit" usage:"enable per-caller rate limiting"`
+	Store          string `yaml:"store" env:"RATE_LIMIT_STORE" flag:"rate-limit-store" usage:"memory (per replica) or postgres (shared)"`
+	ReadPerMinute  int    `yaml:"read_per_minute" env:"RATE_LIMIT_READ_PER_MINUTE" flag:"rate-limit-read-per-minute" usage:"read requests per minute"`
+	ReadBurst      int    `yaml:"read_burst" env:"RATE_LIMIT_READ_BURST" flag:"rate-limit-read-burst" usage:"read burst size"`
+	WritePerMinute int    `yaml:"write_per_minute" env:"RATE_LIMIT_WRITE_PER_MINUTE" flag:"rate-limit-write-per-minute" usage:"write requests per minute"`
+	WriteBurst     int    `yaml:"write_burst" env:"RATE_LIMIT_WRITE_BURST" flag:"rate-limit-write-burst" usage:"write burst size"`
+	BulkPerMinute  int    `yaml:"bulk_per_minute" env:"RATE_LIMIT_BULK_PER_MINUTE" flag:"rate-limit-bulk-per-minute" usage:"bulk delete requests per minute"`
+	BulkBurst      int    `yaml:"bulk_burst" env:"RATE_LIMIT_BULK_BURST" flag:"rate-limit-bulk-burst" usage:"bulk delete burst size"`
+	// IPPerMinute и IPBurst ограничивают все запросы с одного IP ещё до
+	// аутентификации, чтобы перебор ключей и токенов не доходил до базы.
+	IPPerMinute int `yaml:"ip_per_minute" env:"RATE_LIMIT_IP_PER_MINUTE" flag:"rate-limit-ip-per-minute" usage:"requests per minute per client IP before authentication"`
+	IPBurst     int `yaml:"ip_burst" env:"RATE_LIMIT_IP_BURST" flag:"rate-limit-ip-burst" usage:"per client IP burst size before authentication"`
+}
+
+type DBConfig struct {
+	Driver   string `yaml:"driver" env:"DB_DRIVER" flag:"db-driver" usage:"DSN scheme"`
+	Host     string `yaml:"host" env:"DB_HOST" flag:"db-host" usage:"Postgres host"`
+	Port     int    `yaml:"port" env:"DB_PORT" flag:"db-port" usage:"Postgres port"`
+	User     string `yaml:"user" env:"DB_USER" flag:"db-user" usage:"Postgres user"`
+	Password string `yaml:"password" env:"DB_PASSWORD" flag:"db-password" secret:"true" usage:"Postgres password"`
+	Name     string `yaml:"name" env:"DB_NAME" flag:"db-name" usage:"Postgres database"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode" usage:"Postgres sslmode"`
+	Schema   string `yaml:"schema" env:"DB_SCHEMA" flag:"db-schema" usage:"Postgres schema holding the service tables"`
+	// TablePrefix добавляется к именам таблиц и ограничений, чтобы
+	// несколько окружений могли жить и в одной схеме.
+	TablePrefix string `yaml:"table_prefix" env:"DB_TABLE_PREFIX" flag:"db-table-prefix" usage:"prefix for table and constraint names"`
+	// MigrationsTable — таблица версий golang-migrate: "table" внутри
+	// Schema или "schema.table". Пустое значение — <prefix>schema_migrations
+	// внутри Schema.
+	MigrationsTable string `yaml:"migrations_table" env:"DB_MIGRATIONS_TABLE" flag:"db-migrations-table" usage:"migrations version table, optionally schema-qualified"`
+}
+
+type MigrateConfig struct {
+	OnStart     bool          `yaml:"on_start" env:"MIGRATE_ON_START" flag:"migrate" usage:"apply pending migrations before serving, one replica at a time"`
+	LockTimeout time.Duration `yaml:"lock_timeout" env:"MIGRATE_LOCK_TIMEOUT" flag:"migrate-lock-timeout" usage:"how long to wait for another replica's migration lock"`
+}
+
+type TimeoutsConfig struct {
+	Read   time.Duration `yaml:"read" env:"QUERY_TIMEOUT_READ" flag:"query-timeout-read" usage:"deadline for read queries"`
+	Write  time.Duration `yaml:"write" env:"QUERY_TIMEOUT_WRITE" flag:"query-timeout-write" usage:"deadline for write queries"`
+	Report time.Duration `yaml:"report" env:"QUERY_TIMEOUT_REPORT" flag:"query-timeout-report" usage:"deadline for summary, forecast and analytics queries"`
+}
+
+type ShutdownConfig struct {
+	ReadinessDelay time.Duration `yaml:"readiness_delay" env:"SHUTDOWN_READINESS_DELAY" flag:"shutdown-readiness-delay" usage:"time between reporting not ready and draining"`
+	GracePeriod    time.Duration `yaml:"grace_period" env:"SHUTDOWN_GRACE_PERIOD" flag:"shutdown-grace-period" usage:"time allowed for in-flight requests to finish"`
+}
+
+type LogConfig struct {
+	Level      string   `yaml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"debug, info, warn or error"`
+	Format     string   `yaml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"json or text"`
+	RedactMode string   `yaml:"redact_mode" env:"LOG_REDACT_MODE" flag:"log-redact-mode" usage:"none, mask or hash"`
+	RedactKeys []string `yaml:"redact_keys" env:"LOG_REDACT_KEYS" flag:"log-redact-keys" usage:"comma-separated attribute keys to redact"`
+}
+
+type MetricsConfig struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"METRICS_REFRESH_INTERVAL" flag:"metrics-refresh-interval" usage:"business gauges refresh interval"`
+}
+
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" flag:"traces-exporter" usage:"otlp, stdout or none"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" flag:"service-name" usage:"service.name resource attribute"`
+}
+
+type NotifierConfig struct {
+	Enabled    bool          `yaml:"enabled" env:"NOTIFIER_ENABLED" flag:"notifier" usage:"run the renewal reminder scheduler inside the API"`
+	DaysAhead  int           `yaml:"days_ahead" env:"NOTIFY_DAYS_AHEAD" flag:"notify-days-ahead" usage:"days before a charge to send reminders"`
+	Interval   time.Duration `yaml:"interval" env:"NOTIFY_INTERVAL" flag:"notify-interval" usage:"reminder check interval"`
+	WebhookURL string        `yaml:"webhook_url" env:"NOTIFY_WEBHOOK_URL" flag:"notify-webhook-url" secret:"true" usage:"webhook channel URL"`
+	SMTP       SMTPConfig    `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST" flag:"smtp-host" usage:"SMTP host, empty disables email"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" flag:"smtp-port" usage:"SMTP port"`
+	User     string `yaml:"user" env:"SMTP_USER" flag:"smtp-user" usage:"SMTP user, empty disables auth"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" flag:"smtp-password" secret:"true" usage:"SMTP password"`
+	From     string `yaml:"from" env:"SMTP_FROM" flag:"smtp-from" usage:"sender address"`
+}
+
+func Default() Config {
+	return Config{
+		Environment: "development",
+		HTTP:        HTTPConfig{Port: 8080},
+		GRPC:        GRPCConfig{Port: 9090},
+		GraphQL:     GraphQLConfig{MaxDepth: 8, MaxComplexity: 10000},
+		Auth:        AuthConfig{DefaultOrganization: 1},
+		RateLimit: RateLimitConfig{
+			Enabled:        true,
+			Store:          "memory",
+			ReadPerMinute:  120,
+			ReadBurst:      60,
+			WritePerMinute: 30,
+			WriteBurst:     10,
+			BulkPerMinute:  5,
+			BulkBurst:      2,
+			IPPerMinute:    600,
+			IPBurst:        120,
+		},
+		DB: DBConfig{
+			Driver:  "postgres",
+			Host:    "localhost",
+			Port:    5432,
+			User:    "myuser",
+			Name:    "subscription_service",
+			SSLMode: "disable",
+			Schema:  "infosub",
+		},
+		Migrate: MigrateConfig{LockTimeout: 5 * time.Minute},
+		Timeouts: TimeoutsConfig{
+			Read:   3 * time.Second,
+			Write:  3 * time.Second,
+			Report: 15 * time.Second,
+		},
+		Shutdown: ShutdownConfig{
+			ReadinessDelay: 2 * time.Second,
+			GracePeriod:    20 * time.Second,
+		},
+		Log: LogConfig{
+			Level:      "info",
+			Format:     "json",
+			RedactMode: "mask",
+			RedactKeys: []string{"user_id"},
+		},
+		Metrics: MetricsConfig{RefreshInterval: time.Minute},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "subscription-service",
+		},
+		Notifier: NotifierConfig{
+			DaysAhead: 3,
+			Interval:  time.Hour,
+			SMTP: SMTPConfig{
+				Port: 25,
+				From: "noreply@subscription-service.local",
+			},
+		},
+	}
+}
+
+// DSN собирает строку подключения к Postgres.
+func (c DBConfig) DSN() string {
+	u := url.URL{
+		Scheme:   c.Driver,
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+// MigrationsTableName возвращает схему и имя таблицы версий миграций.
+func (c DBConfig) MigrationsTableName() (schema, table string) {
+	if c.MigrationsTable == "" {
+		return c.Schema, c.TablePrefix + "schema_migrations"
+	}
+	if schema, table, ok := strings.Cut(c.MigrationsTable, "."); ok {
+		return schema, table
+	}
+	return c.Schema, c.MigrationsTable
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv убирает на время теста все переменные, которые читает Load,
+// чтобы окружение машины не влияло на результат.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	keys := []string{"CONFIG_FILE"}
+	cfg := Default()
+	walk(reflect.ValueOf(&cfg).Elem(), func(f reflect.StructField, _ reflect.Value) {
+		if key := f.Tag.Get("env"); key != "" {
+			keys = append(keys, key)
+		}
+	})
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+http:
+  port: 8001
+db:
+  host: file-host
+  name: file-db
+log:
+  level: debug
+`)
+	t.Setenv("PORT", "8002")
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("LOG_REDACT_KEYS", "user_id, email")
+
+	cfg, opts, err := Load("test", []string{"--config", path, "--port", "8003", "--grpc", "migrate"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"flag over env and file", cfg.HTTP.Port, 8003},
+		{"env over file", cfg.DB.Host, "env-host"},
+		{"file over default", cfg.DB.Name, "file-db"},
+		{"file over default", cfg.Log.Level, "debug"},
+		{"default", cfg.Timeouts.Read, 3 * time.Second},
+		{"bool flag without value", cfg.GRPC.Enabled, true},
+		{"list from env", cfg.Log.RedactKeys, []string{"user_id", "email"}},
+		{"positional args", opts.Args, []string{"migrate"}},
+		{"config file", opts.ConfigFile, path},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: want %v, got %v", tt.name, tt.want, tt.got)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfig(t, "http:\n  port: 8001\n"))
+
+	cfg, _, err := Load("test", nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.HTTP.Port != 8001 {
+		t.Fatalf("CONFIG_FILE: want port 8001, got %d", cfg.HTTP.Port)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown yaml key", file: "http:\n  prot: 8001\n", wantErr: "field prot not found"},
+		{name: "bad yaml value", file: "http:\n  port: eighty\n", wantErr: "parse config file"},
+		{name: "bad env integer", env: map[string]string{"PORT": "eighty"}, wantErr: `env PORT: invalid integer "eighty"`},
+		{name: "bad env duration", env: map[string]string{"QUERY_TIMEOUT_READ": "3"}, wantErr: `env QUERY_TIMEOUT_READ: invalid duration "3"`},
+		{name: "bad flag boolean", args: []string{"--grpc=maybe"}, wantErr: `flag --grpc: invalid boolean "maybe"`},
+		{name: "unknown flag", args: []string{"--no-such-flag"}, wantErr: "flag provided but not defined"},
+		{name: "missing file", args: []string{"--config", "/nonexistent/config.yaml"}, wantErr: "read config file"},
+		{name: "invalid after merge", env: map[string]string{"LOG_LEVEL": "loud"}, wantErr: "log.level"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeConfig(t, tt.file)}, args...)
+			}
+
+			_, _, err := Load("test", args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default must be valid: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*Config)
+		wantErr string
+	}{
+		{"environment", func(c *Config) { c.Environment = "prod" }, "environment"},
+		{"http port", func(c *Config) { c.HTTP.Port = 70000 }, "http.port: 70000 is not a valid port"},
+		{"grpc port clash", func(c *Config) { c.GRPC.Enabled, c.GRPC.Port = true, c.HTTP.Port }, "grpc.port: must differ"},
+		{"grpc reflection in production", func(c *Config) { c.Environment, c.GRPC.Reflection = "production", true }, "grpc.reflection"},
+		{"graphql depth", func(c *Config) { c.GraphQL.MaxDepth = 0 }, "graphql.max_depth"},
+		{"negative default organization", func(c *Config) { c.Auth.DefaultOrganization = -1 }, "auth.default_organization"},
+		{"rate limit store", func(c *Config) { c.RateLimit.Store = "redis" }, "rate_limit.store"},
+		{"rate limit burst", func(c *Config) { c.RateLimit.IPBurst = 0 }, "rate_limit.ip_burst"},
+		{"db schema", func(c *Config) { c.DB.Schema = "Bad-Schema" }, "db.schema"},
+		{"table prefix", func(c *Config) { c.DB.TablePrefix = "x-" }, "db.table_prefix"},
+		{"migrations table", func(c *Config) { c.DB.MigrationsTable = "a.b.c" }, "db.migrations_table"},
+		{"timeout", func(c *Config) { c.Timeouts.Report = 0 }, "timeouts.report: must be positive"},
+		{"readiness delay", func(c *Config) { c.Shutdown.ReadinessDelay = -time.Second }, "shutdown.readiness_delay"},
+		{"redact mode", func(c *Config) { c.Log.RedactMode = "drop" }, "log.redact_mode"},
+		{"tracing exporter", func(c *Config) { c.Tracing.Exporter = "zipkin" }, "tracing.exporter"},
+		{"webhook url", func(c *Config) { c.Notifier.WebhookURL = "ftp://hooks" }, "notifier.webhook_url"},
+		{"smtp from", func(c *Config) { c.Notifier.SMTP.Host, c.Notifier.SMTP.From = "mail", "" }, "notifier.smtp.from"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.edit(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("reports every error", func(t *testing.T) {
+		cfg := Default()
+		cfg.HTTP.Port = 0
+		cfg.Log.Level = "loud"
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "http.port") || !strings.Contains(err.Error(), "log.level") {
+			t.Fatalf("want both http.port and log.level errors, got %v", err)
+		}
+	})
+
+	t.Run("grpc reflection in development", func(t *testing.T) {
+		cfg := Default()
+		cfg.GRPC.Reflection = true
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("want no error, got %v", err)
+		}
+	})
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.DB.Password = "db-password-value"
+	cfg.Auth.JWTSecret = "jwt-secret-value"
+	cfg.Notifier.WebhookURL = "https://hooks.example.com/token-value"
+	cfg.Notifier.SMTP.Password = "smtp-password-value"
+	cfg.DB.User = "visible-user"
+
+	var buf bytes.Buffer
+	if err := Print(&buf, cfg); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	out := buf.String()
+
+	for _, secret := range []string{"db-password-value", "jwt-secret-value", "token-value", "smtp-password-value"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("output leaks %q:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{
+		"password: '" + redacted + "'",
+		"jwt_secret: '" + redacted + "'",
+		"webhook_url: '" + redacted + "'",
+		"user: visible-user",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if cfg.DB.Password != "db-password-value" {
+		t.Errorf("Print must not change the caller's config, got db.password %q", cfg.DB.Password)
+	}
+
+	// Пустой секрет остаётся пустым: иначе по выводу не понять, задан ли он.
+	buf.Reset()
+	if err := Print(&buf, Default()); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if strings.Contains(buf.String(), redacted) {
+		t.Errorf("empty secrets must not be redacted:\n%s", buf.String())
+	}
+}
+
+func TestLoadPrintConfigFlag(t *testing.T) {
+	clearEnv(t)
+	_, opts, err := Load("test", []string{"--print-config"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !opts.PrintConfig {
+		t.Fatal("--print-config must set Options.PrintConfig")
+	}
+}
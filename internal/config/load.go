@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Options — флаги, которые управляют самой загрузкой, а не настройками.
+type Options struct {
+	ConfigFile  string
+	PrintConfig bool
+	// Args — позиционные аргументы после флагов (например, команда migrate).
+	Args []string
+}
+
+// Load собирает конфигурацию для программы name из аргументов args
+// (без имени программы). Путь к YAML-файлу задаётся флагом --config или
+// переменной CONFIG_FILE.
+func Load(name string, args []string) (Config, Options, error) {
+	cfg := Default()
+	var opts Options
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigFile, "config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective config with secrets redacted and exit")
+
+	// Флаги разбираем первыми, чтобы узнать путь к файлу, но применяем
+	// последними, чтобы они переопределяли файл и окружение.
+	flagValues := make(map[string]string)
+	setters := make(map[string]reflect.Value)
+	walk(reflect.ValueOf(&cfg).Elem(), func(f reflect.StructField, v reflect.Value) {
+		name := f.Tag.Get("flag")
+		if name == "" {
+			return
+		}
+		setters[name] = v
+
+		store := func(s string) error {
+			flagValues[name] = s
+			return nil
+		}
+		if v.Kind() == reflect.Bool {
+			fs.BoolFunc(name, f.Tag.Get("usage"), store)
+		} else {
+			fs.Func(name, f.Tag.Get("usage"), store)
+		}
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, opts, err
+	}
+	opts.Args = fs.Args()
+
+	if opts.ConfigFile != "" {
+		if err := loadFile(&cfg, opts.ConfigFile); err != nil {
+			return cfg, opts, err
+		}
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return cfg, opts, err
+	}
+
+	var errs []error
+	fs.Visit(func(f *flag.Flag) {
+		v, ok := setters[f.Name]
+		if !ok {
+			return
+		}
+		if err := parseInto(v, flagValues[f.Name]); err != nil {
+			errs = append(errs, fmt.Errorf("flag --%s: %w", f.Name, err))
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return cfg, opts, err
+	}
+
+	return cfg, opts, cfg.Validate()
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), func(f reflect.StructField, v reflect.Value) {
+		key := f.Tag.Get("env")
+		if key == "" {
+			return
+		}
+		raw, ok := os.LookupEnv(key)
+		if !ok {
+			return
+		}
+		if err := parseInto(v, raw); err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", key, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Print выводит итоговую конфигурацию в YAML, скрывая секреты.
+func Print(w io.Writer, cfg Config) error {
+	walk(reflect.ValueOf(&cfg).Elem(), func(f reflect.StructField, v reflect.Value) {
+		if f.Tag.Get("secret") == "true" && v.Kind() == reflect.String && v.String() != "" {
+			v.SetString(redacted)
+		}
+	})
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// walk обходит листовые поля вложенных структур.
+func walk(v reflect.Value, fn func(reflect.StructField, reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if fv.Kind() == reflect.Struct && f.Type != reflect.TypeOf(time.Duration(0)) {
+			walk(fv, fn)
+			continue
+		}
+		fn(f, fv)
+	}
+}
+
+func parseInto(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config field type %s", v.Type())
+	}
+	return nil
+}
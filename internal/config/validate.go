@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"time"
+)
+
+var (
+	identifier  = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+	tablePrefix = regexp.MustCompile(`^([a-z_][a-z0-9_]{0,31})?$`)
+)
+
+// Validate проверяет все поля сразу и возвращает все найденные ошибки.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(field, value string, allowed ...string) {
+		check(slices.Contains(allowed, value), "%s: %q is not one of %v", field, value, allowed)
+	}
+	positive := func(field string, d time.Duration) {
+		check(d > 0, "%s: must be positive, got %s", field, d)
+	}
+	port := func(field string, p int) {
+		check(p > 0 && p < 65536, "%s: %d is not a valid port", field, p)
+	}
+
+	oneOf("environment", c.Environment, "development", "test", "staging", "demo", "production")
+	port("http.port", c.HTTP.Port)
+	if c.GRPC.Enabled {
+		port("grpc.port", c.GRPC.Port)
+		check(c.GRPC.Port != c.HTTP.Port, "grpc.port: must differ from http.port %d", c.HTTP.Port)
+	}
+	check(!c.GRPC.Reflection || c.Environment == "development" || c.Environment == "test",
+		"grpc.reflection: allowed only in development and test, got environment %q", c.Environment)
+
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth: must be positive, got %d", c.GraphQL.MaxDepth)
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity: must be positive, got %d", c.GraphQL.MaxComplexity)
+
+	check(c.Auth.DefaultOrganization >= 0, "auth.default_organization: must not be negative, got %d", c.Auth.DefaultOrganization)
+
+	oneOf("rate_limit.store", c.RateLimit.Store, "memory", "postgres")
+	positiveInt := func(field string, n int) {
+		check(n > 0, "%s: must be positive, got %d", field, n)
+	}
+	positiveInt("rate_limit.read_per_minute", c.RateLimit.ReadPerMinute)
+	positiveInt("rate_limit.read_burst", c.RateLimit.ReadBurst)
+	positiveInt("rate_limit.write_per_minute", c.RateLimit.WritePerMinute)
+	positiveInt("rate_limit.write_burst", c.RateLimit.WriteBurst)
+	positiveInt("rate_limit.bulk_per_minute", c.RateLimit.BulkPerMinute)
+	positiveInt("rate_limit.bulk_burst", c.RateLimit.BulkBurst)
+	positiveInt("rate_limit.ip_per_minute", c.RateLimit.IPPerMinute)
+	positiveInt("rate_limit.ip_burst", c.RateLimit.IPBurst)
+
+	check(c.DB.Driver != "", "db.driver: must not be empty")
+	check(c.DB.Host != "", "db.host: must not be empty")
+	port("db.port", c.DB.Port)
+	check(c.DB.User != "", "db.user: must not be empty")
+	check(c.DB.Name != "", "db.name: must not be empty")
+	oneOf("db.sslmode", c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	check(identifier.MatchString(c.DB.Schema), "db.schema: %q is not a valid lowercase identifier", c.DB.Schema)
+	check(tablePrefix.MatchString(c.DB.TablePrefix), "db.table_prefix: %q must be a lowercase identifier of at most 32 characters", c.DB.TablePrefix)
+	if c.DB.MigrationsTable != "" {
+		schema, table := c.DB.MigrationsTableName()
+		check(identifier.MatchString(schema) && identifier.MatchString(table),
+			"db.migrations_table: %q must be \"table\" or \"schema.table\" with lowercase identifiers", c.DB.MigrationsTable)
+	}
+
+	positive("migrate.lock_timeout", c.Migrate.LockTimeout)
+
+	positive("timeouts.read", c.Timeouts.Read)
+	positive("timeouts.write", c.Timeouts.Write)
+	positive("timeouts.report", c.Timeouts.Report)
+
+	check(c.Shutdown.ReadinessDelay >= 0, "shutdown.readiness_delay: must not be negative")
+	positive("shutdown.grace_period", c.Shutdown.GracePeriod)
+
+	oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error")
+	oneOf("log.format", c.Log.Format, "json", "text")
+	oneOf("log.redact_mode", c.Log.RedactMode, "none", "mask", "hash")
+
+	positive("metrics.refresh_interval", c.Metrics.RefreshInterval)
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, "otlp", "stdout", "none")
+	check(c.Tracing.ServiceName != "", "tracing.service_name: must not be empty")
+
+	check(c.Notifier.DaysAhead >= 0, "notifier.days_ahead: must not be negative")
+	positive("notifier.interval", c.Notifier.Interval)
+	if c.Notifier.WebhookURL != "" {
+		u, err := url.Parse(c.Notifier.WebhookURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"notifier.webhook_url: must be an absolute http(s) URL")
+	}
+	if c.Notifier.SMTP.Host != "" {
+		port("notifier.smtp.port", c.Notifier.SMTP.Port)
+		check(c.Notifier.SMTP.From != "", "notifier.smtp.from: must not be empty")
+	}
+
+	return errors.Join(errs...)
+}
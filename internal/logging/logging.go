@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"subscription-service/internal/config"
+)
+
+type requestIDKey struct{}
+
+// requestIDAttr — ключ атрибута с ID запроса в записях лога.
+const requestIDAttr = "request_id"
+
+// WithRequestID кладёт ID запроса в контекст; ContextHandler добавляет его
+// к каждой записи, сделанной с этим контекстом.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New создаёт логгер в формате json или text с редактированием
+// чувствительных значений по правилам r.
+func New(w io.Writer, level slog.Level, format string, r *Redactor) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: r.ReplaceAttr,
+	}
+
+	var h slog.Handler
+	switch format {
+	case "json", "":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q: use json or text", format)
+	}
+
+	return slog.New(&ContextHandler{Handler: h}), nil
+}
+
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q: use debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+// ContextHandler добавляет request_id из контекста записи.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		rec.AddAttrs(slog.String(requestIDAttr, id))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// NewFromConfig собирает логгер по секции log конфигурации.
+func NewFromConfig(w io.Writer, cfg config.LogConfig) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewRedactor(cfg.RedactMode, cfg.RedactKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(w, level, cfg.Format, r)
+}
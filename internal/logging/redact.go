@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+type RedactMode string
+
+const (
+	RedactNone RedactMode = "none"
+	RedactMask RedactMode = "mask"
+	RedactHash RedactMode = "hash"
+)
+
+var uuidPattern = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// Redactor скрывает UUID пользователей в логах. Значения атрибутов с
+// ключами из Keys редактируются целиком, а UUID внутри любых строк
+// (например, в пути запроса) — по отдельности; ошибки и значения с
+// методом String проверяются по их строковому виду. request_id не трогается,
+// даже если клиент прислал UUID: по нему записи ищут и сопоставляют с
+// ответом, а пользователя он не раскрывает.
+//
+// mask оставляет последние 4 символа, hash заменяет значение коротким
+// SHA-256, по которому записи одного пользователя можно сопоставить.
+type Redactor struct {
+	Mode RedactMode
+	Keys map[string]bool
+}
+
+func NewRedactor(mode string, keys []string) (*Redactor, error) {
+	r := &Redactor{Mode: RedactMode(mode), Keys: make(map[string]bool)}
+	switch r.Mode {
+	case RedactNone, RedactMask, RedactHash:
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q: use none, mask or hash", mode)
+	}
+
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			r.Keys[k] = true
+		}
+	}
+	return r, nil
+}
+
+func (r *Redactor) ReplaceAttr(_ []string, a slog.Attr) slog.Attr {
+	if r == nil || r.Mode == RedactNone {
+		return a
+	}
+
+	if r.Keys[a.Key] {
+		return slog.String(a.Key, r.redact(a.Value.String()))
+	}
+	if a.Key == requestIDAttr {
+		return a
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, uuidPattern.ReplaceAllStringFunc(a.Value.String(), r.redact))
+	case slog.KindAny:
+		// Ошибка базы или обёрнутый fmt.Errorf легко несут UUID в тексте.
+		// Значение без UUID остаётся как есть, чтобы обработчик вывел его
+		// по-своему.
+		var s string
+		switch v := a.Value.Any().(type) {
+		case error:
+			s = v.Error()
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			return a
+		}
+		if redacted := uuidPattern.ReplaceAllStringFunc(s, r.redact); redacted != s {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	return a
+}
+
+func (r *Redactor) redact(s string) string {
+	switch r.Mode {
+	case RedactHash:
+		sum := sha256.Sum256([]byte(strings.ToLower(s)))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	default:
+		if len(s) <= 4 {
+			return "****"
+		}
+		return "****" + s[len(s)-4:]
+	}
+}
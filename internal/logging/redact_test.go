@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactorKeepsRequestID(t *testing.T) {
+	const (
+		requestID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+		userID    = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	)
+	r, err := NewRedactor(string(RedactMask), []string{"user_id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger, err := New(&buf, slog.LevelInfo, "json", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithRequestID(context.Background(), requestID)
+	logger.InfoContext(ctx, "request", "path", "/api/subscriptions/user/"+userID, "user_id", userID)
+
+	out := buf.String()
+	if !strings.Contains(out, `"request_id":"`+requestID+`"`) {
+		t.Errorf("request_id was redacted: %s", out)
+	}
+	if strings.Contains(out, userID) {
+		t.Errorf("user UUID leaked: %s", out)
+	}
+	if !strings.Contains(out, "/api/subscriptions/user/****0cba") {
+		t.Errorf("UUID in path was not masked: %s", out)
+	}
+}
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+func TestRedactorAnyValues(t *testing.T) {
+	const userID = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	r, err := NewRedactor(string(RedactMask), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		value    any
+		wantKind slog.Kind
+		want     string
+	}{
+		{"error", fmt.Errorf("load subscriptions of %s: %w", userID, sql.ErrNoRows), slog.KindString, "load subscriptions of ****0cba: sql: no rows in result set"},
+		{"stringer", stringer("user " + userID), slog.KindString, "user ****0cba"},
+		{"error without uuid", sql.ErrNoRows, slog.KindAny, sql.ErrNoRows.Error()},
+		{"other any", []string{userID}, slog.KindAny, "[" + userID + "]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.ReplaceAttr(nil, slog.Any("error", tt.value)).Value
+			if got.Kind() != tt.wantKind || got.String() != tt.want {
+				t.Fatalf("want %s %q, got %s %q", tt.wantKind, tt.want, got.Kind(), got.String())
+			}
+		})
+	}
+}
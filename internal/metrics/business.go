@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"subscription-service/internal/models"
+	"time"
+)
+
+// RefreshBusiness пересчитывает число активных подписок и MRR по сервисам
+// за текущий месяц по всем организациям.
+func (m *Metrics) RefreshBusiness(ctx context.Context, repo models.SubscriptionRepository) error {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	subs, err := repo.GetActive(models.WithAllOrganizations(ctx), month)
+	if err != nil {
+		return err
+	}
+
+	m.activeSubscriptions.Reset()
+	m.mrr.Reset()
+	for _, service := range models.AnalyticsByService(subs, month, month, nil) {
+		current := service.Months[0]
+		m.activeSubscriptions.WithLabelValues(service.ServiceName).Set(float64(current.Active))
+		m.mrr.WithLabelValues(service.ServiceName).Set(float64(current.MRR))
+	}
+	m.businessRefreshed.SetToCurrentTime()
+	return nil
+}
+
+// BusinessRefresher возвращает фоновую задачу, которая обновляет
+// бизнес-метрики раз в interval, а не на каждый scrape.
+func (m *Metrics) BusinessRefresher(repo models.SubscriptionRepository, interval, timeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			refreshCtx, cancel := context.WithTimeout(ctx, timeout)
+			if err := m.RefreshBusiness(refreshCtx, repo); err != nil {
+				slog.Error("refresh business gauges", "error", err)
+			}
+			cancel()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "subscription_service"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpDuration *prometheus.HistogramVec
+	httpInFlight prometheus.Gauge
+
+	grpcDuration *prometheus.HistogramVec
+
+	queryDuration *prometheus.HistogramVec
+
+	activeSubscriptions *prometheus.GaugeVec
+	mrr                 *prometheus.GaugeVec
+	businessRefreshed   prometheus.Gauge
+}
+
+// New регистрирует метрики процесса, пула соединений db, HTTP, gRPC, запросов
+// к хранилищу и бизнес-показателей в собственном реестре.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		}),
+
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of unary gRPC calls by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of subscription repository calls by method and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "outcome"}),
+
+		activeSubscriptions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_subscriptions",
+			Help:      "Number of subscriptions active in the current month by service.",
+		}, []string{"service"}),
+		mrr: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "mrr",
+			Help:      "Monthly recurring revenue for the current month by service.",
+		}, []string{"service"}),
+		businessRefreshed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "business_metrics_refreshed_timestamp_seconds",
+			Help:      "Unix time of the last successful business gauges refresh.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "subscription_service"),
+		m.httpDuration,
+		m.httpInFlight,
+		m.grpcDuration,
+		m.queryDuration,
+		m.activeSubscriptions,
+		m.mrr,
+		m.businessRefreshed,
+	)
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware замеряет запросы по шаблону маршрута, а не по фактическому
+// пути, чтобы ID в URL не раздували число временных рядов.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.httpInFlight.Inc()
+		defer m.httpInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// UnaryServerInterceptor замеряет unary-вызовы gRPC по полному имени
+// метода и коду ответа, в том числе отклонённые до обработчика.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.grpcDuration.
+			WithLabelValues(info.FullMethod, status.Code(err).String()).
+			Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// observeQuery принимает указатель на именованный результат, чтобы его
+// можно было вызывать через defer.
+func (m *Metrics) observeQuery(method string, start time.Time, err *error) {
+	outcome := "ok"
+	if *err != nil {
+		outcome = "error"
+	}
+	m.queryDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"subscription-service/internal/models"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// subscriptions оборачивает SubscriptionRepository и замеряет
+// длительность каждого вызова.
+type subscriptions struct {
+	next    models.SubscriptionRepository
+	metrics *Metrics
+}
+
+func (m *Metrics) InstrumentSubscriptions(next models.SubscriptionRepository) models.SubscriptionRepository {
+	return &subscriptions{next: next, metrics: m}
+}
+
+func (r *subscriptions) GetAll(ctx context.Context) (subs []*models.Subscription, err error) {
+	defer r.metrics.observeQuery("GetAll", time.Now(), &err)
+	return r.next.GetAll(ctx)
+}
+
+func (r *subscriptions) Get(ctx context.Context, id int) (sub *models.Subscription, err error) {
+	defer r.metrics.observeQuery("Get", time.Now(), &err)
+	return r.next.Get(ctx, id)
+}
+
+func (r *subscriptions) GetByUserID(ctx context.Context, uid uuid.UUID) (subs []*models.Subscription, err error) {
+	defer r.metrics.observeQuery("GetByUserID", time.Now(), &err)
+	return r.next.GetByUserID(ctx, uid)
+}
+
+func (r *subscriptions) GetByUserSubscription(ctx context.Context, serviceName string) (subs []*models.Subscription, err error) {
+	defer r.metrics.observeQuery("GetByUserSubscription", time.Now(), &err)
+	return r.next.GetByUserSubscription(ctx, serviceName)
+}
+
+func (r *subscriptions) GetActive(ctx context.Context, at time.Time) (subs []*models.Subscription, err error) {
+	defer r.metrics.observeQuery("GetActive", time.Now(), &err)
+	return r.next.GetActive(ctx, at)
+}
+
+func (r *subscriptions) Find(ctx context.Context, filter models.SubscriptionFilter) (subs []*models.Subscription, err error) {
+	defer r.metrics.observeQuery("Find", time.Now(), &err)
+	return r.next.Find(ctx, filter)
+}
+
+func (r *subscriptions) UserIDs(ctx context.Context, limit int) (uids []uuid.UUID, err error) {
+	defer r.metrics.observeQuery("UserIDs", time.Now(), &err)
+	return r.next.UserIDs(ctx, limit)
+}
+
+func (r *subscriptions) Insert(ctx context.Context, sub *models.Subscription) (err error) {
+	defer r.metrics.observeQuery("Insert", time.Now(), &err)
+	return r.next.Insert(ctx, sub)
+}
+
+func (r *subscriptions) Delete(ctx context.Context, id int) (err error) {
+	defer r.metrics.observeQuery("Delete", time.Now(), &err)
+	return r.next.Delete(ctx, id)
+}
+
+func (r *subscriptions) DeleteByUserID(ctx context.Context, uid uuid.UUID) (err error) {
+	defer r.metrics.observeQuery("DeleteByUserID", time.Now(), &err)
+	return r.next.DeleteByUserID(ctx, uid)
+}
+
+func (r *subscriptions) DeleteByServiceName(ctx context.Context, serviceName string) (err error) {
+	defer r.metrics.observeQuery("DeleteByServiceName", time.Now(), &err)
+	return r.next.DeleteByServiceName(ctx, serviceName)
+}
+
+func (r *subscriptions) SetMembers(ctx context.Context, id int, members []models.SubscriptionMember) (err error) {
+	defer r.metrics.observeQuery("SetMembers", time.Now(), &err)
+	return r.next.SetMembers(ctx, id, members)
+}
+
+func (r *subscriptions) Update(ctx context.Context, upd models.Subscription) (err error) {
+	defer r.metrics.observeQuery("Update", time.Now(), &err)
+	return r.next.Update(ctx, upd)
+}
+
+func (r *subscriptions) GetSummary(ctx context.Context, from, to time.Time, filter models.SubscriptionFilter) (subs []*models.SubscriptionWithCost, total int, err error) {
+	defer r.metrics.observeQuery("GetSummary", time.Now(), &err)
+	return r.next.GetSummary(ctx, from, to, filter)
+}
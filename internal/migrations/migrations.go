@@ -0,0 +1,144 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"subscription-service/internal/config"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// New собирает экземпляр golang-migrate поверх встроенных в бинарник
+// миграций с раскладкой таблиц из cfg. Недостающие схемы создаются.
+// Экземпляр держит отдельное соединение из пула db; Close возвращает его,
+// не закрывая сам пул.
+func New(ctx context.Context, db *sql.DB, cfg config.DBConfig) (*migrate.Migrate, error) {
+	// Схемы создаём заранее: таблица версий golang-migrate должна
+	// появиться до первой миграции.
+	migrationsSchema, migrationsTable := cfg.MigrationsTableName()
+	for _, schema := range []string{cfg.Schema, migrationsSchema} {
+		if _, err := db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+schema); err != nil {
+			return nil, fmt.Errorf("create schema %s: %w", schema, err)
+		}
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := postgres.WithConnection(ctx, conn, &postgres.Config{
+		SchemaName:      migrationsSchema,
+		MigrationsTable: migrationsTable,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	src, err := iofs.New(files, "sql")
+	if err != nil {
+		instance.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", Templated(src, Tables(cfg)), "postgres", instance)
+	if err != nil {
+		instance.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Up применяет все недостающие миграции под advisory-блокировкой
+// Postgres, так что при одновременном старте нескольких реплик мигрирует
+// только одна, а остальные дожидаются её и находят схему актуальной.
+// Ожидание блокировки ограничено ctx.
+func Up(ctx context.Context, db *sql.DB, cfg config.DBConfig) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	key := lockKey(cfg)
+	slog.InfoContext(ctx, "waiting for migration lock", "lock_key", key)
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Блокировка сессионная: отпускаем её явно, пока соединение не
+		// вернулось в пул.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			slog.Error("release migration lock", "error", err)
+		}
+	}()
+
+	m, err := New(ctx, db, cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "migrations applied", "version", version, "dirty", dirty)
+	return nil
+}
+
+// lockKey отличается от ключа, который golang-migrate берёт сам на время
+// каждой операции, иначе вторая блокировка ждала бы первую вечно.
+func lockKey(cfg config.DBConfig) int64 {
+	schema, table := cfg.MigrationsTableName()
+	h := fnv.New64a()
+	fmt.Fprintf(h, "subscription-service:auto-migrate:%s:%s.%s", cfg.Name, schema, table)
+	return int64(h.Sum64())
+}
+
+// Migration — одна встроенная миграция.
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// Available перечисляет встроенные миграции по возрастанию версии.
+func Available() ([]Migration, error) {
+	src, err := iofs.New(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var list []Migration
+	version, err := src.First()
+	for err == nil {
+		r, name, rerr := src.ReadUp(version)
+		if rerr != nil {
+			return nil, rerr
+		}
+		r.Close()
+		list = append(list, Migration{Version: version, Name: name})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS {{.Table "subscriptions"}} CASCADE;
\ No newline at end of file
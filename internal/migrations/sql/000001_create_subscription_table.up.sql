@@ -1,9 +1,9 @@
-CREATE TABLE IF NOT EXISTS infosub.subscriptions (
+CREATE TABLE IF NOT EXISTS {{.Table "subscriptions"}} (
      id SERIAL PRIMARY KEY,
      service_name TEXT NOT NULL,
      price INTEGER NOT NULL,
      user_id UUID NOT NULL,
      start_date DATE NOT NULL,
      end_date DATE,
-     CONSTRAINT unique_service_user UNIQUE (service_name, user_id)
+     CONSTRAINT {{.Ident "unique_service_user"}} UNIQUE (service_name, user_id)
 );
\ No newline at end of file
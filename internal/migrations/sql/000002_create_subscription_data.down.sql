@@ -0,0 +1,2 @@
+-- Откат раньше выполнял TRUNCATE ... CASCADE и стирал рабочие данные.
+-- Прямая миграция больше ничего не вставляет, поэтому и откатывать нечего.
@@ -0,0 +1,4 @@
+-- Раньше здесь вставлялись демонстрационные подписки. Данные больше не
+-- относятся к схеме: их загружает cmd/seed (набор fixtures "demo"), который
+-- отказывается работать в production. Миграция оставлена пустой, чтобы не
+-- ломать нумерацию и уже применённые базы.
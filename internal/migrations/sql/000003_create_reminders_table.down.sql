@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS {{.Table "reminders"}} CASCADE;
+DROP TABLE IF EXISTS {{.Table "user_contacts"}} CASCADE;
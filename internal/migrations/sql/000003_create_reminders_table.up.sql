@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS {{.Table "user_contacts"}} (
+     user_id UUID PRIMARY KEY,
+     email TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS {{.Table "reminders"}} (
+     id SERIAL PRIMARY KEY,
+     subscription_id INTEGER NOT NULL REFERENCES {{.Table "subscriptions"}} (id) ON DELETE CASCADE,
+     charge_date DATE NOT NULL,
+     channel TEXT NOT NULL,
+     sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+     CONSTRAINT {{.Ident "unique_reminder"}} UNIQUE (subscription_id, charge_date, channel)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS {{.Table "budgets"}} (
+     id SERIAL PRIMARY KEY,
+     user_id UUID NOT NULL,
+     category TEXT NOT NULL DEFAULT '',
+     monthly_limit INTEGER NOT NULL CHECK (monthly_limit >= 0),
+     CONSTRAINT {{.Ident "unique_budget_user_category"}} UNIQUE (user_id, category)
+);
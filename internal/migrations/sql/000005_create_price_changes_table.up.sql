@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS {{.Table "price_changes"}} (
+     id SERIAL PRIMARY KEY,
+     subscription_id INTEGER NOT NULL REFERENCES {{.Table "subscriptions"}} (id) ON DELETE CASCADE,
+     effective_date DATE NOT NULL,
+     price INTEGER NOT NULL,
+     CONSTRAINT {{.Ident "unique_price_change"}} UNIQUE (subscription_id, effective_date)
+);
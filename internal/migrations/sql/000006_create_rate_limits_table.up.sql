@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS {{.Table "rate_limits"}} (
+     key TEXT PRIMARY KEY,
+     tokens DOUBLE PRECISION NOT NULL,
+     allowed BOOLEAN NOT NULL,
+     updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS {{.Ident "rate_limits_updated_at_idx"}} ON {{.Table "rate_limits"}} (updated_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS {{.Table "api_keys"}} (
+     id SERIAL PRIMARY KEY,
+     name TEXT NOT NULL,
+     prefix TEXT NOT NULL,
+     key_hash TEXT NOT NULL,
+     scopes TEXT[] NOT NULL,
+     expires_at TIMESTAMPTZ,
+     last_used_at TIMESTAMPTZ,
+     created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+     revoked_at TIMESTAMPTZ,
+     CONSTRAINT {{.Ident "unique_api_key_prefix"}} UNIQUE (prefix)
+);
@@ -0,0 +1,4 @@
+-- Канонические имена, проставленные подпискам при переходе на каталог,
+-- остаются: исходные написания не сохранялись.
+DROP TABLE IF EXISTS {{.Table "service_aliases"}} CASCADE;
+DROP TABLE IF EXISTS {{.Table "services"}} CASCADE;
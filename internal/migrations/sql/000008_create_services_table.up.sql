@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS {{.Table "services"}} (
+     id SERIAL PRIMARY KEY,
+     name TEXT NOT NULL,
+     category TEXT NOT NULL DEFAULT '',
+     default_price INTEGER,
+     website TEXT NOT NULL DEFAULT '',
+     CONSTRAINT {{.Ident "unique_service_name"}} UNIQUE (name)
+);
+
+-- alias хранится нормализованным: нижний регистр, без пробелов по краям
+-- и без повторных пробелов внутри. Каноническое имя тоже его псевдоним.
+CREATE TABLE IF NOT EXISTS {{.Table "service_aliases"}} (
+     alias TEXT PRIMARY KEY,
+     service_id INTEGER NOT NULL REFERENCES {{.Table "services"}} (id) ON DELETE CASCADE
+);
+
+-- Каталог из уже существующих подписок: одно имя на группу написаний,
+-- выбирается самое частое (при равенстве — первое по алфавиту).
+WITH spellings AS (
+     SELECT lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g')) AS alias,
+            regexp_replace(btrim(service_name), '\s+', ' ', 'g') AS name,
+            count(*) AS uses
+     FROM {{.Table "subscriptions"}}
+     GROUP BY 1, 2
+), canonical AS (
+     SELECT DISTINCT ON (alias) alias, name
+     FROM spellings
+     ORDER BY alias, uses DESC, name
+)
+INSERT INTO {{.Table "services"}} (name)
+SELECT name FROM canonical
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO {{.Table "service_aliases"}} (alias, service_id)
+SELECT lower(name), id FROM {{.Table "services"}}
+ON CONFLICT (alias) DO NOTHING;
+
+-- Переименовываем подписки в каноническое имя. Если у пользователя есть
+-- несколько написаний одного сервиса, переименовывается только одна
+-- подписка, остальные остаются как есть, чтобы не нарушить
+-- unique_service_user — их владелец разберётся с дублями сам.
+WITH resolved AS (
+     SELECT sub.id, s.name,
+            row_number() OVER (PARTITION BY sub.user_id, s.id ORDER BY sub.service_name = s.name DESC, sub.id) AS n
+     FROM {{.Table "subscriptions"}} sub
+     JOIN {{.Table "service_aliases"}} a ON a.alias = lower(regexp_replace(btrim(sub.service_name), '\s+', ' ', 'g'))
+     JOIN {{.Table "services"}} s ON s.id = a.service_id
+)
+UPDATE {{.Table "subscriptions"}} sub
+SET service_name = resolved.name
+FROM resolved
+WHERE resolved.id = sub.id AND resolved.n = 1 AND sub.service_name <> resolved.name;
@@ -0,0 +1,6 @@
+-- Индекс по tags удаляется вместе с колонкой.
+ALTER TABLE {{.Table "subscriptions"}}
+     DROP COLUMN IF EXISTS tags,
+     DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS {{.Table "categories"}} CASCADE;
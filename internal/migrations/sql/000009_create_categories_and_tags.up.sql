@@ -0,0 +1,17 @@
+-- Иерархия категорий: корневые категории без parent_id, у вложенных —
+-- родитель. Имя уникально среди соседей без учёта регистра.
+CREATE TABLE IF NOT EXISTS {{.Table "categories"}} (
+     id SERIAL PRIMARY KEY,
+     name TEXT NOT NULL,
+     parent_id INTEGER REFERENCES {{.Table "categories"}} (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS {{.Ident "unique_category_sibling"}}
+     ON {{.Table "categories"}} (COALESCE(parent_id, 0), lower(name));
+
+ALTER TABLE {{.Table "subscriptions"}}
+     ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES {{.Table "categories"}} (id) ON DELETE SET NULL,
+     ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS {{.Ident "subscriptions_tags_idx"}}
+     ON {{.Table "subscriptions"}} USING GIN (tags);
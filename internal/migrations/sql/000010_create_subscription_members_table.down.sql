@@ -0,0 +1 @@
+DROP TABLE IF EXISTS {{.Table "subscription_members"}} CASCADE;
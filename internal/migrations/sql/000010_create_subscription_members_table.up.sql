@@ -0,0 +1,13 @@
+-- Участники совместной подписки и их доли. Подписка без участников
+-- целиком на плательщике (subscriptions.user_id); с участниками стоимость
+-- делится пропорционально weight, и плательщик участвует в разделе,
+-- только если он есть в этой таблице.
+CREATE TABLE IF NOT EXISTS {{.Table "subscription_members"}} (
+     subscription_id INTEGER NOT NULL REFERENCES {{.Table "subscriptions"}} (id) ON DELETE CASCADE,
+     user_id UUID NOT NULL,
+     weight INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0),
+     PRIMARY KEY (subscription_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS {{.Ident "subscription_members_user_idx"}}
+     ON {{.Table "subscription_members"}} (user_id);
@@ -0,0 +1,19 @@
+DROP POLICY IF EXISTS {{.Ident "price_changes_tenant"}} ON {{.Table "price_changes"}};
+ALTER TABLE {{.Table "price_changes"}} NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "price_changes"}} DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS {{.Ident "subscription_members_tenant"}} ON {{.Table "subscription_members"}};
+ALTER TABLE {{.Table "subscription_members"}} NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "subscription_members"}} DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS {{.Ident "subscriptions_tenant"}} ON {{.Table "subscriptions"}};
+ALTER TABLE {{.Table "subscriptions"}} NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "subscriptions"}} DISABLE ROW LEVEL SECURITY;
+
+DROP FUNCTION IF EXISTS {{.Table "tenant_visible"}}(INTEGER);
+
+ALTER TABLE {{.Table "api_keys"}} DROP COLUMN IF EXISTS organization_id;
+-- Индекс по organization_id удаляется вместе с колонкой.
+ALTER TABLE {{.Table "subscriptions"}} DROP COLUMN IF EXISTS organization_id;
+
+DROP TABLE IF EXISTS {{.Table "organizations"}};
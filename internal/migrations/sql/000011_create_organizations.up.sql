@@ -0,0 +1,63 @@
+-- Организации — арендаторы сервиса. Существующие подписки переходят в
+-- организацию по умолчанию (id = 1), которая обслуживает и анонимные
+-- запросы, пока auth.default_organization = 1.
+CREATE TABLE IF NOT EXISTS {{.Table "organizations"}} (
+     id SERIAL PRIMARY KEY,
+     slug TEXT NOT NULL,
+     name TEXT NOT NULL,
+     created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+     CONSTRAINT {{.Ident "unique_organization_slug"}} UNIQUE (slug)
+);
+
+INSERT INTO {{.Table "organizations"}} (id, slug, name) VALUES (1, 'default', 'Default')
+ON CONFLICT (id) DO NOTHING;
+SELECT setval(pg_get_serial_sequence('{{.Table "organizations"}}', 'id'),
+              (SELECT max(id) FROM {{.Table "organizations"}}));
+
+ALTER TABLE {{.Table "subscriptions"}}
+     ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1
+          REFERENCES {{.Table "organizations"}} (id);
+-- Значение по умолчанию нужно только для переноса существующих строк:
+-- дальше организацию всегда задаёт приложение.
+ALTER TABLE {{.Table "subscriptions"}} ALTER COLUMN organization_id DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS {{.Ident "subscriptions_organization_idx"}}
+     ON {{.Table "subscriptions"}} (organization_id);
+
+-- Ключ без организации — ключ платформы: видит все организации и
+-- управляет ими.
+ALTER TABLE {{.Table "api_keys"}}
+     ADD COLUMN IF NOT EXISTS organization_id INTEGER
+          REFERENCES {{.Table "organizations"}} (id) ON DELETE CASCADE;
+
+-- Арендатор транзакции задаётся через set_config('app.organization_id'):
+-- id организации или '*' для всех. Без настройки не видно ничего.
+CREATE OR REPLACE FUNCTION {{.Table "tenant_visible"}}(org INTEGER) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+     SELECT current_setting('app.organization_id', true) = '*'
+         OR org::text = current_setting('app.organization_id', true)
+$$;
+
+-- FORCE — чтобы политики действовали и на владельца таблиц, от имени
+-- которого работает сервис. Поэтому миграции, меняющие данные подписок,
+-- должны начинаться с SET LOCAL app.organization_id = '*'.
+ALTER TABLE {{.Table "subscriptions"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "subscriptions"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "subscriptions_tenant"}} ON {{.Table "subscriptions"}};
+CREATE POLICY {{.Ident "subscriptions_tenant"}} ON {{.Table "subscriptions"}}
+     USING ({{.Table "tenant_visible"}}(organization_id))
+     WITH CHECK ({{.Table "tenant_visible"}}(organization_id));
+
+-- Участники и смены цен видны вместе со своей подпиской: подзапрос к
+-- subscriptions сам проходит через её политику.
+ALTER TABLE {{.Table "subscription_members"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "subscription_members"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "subscription_members_tenant"}} ON {{.Table "subscription_members"}};
+CREATE POLICY {{.Ident "subscription_members_tenant"}} ON {{.Table "subscription_members"}}
+     USING (EXISTS (SELECT 1 FROM {{.Table "subscriptions"}} s WHERE s.id = subscription_id));
+
+ALTER TABLE {{.Table "price_changes"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "price_changes"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "price_changes_tenant"}} ON {{.Table "price_changes"}};
+CREATE POLICY {{.Ident "price_changes_tenant"}} ON {{.Table "price_changes"}}
+     USING (EXISTS (SELECT 1 FROM {{.Table "subscriptions"}} s WHERE s.id = subscription_id));
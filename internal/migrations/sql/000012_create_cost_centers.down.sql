@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS {{.Table "subscription_cost_centers"}};
+DROP TABLE IF EXISTS {{.Table "cost_centers"}};
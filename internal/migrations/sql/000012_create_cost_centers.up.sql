@@ -0,0 +1,34 @@
+-- Центры затрат организации и распределение подписок между ними в
+-- процентах. Сумма долей подписки — 100, это проверяет приложение.
+CREATE TABLE IF NOT EXISTS {{.Table "cost_centers"}} (
+     id SERIAL PRIMARY KEY,
+     organization_id INTEGER NOT NULL REFERENCES {{.Table "organizations"}} (id) ON DELETE CASCADE,
+     code TEXT NOT NULL,
+     name TEXT NOT NULL,
+     CONSTRAINT {{.Ident "unique_cost_center_code"}} UNIQUE (organization_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS {{.Table "subscription_cost_centers"}} (
+     subscription_id INTEGER NOT NULL REFERENCES {{.Table "subscriptions"}} (id) ON DELETE CASCADE,
+     cost_center_id INTEGER NOT NULL REFERENCES {{.Table "cost_centers"}} (id) ON DELETE CASCADE,
+     percent INTEGER NOT NULL CHECK (percent > 0 AND percent <= 100),
+     PRIMARY KEY (subscription_id, cost_center_id)
+);
+
+CREATE INDEX IF NOT EXISTS {{.Ident "subscription_cost_centers_center_idx"}}
+     ON {{.Table "subscription_cost_centers"}} (cost_center_id);
+
+ALTER TABLE {{.Table "cost_centers"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "cost_centers"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "cost_centers_tenant"}} ON {{.Table "cost_centers"}};
+CREATE POLICY {{.Ident "cost_centers_tenant"}} ON {{.Table "cost_centers"}}
+     USING ({{.Table "tenant_visible"}}(organization_id))
+     WITH CHECK ({{.Table "tenant_visible"}}(organization_id));
+
+-- Распределение видно, только если видны и подписка, и центр затрат.
+ALTER TABLE {{.Table "subscription_cost_centers"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "subscription_cost_centers"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "subscription_cost_centers_tenant"}} ON {{.Table "subscription_cost_centers"}};
+CREATE POLICY {{.Ident "subscription_cost_centers_tenant"}} ON {{.Table "subscription_cost_centers"}}
+     USING (EXISTS (SELECT 1 FROM {{.Table "subscriptions"}} s WHERE s.id = subscription_id)
+        AND EXISTS (SELECT 1 FROM {{.Table "cost_centers"}} c WHERE c.id = cost_center_id));
@@ -0,0 +1,24 @@
+DROP POLICY IF EXISTS {{.Ident "reminders_tenant"}} ON {{.Table "reminders"}};
+ALTER TABLE {{.Table "reminders"}} NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "reminders"}} DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS {{.Ident "user_contacts_tenant"}} ON {{.Table "user_contacts"}};
+ALTER TABLE {{.Table "user_contacts"}} NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "user_contacts"}} DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS {{.Ident "budgets_tenant"}} ON {{.Table "budgets"}};
+ALTER TABLE {{.Table "budgets"}} NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "budgets"}} DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE {{.Table "reminders"}} DROP COLUMN IF EXISTS organization_id;
+
+-- Откат не сработает, если у одного user_id уже есть контакты или
+-- бюджеты в нескольких организациях: их нужно свести вручную.
+ALTER TABLE {{.Table "user_contacts"}} DROP CONSTRAINT IF EXISTS {{.Ident "user_contacts_pkey"}};
+ALTER TABLE {{.Table "user_contacts"}} DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE {{.Table "user_contacts"}} ADD CONSTRAINT {{.Ident "user_contacts_pkey"}} PRIMARY KEY (user_id);
+
+ALTER TABLE {{.Table "budgets"}} DROP CONSTRAINT IF EXISTS {{.Ident "unique_budget_user_category"}};
+ALTER TABLE {{.Table "budgets"}} DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE {{.Table "budgets"}}
+     ADD CONSTRAINT {{.Ident "unique_budget_user_category"}} UNIQUE (user_id, category);
@@ -0,0 +1,50 @@
+-- Бюджеты, контакты и журнал напоминаний принадлежат организации так же,
+-- как подписки: один и тот же user_id в разных организациях — разные
+-- пользователи. Существующие бюджеты и контакты переходят в организацию
+-- по умолчанию, напоминания — в организацию своей подписки.
+SET LOCAL app.organization_id = '*';
+
+ALTER TABLE {{.Table "budgets"}}
+     ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1
+          REFERENCES {{.Table "organizations"}} (id) ON DELETE CASCADE;
+ALTER TABLE {{.Table "budgets"}} ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE {{.Table "budgets"}} DROP CONSTRAINT IF EXISTS {{.Ident "unique_budget_user_category"}};
+ALTER TABLE {{.Table "budgets"}}
+     ADD CONSTRAINT {{.Ident "unique_budget_user_category"}} UNIQUE (organization_id, user_id, category);
+
+ALTER TABLE {{.Table "user_contacts"}}
+     ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1
+          REFERENCES {{.Table "organizations"}} (id) ON DELETE CASCADE;
+ALTER TABLE {{.Table "user_contacts"}} ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE {{.Table "user_contacts"}} DROP CONSTRAINT IF EXISTS {{.Ident "user_contacts_pkey"}};
+ALTER TABLE {{.Table "user_contacts"}}
+     ADD CONSTRAINT {{.Ident "user_contacts_pkey"}} PRIMARY KEY (organization_id, user_id);
+
+ALTER TABLE {{.Table "reminders"}}
+     ADD COLUMN IF NOT EXISTS organization_id INTEGER
+          REFERENCES {{.Table "organizations"}} (id) ON DELETE CASCADE;
+UPDATE {{.Table "reminders"}} r SET organization_id = s.organization_id
+FROM {{.Table "subscriptions"}} s
+WHERE s.id = r.subscription_id AND r.organization_id IS NULL;
+ALTER TABLE {{.Table "reminders"}} ALTER COLUMN organization_id SET NOT NULL;
+
+ALTER TABLE {{.Table "budgets"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "budgets"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "budgets_tenant"}} ON {{.Table "budgets"}};
+CREATE POLICY {{.Ident "budgets_tenant"}} ON {{.Table "budgets"}}
+     USING ({{.Table "tenant_visible"}}(organization_id))
+     WITH CHECK ({{.Table "tenant_visible"}}(organization_id));
+
+ALTER TABLE {{.Table "user_contacts"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "user_contacts"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "user_contacts_tenant"}} ON {{.Table "user_contacts"}};
+CREATE POLICY {{.Ident "user_contacts_tenant"}} ON {{.Table "user_contacts"}}
+     USING ({{.Table "tenant_visible"}}(organization_id))
+     WITH CHECK ({{.Table "tenant_visible"}}(organization_id));
+
+ALTER TABLE {{.Table "reminders"}} ENABLE ROW LEVEL SECURITY;
+ALTER TABLE {{.Table "reminders"}} FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS {{.Ident "reminders_tenant"}} ON {{.Table "reminders"}};
+CREATE POLICY {{.Ident "reminders_tenant"}} ON {{.Table "reminders"}}
+     USING ({{.Table "tenant_visible"}}(organization_id))
+     WITH CHECK ({{.Table "tenant_visible"}}(organization_id));
@@ -0,0 +1,5 @@
+-- Откат не пройдёт, если одна пара (сервис, пользователь) уже заведена в
+-- нескольких организациях: лишние записи нужно удалить вручную.
+ALTER TABLE {{.Table "subscriptions"}} DROP CONSTRAINT IF EXISTS {{.Ident "unique_service_user"}};
+ALTER TABLE {{.Table "subscriptions"}}
+     ADD CONSTRAINT {{.Ident "unique_service_user"}} UNIQUE (service_name, user_id);
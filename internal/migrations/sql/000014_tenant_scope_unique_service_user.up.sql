@@ -0,0 +1,6 @@
+-- Подписка уникальна по (сервис, пользователь) внутри организации: один и
+-- тот же user_id в разных организациях — разные пользователи, и конфликт
+-- не должен выдавать, что такая запись есть в чужой организации.
+ALTER TABLE {{.Table "subscriptions"}} DROP CONSTRAINT IF EXISTS {{.Ident "unique_service_user"}};
+ALTER TABLE {{.Table "subscriptions"}}
+     ADD CONSTRAINT {{.Ident "unique_service_user"}} UNIQUE (organization_id, service_name, user_id);
@@ -0,0 +1,73 @@
+// Package migrations применяет SQL-миграции сервиса. Файлы миграций —
+// шаблоны text/template: имена таблиц и ограничений в них записываются
+// как {{.Table "subscriptions"}} и {{.Ident "unique_service_user"}}, чтобы
+// схему и префикс можно было задать конфигурацией.
+package migrations
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"subscription-service/internal/config"
+	"subscription-service/internal/models"
+	"text/template"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Tables строит раскладку таблиц из секции db конфигурации.
+func Tables(cfg config.DBConfig) models.Tables {
+	schema, table := cfg.MigrationsTableName()
+	return models.Tables{
+		Schema:     cfg.Schema,
+		Prefix:     cfg.TablePrefix,
+		Migrations: schema + "." + table,
+	}
+}
+
+// Templated оборачивает источник миграций так, что каждый файл
+// перед применением рендерится с раскладкой tables.
+func Templated(src source.Driver, tables models.Tables) source.Driver {
+	return &templated{Driver: src, tables: tables}
+}
+
+type templated struct {
+	source.Driver
+	tables models.Tables
+}
+
+func (t *templated) ReadUp(version uint) (io.ReadCloser, string, error) {
+	r, identifier, err := t.Driver.ReadUp(version)
+	if err != nil {
+		return nil, identifier, err
+	}
+	return t.render(r, identifier)
+}
+
+func (t *templated) ReadDown(version uint) (io.ReadCloser, string, error) {
+	r, identifier, err := t.Driver.ReadDown(version)
+	if err != nil {
+		return nil, identifier, err
+	}
+	return t.render(r, identifier)
+}
+
+func (t *templated) render(r io.ReadCloser, identifier string) (io.ReadCloser, string, error) {
+	defer r.Close()
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, identifier, err
+	}
+
+	tmpl, err := template.New(identifier).Option("missingkey=error").Parse(string(body))
+	if err != nil {
+		return nil, identifier, fmt.Errorf("parse migration %s: %w", identifier, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, t.tables); err != nil {
+		return nil, identifier, fmt.Errorf("render migration %s: %w", identifier, err)
+	}
+	return io.NopCloser(&out), identifier, nil
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type AnalyticsMonth struct {
+	Month     time.Time `json:"month"`
+	MRR       int       `json:"mrr"`
+	ARR       int       `json:"arr"`
+	Active    int       `json:"active"`
+	New       int       `json:"new"`
+	Churned   int       `json:"churned"`
+	ChurnRate float64   `json:"churn_rate"`
+	NetGrowth int       `json:"net_growth"`
+}
+
+type ServiceAnalytics struct {
+	ServiceName string            `json:"service_name"`
+	Months      []*AnalyticsMonth `json:"months"`
+}
+
+// Analytics считает помесячные метрики за период [from, to]. Подписка
+// активна в месяце по тем же правилам пересечения, что и в GetSummary;
+// новой считается в месяце start_date, ушедшей — в месяце end_date.
+// Churn rate — доля ушедших среди активных в предыдущем месяце. Если
+// задан uid, MRR и ARR считаются по доле этого пользователя в совместных
+// подписках.
+func Analytics(subs []*Subscription, from, to time.Time, uid *uuid.UUID) []*AnalyticsMonth {
+	result := []*AnalyticsMonth{}
+
+	for month := monthStart(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		am := AnalyticsMonth{Month: month}
+		prev := month.AddDate(0, -1, 0)
+		activePrev := 0
+
+		for _, sub := range subs {
+			if _, _, cost, ok := overlapCost(sub, month, month); ok {
+				if uid != nil {
+					cost = sub.ShareOf(cost, *uid)
+				}
+				am.MRR += cost
+				am.Active++
+			}
+			if _, _, _, ok := overlapCost(sub, prev, prev); ok {
+				activePrev++
+			}
+			if sameMonth(sub.StartDate, month) {
+				am.New++
+			}
+			if sub.EndDate != nil && sameMonth(*sub.EndDate, month) {
+				am.Churned++
+			}
+		}
+
+		am.ARR = am.MRR * 12
+		am.NetGrowth = am.New - am.Churned
+		if activePrev > 0 {
+			am.ChurnRate = float64(am.Churned) / float64(activePrev)
+		}
+
+		result = append(result, &am)
+	}
+	return result
+}
+
+// AnalyticsByService группирует подписки по сервису и считает Analytics
+// для каждой группы.
+func AnalyticsByService(subs []*Subscription, from, to time.Time, uid *uuid.UUID) []*ServiceAnalytics {
+	groups := make(map[string][]*Subscription)
+	for _, sub := range subs {
+		groups[sub.ServiceName] = append(groups[sub.ServiceName], sub)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]*ServiceAnalytics, 0, len(names))
+	for _, name := range names {
+		result = append(result, &ServiceAnalytics{
+			ServiceName: name,
+			Months:      Analytics(groups[name], from, to, uid),
+		})
+	}
+	return result
+}
+
+func sameMonth(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
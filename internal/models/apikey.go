@@ -0,0 +1,293 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+// Области доступа ключей. Каждая следующая включает предыдущие:
+// admin ⊇ write ⊇ read.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+var scopeRank = map[string]int{ScopeRead: 1, ScopeWrite: 2, ScopeAdmin: 3}
+
+// ErrInvalidAPIKey — ключ не найден, отозван, просрочен или не совпал.
+// Причину наружу не сообщаем.
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+const apiKeyPrefix = "sk_"
+
+// APIKey — ключ сервисного клиента. В базе хранится только SHA-256 от
+// ключа; сам ключ показывается один раз при создании. Ключ без
+// OrganizationID — ключ платформы, он видит все организации.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// OrganizationID — организация, от имени которой действует ключ.
+	OrganizationID *int   `json:"organization_id,omitempty"`
+	Hash           string `json:"-"`
+}
+
+// APIKeyRepository — хранилище API-ключей. GetAll и Revoke видят ключи
+// организации арендатора из ctx, при доступе ко всем организациям — все;
+// Authenticate ищет среди всех ключей.
+type APIKeyRepository interface {
+	GetAll(ctx context.Context) ([]*APIKey, error)
+	Authenticate(ctx context.Context, plaintext string, now time.Time) (*APIKey, error)
+	Insert(ctx context.Context, k *APIKey) error
+	Revoke(ctx context.Context, id int, at time.Time) error
+}
+
+// HasScope сообщает, покрывают ли области ключа требуемую.
+func (k *APIKey) HasScope(scope string) bool {
+	return HasScope(k.Scopes, scope)
+}
+
+// HasScope сообщает, покрывает ли набор scopes требуемую область с учётом
+// иерархии admin ⊇ write ⊇ read.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if scopeRank[s] >= scopeRank[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidScopes проверяет, что все области известны и их хотя бы одна.
+func ValidScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, s := range scopes {
+		if _, ok := scopeRank[s]; !ok {
+			return fmt.Errorf("unknown scope %q, expected read, write or admin", s)
+		}
+	}
+	return nil
+}
+
+// NewAPIKey генерирует ключ вида sk_<prefix>_<secret>. Возвращает ключ
+// целиком для выдачи клиенту и заполняет Prefix и Hash для хранения.
+func NewAPIKey(name string, scopes []string, expiresAt *time.Time) (string, *APIKey, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	prefix := make([]byte, 5)
+	secret := make([]byte, 20)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+
+	k := &APIKey{
+		Name:      name,
+		Prefix:    strings.ToLower(enc.EncodeToString(prefix)),
+		Scopes:    slices.Clone(scopes),
+		ExpiresAt: expiresAt,
+	}
+	plaintext := apiKeyPrefix + k.Prefix + "_" + strings.ToLower(enc.EncodeToString(secret))
+	k.Hash = hashAPIKey(plaintext)
+	return plaintext, k, nil
+}
+
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookupPrefix возвращает часть ключа, по которой он ищется в хранилище.
+func lookupPrefix(plaintext string) (string, bool) {
+	rest, ok := strings.CutPrefix(plaintext, apiKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	return prefix, ok
+}
+
+// verify проверяет, что plaintext — этот ключ и что на момент now он не
+// отозван и не просрочен.
+func (k *APIKey) verify(plaintext string, now time.Time) error {
+	if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hashAPIKey(plaintext))) != 1 {
+		return ErrInvalidAPIKey
+	}
+	if k.RevokedAt != nil || (k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)) {
+		return ErrInvalidAPIKey
+	}
+	return nil
+}
+
+// touchDue сообщает, пора ли записать время использования: не чаще раза
+// в минуту.
+func (k *APIKey) touchDue(now time.Time) bool {
+	return k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= time.Minute
+}
+
+type APIKeyDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+// GetAll возвращает ключи организации арендатора из ctx; при доступе ко
+// всем организациям — все ключи, включая платформенные.
+func (m *APIKeyDB) GetAll(ctx context.Context) ([]*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM {api_keys} WHERE ($1::integer IS NULL OR organization_id = $1) ORDER BY id`
+
+	org, err := apiKeyOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, m.Tables.sql(query), org)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Authenticate находит ключ по предъявленной строке и проверяет его. При
+// успехе отмечает время использования не чаще раза в минуту, чтобы не
+// писать в базу на каждый запрос.
+func (m *APIKeyDB) Authenticate(ctx context.Context, plaintext string, now time.Time) (*APIKey, error) {
+	prefix, ok := lookupPrefix(plaintext)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	query := `SELECT ` + apiKeyColumns + ` FROM {api_keys} WHERE prefix = $1`
+
+	k, err := scanAPIKey(m.DB.QueryRowContext(ctx, m.Tables.sql(query), prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := k.verify(plaintext, now); err != nil {
+		return nil, err
+	}
+
+	if k.touchDue(now) {
+		touch := `UPDATE {api_keys} SET last_used_at = $2 WHERE id = $1`
+		if _, err := m.DB.ExecContext(ctx, m.Tables.sql(touch), k.ID, now); err != nil {
+			return nil, err
+		}
+		k.LastUsedAt = &now
+	}
+	return k, nil
+}
+
+//********************************************************************//
+//  							 INSERT								  //
+//********************************************************************//
+
+func (m *APIKeyDB) Insert(ctx context.Context, k *APIKey) error {
+	query := `INSERT INTO {api_keys} (name, prefix, key_hash, scopes, expires_at, organization_id)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id, created_at`
+
+	var scopes pgtype.TextArray
+	if err := scopes.Set(k.Scopes); err != nil {
+		return err
+	}
+	return m.DB.QueryRowContext(ctx, m.Tables.sql(query), k.Name, k.Prefix, k.Hash, scopes, k.ExpiresAt, k.OrganizationID).Scan(&k.ID, &k.CreatedAt)
+}
+
+//********************************************************************//
+//  							 REVOKE								  //
+//********************************************************************//
+
+// Revoke отзывает ключ. Запись остаётся для аудита; повторный отзыв и
+// ключ чужой организации возвращают sql.ErrNoRows.
+func (m *APIKeyDB) Revoke(ctx context.Context, id int, at time.Time) error {
+	query := `UPDATE {api_keys} SET revoked_at = $2
+              WHERE id = $1 AND revoked_at IS NULL AND ($3::integer IS NULL OR organization_id = $3)`
+
+	org, err := apiKeyOrganization(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := m.DB.ExecContext(ctx, m.Tables.sql(query), id, at, org)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// apiKeyOrganization возвращает организацию, ключами которой можно
+// управлять из ctx; nil — всеми.
+func apiKeyOrganization(ctx context.Context) (*int, error) {
+	id, all, err := OrganizationFrom(ctx)
+	if err != nil || all {
+		return nil, err
+	}
+	return &id, nil
+}
+
+const apiKeyColumns = `id, name, prefix, scopes, expires_at, last_used_at, created_at, revoked_at, organization_id, key_hash`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var k APIKey
+	var scopes pgtype.TextArray
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.ExpiresAt, &k.LastUsedAt, &k.CreatedAt, &k.RevokedAt, &k.OrganizationID, &k.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := scopes.AssignTo(&k.Scopes); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
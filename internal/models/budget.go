@@ -0,0 +1,184 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type BudgetDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+// Budget — месячный лимит трат пользователя. Пустая категория означает
+// общий лимит по всем подпискам.
+type Budget struct {
+	ID             int       `json:"id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Category       string    `json:"category"`
+	MonthlyLimit   int       `json:"monthly_limit"`
+	OrganizationID int       `json:"organization_id"`
+}
+
+type BudgetMonth struct {
+	Month     time.Time `json:"month"`
+	Category  string    `json:"category"`
+	Limit     int       `json:"limit"`
+	Spent     int       `json:"spent"`
+	Remaining int       `json:"remaining"`
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+// GetByUserID возвращает бюджеты пользователя в организации арендатора
+// из ctx.
+func (m *BudgetDB) GetByUserID(ctx context.Context, uid uuid.UUID) ([]*Budget, error) {
+	query := `SELECT b.id, b.user_id, b.category, b.monthly_limit, b.organization_id FROM {budgets} b
+              WHERE b.user_id = $1 AND {tenant_visible}(b.organization_id)
+              ORDER BY b.organization_id, b.category`
+
+	budgets := []*Budget{}
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, m.Tables.sql(query), uid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var b Budget
+			if err := rows.Scan(&b.ID, &b.UserID, &b.Category, &b.MonthlyLimit, &b.OrganizationID); err != nil {
+				return err
+			}
+			budgets = append(budgets, &b)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+//********************************************************************//
+//  							 UPSERT								  //
+//********************************************************************//
+
+// Upsert сохраняет бюджет в организации арендатора из ctx. При доступе ко
+// всем организациям она должна быть указана в b.OrganizationID.
+func (m *BudgetDB) Upsert(ctx context.Context, b *Budget) error {
+	query := `INSERT INTO {budgets} (organization_id, user_id, category, monthly_limit) VALUES ($1, $2, $3, $4)
+              ON CONFLICT (organization_id, user_id, category)
+              DO UPDATE SET monthly_limit = EXCLUDED.monthly_limit
+              RETURNING id`
+
+	org, err := insertOrganization(ctx, b.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, m.Tables.sql(query), org, b.UserID, b.Category, b.MonthlyLimit).Scan(&b.ID); err != nil {
+			return err
+		}
+		b.OrganizationID = org
+		return nil
+	})
+}
+
+//********************************************************************//
+//  							 DELETE								  //
+//********************************************************************//
+
+func (m *BudgetDB) Delete(ctx context.Context, uid uuid.UUID, category string) error {
+	query := `DELETE FROM {budgets} b WHERE b.user_id = $1 AND b.category = $2 AND {tenant_visible}(b.organization_id)`
+
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, m.Tables.sql(query), uid, category)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+//********************************************************************//
+//  							 EVALUATE							  //
+//********************************************************************//
+
+// EvaluateBudgets считает траты по каждому бюджету за каждый месяц
+// периода [from, to] по тем же правилам пересечения, что и GetSummary.
+// Совместные подписки учитываются долей владельца бюджета, поэтому subs
+// должны включать и подписки, где он только участник. Категории подписок
+// берутся из каталога сервисов.
+func EvaluateBudgets(budgets []*Budget, subs []*Subscription, categories ServiceCategories, from, to time.Time) []*BudgetMonth {
+	result := []*BudgetMonth{}
+
+	for month := monthStart(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		for _, b := range budgets {
+			spent := 0
+			for _, sub := range subs {
+				if !b.Covers(sub, categories) {
+					continue
+				}
+				if _, _, cost, ok := overlapCost(sub, month, month); ok {
+					spent += sub.ShareOf(cost, b.UserID)
+				}
+			}
+
+			result = append(result, &BudgetMonth{
+				Month:     month,
+				Category:  b.Category,
+				Limit:     b.MonthlyLimit,
+				Spent:     spent,
+				Remaining: b.MonthlyLimit - spent,
+			})
+		}
+	}
+	return result
+}
+
+// NewlyExceeded возвращает месяцы after, в которых лимит превышен, хотя в
+// before траты в него укладывались. before и after — оценки
+// EvaluateBudgets одних бюджетов за один период, до и после изменения.
+func NewlyExceeded(before, after []*BudgetMonth) []*BudgetMonth {
+	var exceeded []*BudgetMonth
+	for i, month := range after {
+		if month.Remaining < 0 && (i >= len(before) || before[i].Remaining >= 0) {
+			exceeded = append(exceeded, month)
+		}
+	}
+	return exceeded
+}
+
+// Covers сообщает, учитывается ли подписка в бюджете. Категория подписки
+// — категория её сервиса в каталоге; сервис вне каталога считается
+// отдельной категорией со своим именем. Подписки другой организации
+// бюджет не покрывает.
+func (b *Budget) Covers(sub *Subscription, categories ServiceCategories) bool {
+	if b.OrganizationID != 0 && sub.OrganizationID != 0 && b.OrganizationID != sub.OrganizationID {
+		return false
+	}
+	if b.Category == "" {
+		return true
+	}
+	return strings.EqualFold(categories.Of(sub.ServiceName), strings.TrimSpace(b.Category))
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrDuplicateCategory — у родителя уже есть категория с таким именем.
+var ErrDuplicateCategory = errors.New("category with this name already exists under the parent")
+
+// CategoryPathSeparator разделяет уровни в пути категории: "media/music".
+const CategoryPathSeparator = "/"
+
+// Category — узел иерархии категорий подписок. Path — имена от корня до
+// самой категории через CategoryPathSeparator.
+type Category struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id,omitempty"`
+	Path     string `json:"path"`
+}
+
+type CategoryDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+// GetAll возвращает все категории с путями, упорядоченные по пути.
+func (m *CategoryDB) GetAll(ctx context.Context) ([]*Category, error) {
+	query := `WITH RECURSIVE tree AS (
+                  SELECT id, name, parent_id, name AS path FROM {categories} WHERE parent_id IS NULL
+                  UNION ALL
+                  SELECT c.id, c.name, c.parent_id, tree.path || '` + CategoryPathSeparator + `' || c.name
+                  FROM {categories} c JOIN tree ON c.parent_id = tree.id
+              )
+              SELECT id, name, parent_id, path FROM tree ORDER BY path`
+
+	rows, err := m.DB.QueryContext(ctx, m.Tables.sql(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.Path); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+//********************************************************************//
+//  							 INSERT								  //
+//********************************************************************//
+
+// Insert добавляет категорию. Несуществующий родитель — ErrUnknownCategory.
+func (m *CategoryDB) Insert(ctx context.Context, c *Category) error {
+	query := `INSERT INTO {categories} (name, parent_id) VALUES ($1, $2) RETURNING id`
+
+	err := constraintViolation(m.DB.QueryRowContext(ctx, m.Tables.sql(query), c.Name, c.ParentID).Scan(&c.ID), m.Tables)
+	if errors.Is(err, ErrDuplicateSubscription) {
+		return ErrDuplicateCategory
+	}
+	return err
+}
+
+//********************************************************************//
+//  							 DELETE								  //
+//********************************************************************//
+
+// Delete удаляет категорию вместе с вложенными. Подписки из удалённых
+// категорий остаются без категории.
+func (m *CategoryDB) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM {categories} WHERE id = $1`
+
+	res, err := m.DB.ExecContext(ctx, m.Tables.sql(query), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+//********************************************************************//
+//  							 GROUPING							  //
+//********************************************************************//
+
+// Группировки сводки по подпискам.
+const (
+	GroupByCategory = "category"
+	GroupByTag      = "tag"
+)
+
+// CostGroup — траты группы подписок за период сводки.
+type CostGroup struct {
+	Key           string `json:"key"`
+	Cost          int    `json:"cost"`
+	Subscriptions int    `json:"subscriptions"`
+}
+
+// GroupCosts раскладывает стоимость подписок сводки по группам.
+//
+// По категории ключ — путь категории, и стоимость учитывается в ней и во
+// всех её предках, так что "media" включает "media/music". По тегу
+// подписка попадает в группу каждого своего тега. Подписки без категории
+// или без тегов собираются в группу с пустым ключом.
+func GroupCosts(subs []*SubscriptionWithCost, by string, categories []*Category) []*CostGroup {
+	paths := make(map[int]string, len(categories))
+	for _, c := range categories {
+		paths[c.ID] = c.Path
+	}
+
+	groups := map[string]*CostGroup{}
+	add := func(key string, cost int) {
+		g, ok := groups[key]
+		if !ok {
+			g = &CostGroup{Key: key}
+			groups[key] = g
+		}
+		g.Cost += cost
+		g.Subscriptions++
+	}
+
+	for _, sub := range subs {
+		var keys []string
+		switch by {
+		case GroupByCategory:
+			if sub.CategoryID != nil {
+				keys = categoryAncestry(paths[*sub.CategoryID])
+			}
+		case GroupByTag:
+			keys = sub.Tags
+		}
+		if len(keys) == 0 {
+			keys = []string{""}
+		}
+		for _, key := range keys {
+			add(key, sub.Cost)
+		}
+	}
+
+	result := make([]*CostGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, g)
+	}
+	slices.SortFunc(result, func(a, b *CostGroup) int {
+		if c := cmp.Compare(b.Cost, a.Cost); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return result
+}
+
+// categoryAncestry возвращает путь и пути всех предков: для "a/b/c" —
+// "a", "a/b" и "a/b/c".
+func categoryAncestry(path string) []string {
+	if path == "" {
+		return nil
+	}
+	parts := strings.Split(path, CategoryPathSeparator)
+	ancestry := make([]string, len(parts))
+	for i := range parts {
+		ancestry[i] = strings.Join(parts[:i+1], CategoryPathSeparator)
+	}
+	return ancestry
+}
@@ -0,0 +1,382 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// ErrDuplicateCostCenter — в организации уже есть центр затрат с таким кодом.
+var ErrDuplicateCostCenter = errors.New("cost center with this code already exists")
+
+// ErrUnknownCostCenter — центр затрат не найден в организации подписки.
+var ErrUnknownCostCenter = errors.New("cost center does not exist")
+
+// CostCenter — центр затрат организации, на который относятся расходы
+// на подписки.
+type CostCenter struct {
+	ID             int    `json:"id"`
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	OrganizationID int    `json:"organization_id"`
+}
+
+// CostAllocation — доля подписки, относимая на центр затрат, в процентах.
+type CostAllocation struct {
+	CostCenterID int `json:"cost_center_id"`
+	Percent      int `json:"percent"`
+}
+
+// NormalizeAllocations проверяет распределение подписки: у единственного
+// центра процент по умолчанию 100, каждый процент от 1 до 100, центры не
+// повторяются, а сумма равна 100. Пустое распределение допустимо —
+// подписка не распределена. Список сортируется по cost_center_id.
+func NormalizeAllocations(allocations []CostAllocation) ([]CostAllocation, error) {
+	if len(allocations) == 1 && allocations[0].Percent == 0 {
+		allocations = []CostAllocation{{CostCenterID: allocations[0].CostCenterID, Percent: 100}}
+	}
+
+	normalized := make([]CostAllocation, 0, len(allocations))
+	total := 0
+	for _, a := range allocations {
+		if a.Percent < 1 || a.Percent > 100 {
+			return nil, fmt.Errorf("cost center %d: percent must be between 1 and 100", a.CostCenterID)
+		}
+		if slices.ContainsFunc(normalized, func(o CostAllocation) bool { return o.CostCenterID == a.CostCenterID }) {
+			return nil, fmt.Errorf("cost center %d is listed twice", a.CostCenterID)
+		}
+		total += a.Percent
+		normalized = append(normalized, a)
+	}
+	if len(normalized) > 0 && total != 100 {
+		return nil, fmt.Errorf("percentages must add up to 100, got %d", total)
+	}
+
+	slices.SortFunc(normalized, func(a, b CostAllocation) int { return cmp.Compare(a.CostCenterID, b.CostCenterID) })
+	return normalized, nil
+}
+
+type CostCenterDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+// GetAll возвращает центры затрат арендатора из ctx, упорядоченные по коду.
+func (m *CostCenterDB) GetAll(ctx context.Context) ([]*CostCenter, error) {
+	query := `SELECT c.id, c.code, c.name, c.organization_id FROM {cost_centers} c
+              WHERE {tenant_visible}(c.organization_id) ORDER BY c.code, c.id`
+
+	centers := []*CostCenter{}
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, m.Tables.sql(query))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c CostCenter
+			if err := rows.Scan(&c.ID, &c.Code, &c.Name, &c.OrganizationID); err != nil {
+				return err
+			}
+			centers = append(centers, &c)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return centers, nil
+}
+
+// Allocations возвращает распределения всех подписок арендатора по
+// subscription_id. Нераспределённых подписок в ответе нет.
+func (m *CostCenterDB) Allocations(ctx context.Context) (map[int][]CostAllocation, error) {
+	query := `SELECT a.subscription_id, a.cost_center_id, a.percent
+              FROM {subscription_cost_centers} a
+              JOIN {subscriptions} s ON s.id = a.subscription_id
+              WHERE ` + tenantScope + `
+              ORDER BY a.subscription_id, a.cost_center_id`
+
+	return m.allocations(ctx, query)
+}
+
+// GetAllocations возвращает распределение подписки id; пустое, если она
+// не распределена или не видна арендатору.
+func (m *CostCenterDB) GetAllocations(ctx context.Context, id int) ([]CostAllocation, error) {
+	query := `SELECT a.subscription_id, a.cost_center_id, a.percent
+              FROM {subscription_cost_centers} a
+              JOIN {subscriptions} s ON s.id = a.subscription_id
+              WHERE a.subscription_id = $1 AND ` + tenantScope + `
+              ORDER BY a.cost_center_id`
+
+	bySub, err := m.allocations(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if bySub[id] == nil {
+		return []CostAllocation{}, nil
+	}
+	return bySub[id], nil
+}
+
+func (m *CostCenterDB) allocations(ctx context.Context, query string, args ...any) (map[int][]CostAllocation, error) {
+	bySub := map[int][]CostAllocation{}
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, m.Tables.sql(query), args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int
+			var a CostAllocation
+			if err := rows.Scan(&id, &a.CostCenterID, &a.Percent); err != nil {
+				return err
+			}
+			bySub[id] = append(bySub[id], a)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bySub, nil
+}
+
+//********************************************************************//
+//  							 INSERT								  //
+//********************************************************************//
+
+// Insert добавляет центр затрат в организацию арендатора из ctx. При
+// доступе ко всем организациям она должна быть указана в c.OrganizationID.
+func (m *CostCenterDB) Insert(ctx context.Context, c *CostCenter) error {
+	query := `INSERT INTO {cost_centers} (organization_id, code, name) VALUES ($1, $2, $3) RETURNING id`
+
+	org, err := insertOrganization(ctx, c.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		err := uniqueViolation(tx.QueryRowContext(ctx, m.Tables.sql(query), org, c.Code, c.Name).Scan(&c.ID))
+		if errors.Is(err, ErrDuplicateSubscription) {
+			return ErrDuplicateCostCenter
+		}
+		if err != nil {
+			return err
+		}
+		c.OrganizationID = org
+		return nil
+	})
+}
+
+// SetAllocations заменяет распределение подписки id. Центры затрат должны
+// принадлежать организации подписки, иначе — ErrUnknownCostCenter.
+// Пустой список снимает распределение.
+func (m *CostCenterDB) SetAllocations(ctx context.Context, id int, allocations []CostAllocation) error {
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		var locked int
+		query := `SELECT s.id FROM {subscriptions} s WHERE s.id = $1 AND ` + tenantScope + ` FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, m.Tables.sql(query), id).Scan(&locked); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM {subscription_cost_centers} WHERE subscription_id = $1`
+		if _, err := tx.ExecContext(ctx, m.Tables.sql(query), id); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO {subscription_cost_centers} (subscription_id, cost_center_id, percent)
+                 SELECT s.id, c.id, $3::integer
+                 FROM {subscriptions} s
+                 JOIN {cost_centers} c ON c.organization_id = s.organization_id
+                 WHERE s.id = $1 AND c.id = $2`
+		for _, a := range allocations {
+			res, err := tx.ExecContext(ctx, m.Tables.sql(query), id, a.CostCenterID, a.Percent)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("%w: %d", ErrUnknownCostCenter, a.CostCenterID)
+			}
+		}
+		return nil
+	})
+}
+
+//********************************************************************//
+//  							 DELETE								  //
+//********************************************************************//
+
+// Delete удаляет центр затрат. Подписки, у которых была доля в нём,
+// теряют распределение целиком и становятся нераспределёнными: остаток
+// долей уже не складывался бы в 100.
+func (m *CostCenterDB) Delete(ctx context.Context, id int) error {
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		query := `DELETE FROM {subscription_cost_centers} a
+                  WHERE a.subscription_id IN (
+                      SELECT o.subscription_id FROM {subscription_cost_centers} o WHERE o.cost_center_id = $1)`
+		if _, err := tx.ExecContext(ctx, m.Tables.sql(query), id); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM {cost_centers} c WHERE c.id = $1 AND {tenant_visible}(c.organization_id)`
+		res, err := tx.ExecContext(ctx, m.Tables.sql(query), id)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+//********************************************************************//
+//  							 CHARGEBACK							  //
+//********************************************************************//
+
+// ChargebackLine — часть стоимости подписки за период, отнесённая на
+// центр затрат.
+type ChargebackLine struct {
+	SubscriptionID int       `json:"subscription_id"`
+	ServiceName    string    `json:"service_name"`
+	UserID         uuid.UUID `json:"user_id"`
+	DateFrom       time.Time `json:"date_from"`
+	DateTo         time.Time `json:"date_to"`
+	Percent        int       `json:"percent"`
+	Cost           int       `json:"cost"`
+	// FullCost — стоимость подписки за период целиком.
+	FullCost int `json:"full_cost"`
+}
+
+// ChargebackCenter — расходы центра затрат за период. Подписки без
+// распределения собираются в центр с пустым CostCenterID.
+type ChargebackCenter struct {
+	CostCenterID *int             `json:"cost_center_id"`
+	Code         string           `json:"code"`
+	Name         string           `json:"name"`
+	Total        int              `json:"total"`
+	Lines        []ChargebackLine `json:"lines"`
+}
+
+// Chargeback раскладывает стоимость подписок сводки по центрам затрат
+// согласно allocations. Копейки, оставшиеся от округления вниз,
+// отдаются центрам по одной в порядке убывания процента, так что сумма
+// по центрам равна стоимости подписки. Доля центра, которого нет в
+// centers, и непокрытый остаток, если проценты не складываются в 100,
+// уходят в нераспределённые. Центры упорядочены по коду,
+// нераспределённые — последними; общий итог равен итогу сводки.
+func Chargeback(subs []*SubscriptionWithCost, centers []*CostCenter, allocations map[int][]CostAllocation) ([]*ChargebackCenter, int) {
+	byID := make(map[int]*ChargebackCenter, len(centers))
+	for _, c := range centers {
+		byID[c.ID] = &ChargebackCenter{CostCenterID: &c.ID, Code: c.Code, Name: c.Name, Lines: []ChargebackLine{}}
+	}
+	unallocated := &ChargebackCenter{Lines: []ChargebackLine{}}
+
+	total := 0
+	for _, sub := range subs {
+		total += sub.Cost
+		line := ChargebackLine{
+			SubscriptionID: sub.ID,
+			ServiceName:    sub.ServiceName,
+			UserID:         sub.UserID,
+			DateFrom:       sub.DateFrom,
+			DateTo:         sub.DateTo,
+			FullCost:       sub.Cost,
+		}
+
+		allocs := allocations[sub.ID]
+		if len(allocs) == 0 {
+			line.Percent, line.Cost = 100, sub.Cost
+			unallocated.Lines = append(unallocated.Lines, line)
+			unallocated.Total += line.Cost
+			continue
+		}
+		shares, rest := splitPercent(sub.Cost, allocs)
+		percent := 100
+		for i, cost := range shares {
+			center, ok := byID[allocs[i].CostCenterID]
+			if !ok {
+				center = unallocated
+			}
+			line.Percent, line.Cost = allocs[i].Percent, cost
+			center.Lines = append(center.Lines, line)
+			center.Total += cost
+			percent -= allocs[i].Percent
+		}
+		if percent > 0 {
+			line.Percent, line.Cost = percent, rest
+			unallocated.Lines = append(unallocated.Lines, line)
+			unallocated.Total += rest
+		}
+	}
+
+	result := make([]*ChargebackCenter, 0, len(byID)+1)
+	for _, c := range byID {
+		if len(c.Lines) > 0 {
+			result = append(result, c)
+		}
+	}
+	slices.SortFunc(result, func(a, b *ChargebackCenter) int {
+		if c := cmp.Compare(a.Code, b.Code); c != 0 {
+			return c
+		}
+		return cmp.Compare(*a.CostCenterID, *b.CostCenterID)
+	})
+	if len(unallocated.Lines) > 0 {
+		result = append(result, unallocated)
+	}
+	return result, total
+}
+
+// splitPercent делит cost по процентам allocs; i-я доля соответствует
+// allocs[i]. Доли вместе не превышают сумму процентов: копейки округления
+// раздаются по одной, самым крупным долям сначала, а то, что приходится
+// на непокрытые проценты, возвращается в rest.
+func splitPercent(cost int, allocs []CostAllocation) (shares []int, rest int) {
+	shares = make([]int, len(allocs))
+	percent := 0
+	for _, a := range allocs {
+		percent += a.Percent
+	}
+	allocated := cost * min(percent, 100) / 100
+
+	left := allocated
+	for i, a := range allocs {
+		shares[i] = cost * a.Percent / 100
+		left -= shares[i]
+	}
+
+	// Округление вниз теряет меньше копейки на долю, поэтому left меньше
+	// числа долей и раздаётся за один проход.
+	order := make([]int, len(allocs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(allocs[b].Percent, allocs[a].Percent) })
+	for _, i := range order[:max(left, 0)] {
+		shares[i]++
+	}
+	return shares, cost - allocated
+}
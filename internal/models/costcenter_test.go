@@ -0,0 +1,133 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeAllocations(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []CostAllocation
+		want    []CostAllocation
+		wantErr string
+	}{
+		{name: "empty", in: nil, want: []CostAllocation{}},
+		{name: "single defaults to 100", in: []CostAllocation{{CostCenterID: 3}}, want: []CostAllocation{{3, 100}}},
+		{name: "sorted by center", in: []CostAllocation{{5, 40}, {2, 60}}, want: []CostAllocation{{2, 60}, {5, 40}}},
+		{name: "zero percent in a split", in: []CostAllocation{{1, 0}, {2, 100}}, wantErr: "between 1 and 100"},
+		{name: "over 100", in: []CostAllocation{{1, 101}}, wantErr: "between 1 and 100"},
+		{name: "repeated center", in: []CostAllocation{{1, 50}, {1, 50}}, wantErr: "listed twice"},
+		{name: "sum below 100", in: []CostAllocation{{1, 50}, {2, 40}}, wantErr: "add up to 100, got 90"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeAllocations(tc.in)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSplitPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		cost     int
+		percents []int
+		want     []int
+		wantRest int
+	}{
+		{name: "even", cost: 1000, percents: []int{60, 40}, want: []int{600, 400}},
+		{name: "remainder to the largest share", cost: 101, percents: []int{50, 50}, want: []int{51, 50}},
+		{name: "remainder cents by descending percent", cost: 100, percents: []int{33, 34, 33}, want: []int{33, 34, 33}},
+		{name: "two remainder cents", cost: 2, percents: []int{33, 34, 33}, want: []int{1, 1, 0}},
+		{name: "thirds of 10", cost: 10, percents: []int{20, 30, 50}, want: []int{2, 3, 5}},
+		{name: "uneven thirds", cost: 7, percents: []int{25, 25, 50}, want: []int{2, 1, 4}},
+		{name: "zero cost", cost: 0, percents: []int{60, 40}, want: []int{0, 0}},
+		// Центр удалён до исправления Delete: осталась одна доля в 40%.
+		{name: "partial allocation keeps the rest", cost: 1000, percents: []int{40}, want: []int{400}, wantRest: 600},
+		{name: "partial allocation rounds down", cost: 999, percents: []int{40}, want: []int{399}, wantRest: 600},
+		{name: "large cost", cost: 9_000_000_001, percents: []int{40}, want: []int{3_600_000_000}, wantRest: 5_400_000_001},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allocs := make([]CostAllocation, len(tc.percents))
+			for i, p := range tc.percents {
+				allocs[i] = CostAllocation{CostCenterID: i + 1, Percent: p}
+			}
+			got, rest := splitPercent(tc.cost, allocs)
+			if !slices.Equal(got, tc.want) || rest != tc.wantRest {
+				t.Fatalf("want %v rest %d, got %v rest %d", tc.want, tc.wantRest, got, rest)
+			}
+		})
+	}
+}
+
+func TestChargeback(t *testing.T) {
+	centers := []*CostCenter{
+		{ID: 1, Code: "OPS", Name: "Operations"},
+		{ID: 2, Code: "DEV", Name: "Development"},
+	}
+	subs := []*SubscriptionWithCost{
+		{Subscription: Subscription{ID: 10, ServiceName: "Netflix"}, Cost: 1001},
+		{Subscription: Subscription{ID: 11, ServiceName: "Spotify"}, Cost: 300},
+		{Subscription: Subscription{ID: 12, ServiceName: "Slack"}, Cost: 500},
+		{Subscription: Subscription{ID: 13, ServiceName: "Figma"}, Cost: 1000},
+	}
+	allocations := map[int][]CostAllocation{
+		10: {{CostCenterID: 1, Percent: 50}, {CostCenterID: 2, Percent: 50}},
+		// 12 распределена на центр 3, которого больше нет.
+		12: {{CostCenterID: 3, Percent: 100}},
+		// 13 сохранила только 40% после удаления второго центра.
+		13: {{CostCenterID: 2, Percent: 40}},
+	}
+
+	got, total := Chargeback(subs, centers, allocations)
+	if total != 2801 {
+		t.Fatalf("total: want 2801, got %d", total)
+	}
+
+	type line struct{ sub, percent, cost int }
+	want := []struct {
+		code  string
+		total int
+		lines []line
+	}{
+		{"DEV", 900, []line{{10, 50, 500}, {13, 40, 400}}},
+		{"OPS", 501, []line{{10, 50, 501}}},
+		{"", 1400, []line{{11, 100, 300}, {12, 100, 500}, {13, 60, 600}}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d centers, got %d", len(want), len(got))
+	}
+	sum := 0
+	for i, w := range want {
+		c := got[i]
+		if c.Code != w.code || c.Total != w.total || len(c.Lines) != len(w.lines) {
+			t.Fatalf("center %d: want %s total %d with %d lines, got %s total %d with %d lines",
+				i, w.code, w.total, len(w.lines), c.Code, c.Total, len(c.Lines))
+		}
+		for j, wl := range w.lines {
+			l := c.Lines[j]
+			if l.SubscriptionID != wl.sub || l.Percent != wl.percent || l.Cost != wl.cost {
+				t.Errorf("center %s line %d: want %+v, got sub %d percent %d cost %d",
+					w.code, j, wl, l.SubscriptionID, l.Percent, l.Cost)
+			}
+		}
+		sum += c.Total
+	}
+	if (got[2].CostCenterID != nil) || sum != total {
+		t.Fatalf("unallocated must be last and centers must add up to the total %d, got %d", total, sum)
+	}
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type PriceChangeDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+// PriceChange — запланированная смена цены подписки начиная с месяца EffectiveDate.
+type PriceChange struct {
+	ID             int       `json:"id"`
+	SubscriptionID int       `json:"subscription_id"`
+	EffectiveDate  time.Time `json:"effective_date"`
+	Price          int       `json:"price"`
+}
+
+type ForecastMonth struct {
+	Month         time.Time `json:"month"`
+	Cost          int       `json:"cost"`
+	Cumulative    int       `json:"cumulative"`
+	Subscriptions int       `json:"subscriptions"`
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+// Смены цен видны вместе со своей подпиской, поэтому все выборки идут
+// через подписку s с условием арендатора.
+
+func (m *PriceChangeDB) GetAll(ctx context.Context) ([]*PriceChange, error) {
+	query := `SELECT pc.id, pc.subscription_id, pc.effective_date, pc.price
+              FROM {price_changes} pc
+              JOIN {subscriptions} s ON s.id = pc.subscription_id
+              WHERE ` + tenantScope + `
+              ORDER BY pc.subscription_id, pc.effective_date`
+
+	return m.query(ctx, query)
+}
+
+func (m *PriceChangeDB) GetBySubscriptionID(ctx context.Context, id int) ([]*PriceChange, error) {
+	query := `SELECT pc.id, pc.subscription_id, pc.effective_date, pc.price
+              FROM {price_changes} pc
+              JOIN {subscriptions} s ON s.id = pc.subscription_id
+              WHERE pc.subscription_id = $1 AND ` + tenantScope + `
+              ORDER BY pc.effective_date`
+
+	return m.query(ctx, query, id)
+}
+
+// GetByUserID возвращает смены цены подписок, которые пользователь
+// оплачивает или в которых участвует.
+func (m *PriceChangeDB) GetByUserID(ctx context.Context, uid uuid.UUID) ([]*PriceChange, error) {
+	query := `SELECT pc.id, pc.subscription_id, pc.effective_date, pc.price
+              FROM {price_changes} pc
+              JOIN {subscriptions} s ON s.id = pc.subscription_id
+              WHERE (s.user_id = $1 OR EXISTS (
+                  SELECT 1 FROM {subscription_members} sm WHERE sm.subscription_id = s.id AND sm.user_id = $1))
+                AND ` + tenantScope + `
+              ORDER BY pc.subscription_id, pc.effective_date`
+
+	return m.query(ctx, query, uid)
+}
+
+func (m *PriceChangeDB) query(ctx context.Context, query string, args ...any) ([]*PriceChange, error) {
+	var changes []*PriceChange
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, m.Tables.sql(query), args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		changes, err = scanPriceChanges(rows)
+		return err
+	})
+	return changes, err
+}
+
+//********************************************************************//
+//  							 CREATE								  //
+//********************************************************************//
+
+// Upsert сохраняет смену цены. Подписка чужого арендатора не видна, и для
+// неё возвращается sql.ErrNoRows.
+func (m *PriceChangeDB) Upsert(ctx context.Context, pc *PriceChange) error {
+	query := `INSERT INTO {price_changes} (subscription_id, effective_date, price)
+              SELECT s.id, $2::date, $3::integer FROM {subscriptions} s WHERE s.id = $1 AND ` + tenantScope + `
+              ON CONFLICT (subscription_id, effective_date)
+              DO UPDATE SET price = EXCLUDED.price
+              RETURNING id`
+
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, m.Tables.sql(query), pc.SubscriptionID, pc.EffectiveDate, pc.Price).Scan(&pc.ID)
+	})
+}
+
+//********************************************************************//
+//  							 FORECAST							  //
+//********************************************************************//
+
+// Forecast проецирует помесячные траты на months месяцев начиная с from.
+// Бессрочные подписки продолжаются весь период, цена месяца берётся из
+// последней смены цены, вступившей в силу не позже этого месяца. Если
+// задан uid, совместные подписки учитываются долей этого пользователя.
+func Forecast(subs []*Subscription, changes []*PriceChange, from time.Time, months int, uid *uuid.UUID) ([]*ForecastMonth, int) {
+	bySub := make(map[int][]*PriceChange)
+	for _, pc := range changes {
+		bySub[pc.SubscriptionID] = append(bySub[pc.SubscriptionID], pc)
+	}
+
+	series := []*ForecastMonth{}
+	total := 0
+
+	month := monthStart(from)
+	for i := 0; i < months; i++ {
+		fm := ForecastMonth{Month: month}
+
+		for _, sub := range subs {
+			priced := *sub
+			priced.Price = priceAt(sub, bySub[sub.ID], month)
+
+			if _, _, cost, ok := overlapCost(&priced, month, month); ok {
+				if uid != nil {
+					cost = sub.ShareOf(cost, *uid)
+				}
+				fm.Cost += cost
+				fm.Subscriptions++
+			}
+		}
+
+		total += fm.Cost
+		fm.Cumulative = total
+		series = append(series, &fm)
+
+		month = month.AddDate(0, 1, 0)
+	}
+	return series, total
+}
+
+func priceAt(sub *Subscription, changes []*PriceChange, month time.Time) int {
+	price := sub.Price
+	var effective time.Time
+	for _, pc := range changes {
+		if pc.EffectiveDate.After(month) || pc.EffectiveDate.Before(effective) {
+			continue
+		}
+		price = pc.Price
+		effective = pc.EffectiveDate
+	}
+	return price
+}
+
+func scanPriceChanges(rows *sql.Rows) ([]*PriceChange, error) {
+	changes := []*PriceChange{}
+	for rows.Next() {
+		var pc PriceChange
+		if err := rows.Scan(&pc.ID, &pc.SubscriptionID, &pc.EffectiveDate, &pc.Price); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &pc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// SubscriptionMember — участник совместной подписки. Стоимость делится
+// между участниками пропорционально Weight; равный раздел — все веса 1.
+type SubscriptionMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Weight int       `json:"weight"`
+}
+
+// MemberShare — доля участника в стоимости подписки.
+type MemberShare struct {
+	UserID uuid.UUID `json:"user_id"`
+	Weight int       `json:"weight"`
+	Cost   int       `json:"cost"`
+}
+
+// NormalizeMembers проверяет список участников: вес по умолчанию 1,
+// отрицательный вес и повтор пользователя — ошибка. Список сортируется
+// по user_id, чтобы раздел копеек был детерминированным.
+func NormalizeMembers(members []SubscriptionMember) ([]SubscriptionMember, error) {
+	normalized := make([]SubscriptionMember, 0, len(members))
+	for _, m := range members {
+		if m.UserID.Status != pgtype.Present {
+			return nil, errors.New("member user_id is required")
+		}
+		switch {
+		case m.Weight == 0:
+			m.Weight = 1
+		case m.Weight < 0:
+			return nil, fmt.Errorf("member %s: weight must be positive", m.UserID.UUID)
+		}
+		if slices.ContainsFunc(normalized, func(o SubscriptionMember) bool { return o.UserID.UUID == m.UserID.UUID }) {
+			return nil, fmt.Errorf("member %s is listed twice", m.UserID.UUID)
+		}
+		normalized = append(normalized, m)
+	}
+	slices.SortFunc(normalized, func(a, b SubscriptionMember) int {
+		return bytes.Compare(a.UserID.UUID[:], b.UserID.UUID[:])
+	})
+	return normalized, nil
+}
+
+// Shares делит cost между участниками подписки. Без участников вся
+// стоимость на плательщике. Копейки, оставшиеся от округления вниз,
+// раздаются по одной: сначала плательщику, если он участник, затем
+// остальным по порядку.
+func (s *Subscription) Shares(cost int) []MemberShare {
+	if len(s.Members) == 0 {
+		return []MemberShare{{UserID: s.UserID, Weight: 1, Cost: cost}}
+	}
+
+	total := 0
+	for _, m := range s.Members {
+		total += m.Weight
+	}
+
+	shares := make([]MemberShare, len(s.Members))
+	left := cost
+	for i, m := range s.Members {
+		shares[i] = MemberShare{UserID: m.UserID, Weight: m.Weight, Cost: cost * m.Weight / total}
+		left -= shares[i].Cost
+	}
+
+	order := make([]int, len(shares))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(payerFirst(shares[a], s.UserID), payerFirst(shares[b], s.UserID))
+	})
+	for i := 0; left > 0; i = (i + 1) % len(order) {
+		shares[order[i]].Cost++
+		left--
+	}
+	return shares
+}
+
+// ShareOf возвращает долю пользователя в cost; не участнику — 0.
+func (s *Subscription) ShareOf(cost int, uid uuid.UUID) int {
+	for _, share := range s.Shares(cost) {
+		if share.UserID.UUID == uid.UUID {
+			return share.Cost
+		}
+	}
+	return 0
+}
+
+// IsMember сообщает, участвует ли пользователь в подписке: как плательщик
+// или как участник раздела.
+func (s *Subscription) IsMember(uid uuid.UUID) bool {
+	if s.UserID.UUID == uid.UUID {
+		return true
+	}
+	return slices.ContainsFunc(s.Members, func(m SubscriptionMember) bool { return m.UserID.UUID == uid.UUID })
+}
+
+func payerFirst(share MemberShare, payer uuid.UUID) int {
+	if share.UserID.UUID == payer.UUID {
+		return 0
+	}
+	return 1
+}
+
+//********************************************************************//
+//  							 SETTLEMENT							  //
+//********************************************************************//
+
+// Debt — сколько участник должен плательщику за период по совместным
+// подпискам плательщика.
+type Debt struct {
+	PayerID       uuid.UUID    `json:"payer_id"`
+	MemberID      uuid.UUID    `json:"member_id"`
+	Amount        int          `json:"amount"`
+	Subscriptions []DebtDetail `json:"subscriptions"`
+}
+
+type DebtDetail struct {
+	SubscriptionID int    `json:"subscription_id"`
+	ServiceName    string `json:"service_name"`
+	Amount         int    `json:"amount"`
+}
+
+// Settle считает взаиморасчёты за период [from, to]: для каждой пары
+// (участник, плательщик) — сумма долей участника в подписках плательщика
+// по тем же правилам пересечения, что и GetSummary. Долги упорядочены по
+// плательщику, затем по участнику.
+func Settle(subs []*Subscription, from, to time.Time) []*Debt {
+	type pair struct{ payer, member [16]byte }
+	debts := map[pair]*Debt{}
+
+	for _, sub := range subs {
+		if len(sub.Members) == 0 {
+			continue
+		}
+		_, _, cost, ok := overlapCost(sub, from, to)
+		if !ok {
+			continue
+		}
+		for _, share := range sub.Shares(cost) {
+			if share.UserID.UUID == sub.UserID.UUID || share.Cost == 0 {
+				continue
+			}
+			key := pair{sub.UserID.UUID, share.UserID.UUID}
+			d, ok := debts[key]
+			if !ok {
+				d = &Debt{PayerID: sub.UserID, MemberID: share.UserID}
+				debts[key] = d
+			}
+			d.Amount += share.Cost
+			d.Subscriptions = append(d.Subscriptions, DebtDetail{
+				SubscriptionID: sub.ID,
+				ServiceName:    sub.ServiceName,
+				Amount:         share.Cost,
+			})
+		}
+	}
+
+	result := make([]*Debt, 0, len(debts))
+	for _, d := range debts {
+		result = append(result, d)
+	}
+	slices.SortFunc(result, func(a, b *Debt) int {
+		if c := bytes.Compare(a.PayerID.UUID[:], b.PayerID.UUID[:]); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.MemberID.UUID[:], b.MemberID.UUID[:])
+	})
+	return result
+}
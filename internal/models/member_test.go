@@ -0,0 +1,114 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+func testUUID(t *testing.T, s string) uuid.UUID {
+	t.Helper()
+	var uid uuid.UUID
+	if err := uid.Set(s); err != nil {
+		t.Fatalf("parse uuid %q: %v", s, err)
+	}
+	return uid
+}
+
+// Семейная подписка за 900 в месяц: плательщик несёт 2/3, участник — 1/3.
+func sharedTestSubscription(t *testing.T) (sub *Subscription, payer, member uuid.UUID) {
+	payer = testUUID(t, "11111111-1111-1111-1111-111111111111")
+	member = testUUID(t, "22222222-2222-2222-2222-222222222222")
+	sub = &Subscription{
+		ID:          1,
+		ServiceName: "Yandex Plus",
+		Price:       900,
+		UserID:      payer,
+		StartDate:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Members: []SubscriptionMember{
+			{UserID: payer, Weight: 2},
+			{UserID: member, Weight: 1},
+		},
+	}
+	return sub, payer, member
+}
+
+func TestEvaluateBudgetsUsesShares(t *testing.T) {
+	sub, payer, member := sharedTestSubscription(t)
+	month := sub.StartDate
+
+	for _, tc := range []struct {
+		user uuid.UUID
+		want int
+	}{{payer, 600}, {member, 300}} {
+		budgets := []*Budget{{UserID: tc.user, MonthlyLimit: 500}}
+		got := EvaluateBudgets(budgets, []*Subscription{sub}, nil, month, month)
+		if len(got) != 1 || got[0].Spent != tc.want || got[0].Remaining != 500-tc.want {
+			t.Fatalf("user %s: want spent %d, got %+v", tc.user.UUID, tc.want, got[0])
+		}
+	}
+}
+
+func TestForecastUsesShares(t *testing.T) {
+	sub, _, member := sharedTestSubscription(t)
+	changes := []*PriceChange{{SubscriptionID: sub.ID, EffectiveDate: sub.StartDate.AddDate(0, 1, 0), Price: 1200}}
+
+	series, total := Forecast([]*Subscription{sub}, changes, sub.StartDate, 2, &member)
+	if series[0].Cost != 300 || series[1].Cost != 400 || total != 700 {
+		t.Fatalf("member forecast: want 300, 400 (total 700), got %d, %d (total %d)", series[0].Cost, series[1].Cost, total)
+	}
+
+	_, total = Forecast([]*Subscription{sub}, changes, sub.StartDate, 2, nil)
+	if total != 2100 {
+		t.Fatalf("forecast without user: want full cost 2100, got %d", total)
+	}
+}
+
+func TestAnalyticsUsesShares(t *testing.T) {
+	sub, _, member := sharedTestSubscription(t)
+
+	got := Analytics([]*Subscription{sub}, sub.StartDate, sub.StartDate, &member)
+	if got[0].MRR != 300 || got[0].ARR != 3600 || got[0].Active != 1 {
+		t.Fatalf("member analytics: want MRR 300, ARR 3600, 1 active, got %+v", got[0])
+	}
+
+	got = Analytics([]*Subscription{sub}, sub.StartDate, sub.StartDate, nil)
+	if got[0].MRR != 900 {
+		t.Fatalf("analytics without user: want MRR 900, got %d", got[0].MRR)
+	}
+}
+
+func TestNewlyExceeded(t *testing.T) {
+	sub, payer, _ := sharedTestSubscription(t)
+	other := &Subscription{ID: 2, ServiceName: "Netflix", Price: 500, UserID: payer, StartDate: sub.StartDate}
+	budgets := []*Budget{{UserID: payer, MonthlyLimit: 1000}}
+	from, to := sub.StartDate, sub.StartDate.AddDate(0, 1, 0)
+
+	// Netflix уже укладывается в лимит; семейная подписка добавляет 600.
+	before := EvaluateBudgets(budgets, []*Subscription{other}, nil, from, to)
+	after := EvaluateBudgets(budgets, []*Subscription{other, sub}, nil, from, to)
+	if got := NewlyExceeded(before, after); len(got) != 2 {
+		t.Fatalf("new subscription: want both months exceeded, got %d", len(got))
+	}
+
+	// Лимит превышен и раньше: повышение цены не даёт новых предупреждений.
+	pricier := *sub
+	pricier.Price = 1200
+	before = after
+	after = EvaluateBudgets(budgets, []*Subscription{other, &pricier}, nil, from, to)
+	if got := NewlyExceeded(before, after); len(got) != 0 {
+		t.Fatalf("already over budget: want no months, got %d", len(got))
+	}
+
+	// Подписка, закончившаяся в первом месяце, снова укладывается во
+	// второй; продление выводит за лимит только его.
+	ended := *sub
+	end := sub.StartDate
+	ended.EndDate = &end
+	before = EvaluateBudgets(budgets, []*Subscription{other, &ended}, nil, from, to)
+	got := NewlyExceeded(before, EvaluateBudgets(budgets, []*Subscription{other, sub}, nil, from, to))
+	if len(got) != 1 || !got[0].Month.Equal(to) {
+		t.Fatalf("extended subscription: want only %s, got %+v", to.Format("01-2006"), got)
+	}
+}
@@ -0,0 +1,322 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// MemorySubscriptionDB — SubscriptionRepository в памяти процесса с той же
+// семантикой, что и SubscriptionDB. Подходит для тестов обработчиков.
+type MemorySubscriptionDB struct {
+	mu     sync.RWMutex
+	subs   map[int]Subscription
+	nextID int
+}
+
+func NewMemorySubscriptionDB() *MemorySubscriptionDB {
+	return &MemorySubscriptionDB{
+		subs:   make(map[int]Subscription),
+		nextID: 1,
+	}
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+func (m *MemorySubscriptionDB) GetAll(ctx context.Context) ([]*Subscription, error) {
+	return m.filter(ctx, func(Subscription) bool { return true })
+}
+
+func (m *MemorySubscriptionDB) Get(ctx context.Context, id int) (*Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sub, err := m.visible(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return copySubscription(sub), nil
+}
+
+func (m *MemorySubscriptionDB) GetByUserID(ctx context.Context, uid uuid.UUID) ([]*Subscription, error) {
+	return m.filter(ctx, func(sub Subscription) bool { return sub.UserID.UUID == uid.UUID })
+}
+
+func (m *MemorySubscriptionDB) GetByUserSubscription(ctx context.Context, serviceName string) ([]*Subscription, error) {
+	return m.filter(ctx, func(sub Subscription) bool { return sub.ServiceName == serviceName })
+}
+
+func (m *MemorySubscriptionDB) GetActive(ctx context.Context, at time.Time) ([]*Subscription, error) {
+	return m.filter(ctx, func(sub Subscription) bool { return sub.EndDate == nil || !sub.EndDate.Before(at) })
+}
+
+func (m *MemorySubscriptionDB) Find(ctx context.Context, filter SubscriptionFilter) ([]*Subscription, error) {
+	uid, err := filter.user()
+	if err != nil {
+		return nil, err
+	}
+	uids, err := filter.users()
+	if err != nil {
+		return nil, err
+	}
+	tags := NormalizeTags(filter.Tags)
+
+	subs, err := m.filter(ctx, func(sub Subscription) bool {
+		if uid != nil && !sub.IsMember(*uid) {
+			return false
+		}
+		if filter.ServiceName != "" && sub.ServiceName != filter.ServiceName {
+			return false
+		}
+		if len(uids) > 0 && !slices.ContainsFunc(uids, sub.IsMember) {
+			return false
+		}
+		if len(filter.ServiceNames) > 0 && !slices.Contains(filter.ServiceNames, sub.ServiceName) {
+			return false
+		}
+		for _, tag := range tags {
+			if !slices.Contains(sub.Tags, tag) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit > 0 && len(subs) > filter.Limit {
+		subs = subs[:filter.Limit]
+	}
+	return subs, nil
+}
+
+func (m *MemorySubscriptionDB) UserIDs(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	subs, err := m.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uids := []uuid.UUID{}
+	for _, sub := range subs {
+		uids = append(uids, sub.UserID)
+		for _, member := range sub.Members {
+			uids = append(uids, member.UserID)
+		}
+	}
+	slices.SortFunc(uids, func(a, b uuid.UUID) int { return bytes.Compare(a.UUID[:], b.UUID[:]) })
+	uids = slices.CompactFunc(uids, func(a, b uuid.UUID) bool { return a.UUID == b.UUID })
+	if limit > 0 && len(uids) > limit {
+		uids = uids[:limit]
+	}
+	return uids, nil
+}
+
+//********************************************************************//
+//  							 CREATE								  //
+//********************************************************************//
+
+func (m *MemorySubscriptionDB) Insert(ctx context.Context, sub *Subscription) error {
+	org, err := insertOrganization(ctx, sub.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	candidate := *sub
+	candidate.OrganizationID = org
+	if m.conflicts(candidate, 0) {
+		return ErrDuplicateSubscription
+	}
+
+	sub.OrganizationID = org
+	sub.ID = m.nextID
+	m.nextID++
+	sub.Tags = NormalizeTags(sub.Tags)
+	m.subs[sub.ID] = *copySubscription(*sub)
+	return nil
+}
+
+//********************************************************************//
+//  							 DELETE								  //
+//********************************************************************//
+
+func (m *MemorySubscriptionDB) Delete(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.visible(ctx, id); err != nil {
+		return err
+	}
+	delete(m.subs, id)
+	return nil
+}
+
+func (m *MemorySubscriptionDB) DeleteByUserID(ctx context.Context, uid uuid.UUID) error {
+	return m.deleteWhere(ctx, func(sub Subscription) bool { return sub.UserID.UUID == uid.UUID })
+}
+
+func (m *MemorySubscriptionDB) DeleteByServiceName(ctx context.Context, serviceName string) error {
+	return m.deleteWhere(ctx, func(sub Subscription) bool { return sub.ServiceName == serviceName })
+}
+
+//********************************************************************//
+//  							 UPDATE								  //
+//********************************************************************//
+
+func (m *MemorySubscriptionDB) Update(ctx context.Context, upd Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, err := m.visible(ctx, upd.ID)
+	if err != nil {
+		return err
+	}
+	upd.OrganizationID = current.OrganizationID
+	if m.conflicts(upd, upd.ID) {
+		return ErrDuplicateSubscription
+	}
+
+	upd.Tags = NormalizeTags(upd.Tags)
+	upd.Members = current.Members
+	m.subs[upd.ID] = *copySubscription(upd)
+	return nil
+}
+
+func (m *MemorySubscriptionDB) SetMembers(ctx context.Context, id int, members []SubscriptionMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub, err := m.visible(ctx, id)
+	if err != nil {
+		return err
+	}
+	sub.Members = members
+	m.subs[id] = *copySubscription(sub)
+	return nil
+}
+
+//********************************************************************//
+//  							 FILTER								  //
+//********************************************************************//
+
+func (m *MemorySubscriptionDB) GetSummary(ctx context.Context, from, to time.Time, filter SubscriptionFilter) ([]*SubscriptionWithCost, int, error) {
+	subs, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	uid, err := filter.user()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	subCosts, totalcost := Summarize(ctx, subs, from, to, uid)
+	return subCosts, totalcost, nil
+}
+
+//********************************************************************//
+//  							 HELPERS							  //
+//********************************************************************//
+
+// filter возвращает копии подходящих подписок арендатора из ctx в порядке
+// ORDER BY user_id, service_name.
+func (m *MemorySubscriptionDB) filter(ctx context.Context, keep func(Subscription) bool) ([]*Subscription, error) {
+	org, all, err := OrganizationFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subscriptions := []*Subscription{}
+	for _, sub := range m.subs {
+		if (all || sub.OrganizationID == org) && keep(sub) {
+			subscriptions = append(subscriptions, copySubscription(sub))
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if c := bytes.Compare(a.UserID.UUID[:], b.UserID.UUID[:]); c != 0 {
+			return c < 0
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.ID < b.ID
+	})
+	return subscriptions, nil
+}
+
+// visible возвращает подписку id, если она принадлежит арендатору из ctx.
+// Чужая подписка неотличима от отсутствующей. Вызывается под m.mu.
+func (m *MemorySubscriptionDB) visible(ctx context.Context, id int) (Subscription, error) {
+	org, all, err := OrganizationFrom(ctx)
+	if err != nil {
+		return Subscription{}, err
+	}
+	sub, ok := m.subs[id]
+	if !ok || (!all && sub.OrganizationID != org) {
+		return Subscription{}, sql.ErrNoRows
+	}
+	return sub, nil
+}
+
+func (m *MemorySubscriptionDB) deleteWhere(ctx context.Context, match func(Subscription) bool) error {
+	org, all, err := OrganizationFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, sub := range m.subs {
+		if (all || sub.OrganizationID == org) && match(sub) {
+			delete(m.subs, id)
+		}
+	}
+	return nil
+}
+
+// conflicts проверяет ограничение unique_service_user внутри организации
+// sub, не считая запись skipID.
+func (m *MemorySubscriptionDB) conflicts(sub Subscription, skipID int) bool {
+	for id, other := range m.subs {
+		if id != skipID && other.OrganizationID == sub.OrganizationID &&
+			other.ServiceName == sub.ServiceName && other.UserID.UUID == sub.UserID.UUID {
+			return true
+		}
+	}
+	return false
+}
+
+func copySubscription(sub Subscription) *Subscription {
+	if sub.EndDate != nil {
+		end := *sub.EndDate
+		sub.EndDate = &end
+	}
+	if sub.CategoryID != nil {
+		category := *sub.CategoryID
+		sub.CategoryID = &category
+	}
+	sub.Tags = slices.Clone(sub.Tags)
+	if sub.Tags == nil {
+		sub.Tags = []string{}
+	}
+	sub.Members = slices.Clone(sub.Members)
+	if len(sub.Members) == 0 {
+		sub.Members = nil
+	}
+	return &sub
+}
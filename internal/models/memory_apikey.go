@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryAPIKeyDB — APIKeyRepository в памяти процесса с той же
+// семантикой, что и APIKeyDB, включая редкую запись last_used_at.
+// Подходит для тестов аутентификации.
+type MemoryAPIKeyDB struct {
+	mu     sync.Mutex
+	keys   map[int]APIKey
+	nextID int
+}
+
+func NewMemoryAPIKeyDB() *MemoryAPIKeyDB {
+	return &MemoryAPIKeyDB{
+		keys:   make(map[int]APIKey),
+		nextID: 1,
+	}
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+func (m *MemoryAPIKeyDB) GetAll(ctx context.Context) ([]*APIKey, error) {
+	org, err := apiKeyOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []*APIKey{}
+	for _, k := range m.keys {
+		if org == nil || (k.OrganizationID != nil && *k.OrganizationID == *org) {
+			keys = append(keys, copyAPIKey(k))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (m *MemoryAPIKeyDB) Authenticate(ctx context.Context, plaintext string, now time.Time) (*APIKey, error) {
+	prefix, ok := lookupPrefix(plaintext)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, k := range m.keys {
+		if k.Prefix != prefix {
+			continue
+		}
+		if err := k.verify(plaintext, now); err != nil {
+			return nil, err
+		}
+		if k.touchDue(now) {
+			k.LastUsedAt = &now
+			m.keys[id] = k
+		}
+		return copyAPIKey(k), nil
+	}
+	return nil, ErrInvalidAPIKey
+}
+
+//********************************************************************//
+//  							 INSERT								  //
+//********************************************************************//
+
+func (m *MemoryAPIKeyDB) Insert(ctx context.Context, k *APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k.ID = m.nextID
+	m.nextID++
+	k.CreatedAt = time.Now()
+	m.keys[k.ID] = *copyAPIKey(*k)
+	return nil
+}
+
+//********************************************************************//
+//  							 REVOKE								  //
+//********************************************************************//
+
+func (m *MemoryAPIKeyDB) Revoke(ctx context.Context, id int, at time.Time) error {
+	org, err := apiKeyOrganization(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.keys[id]
+	if !ok || k.RevokedAt != nil || (org != nil && (k.OrganizationID == nil || *k.OrganizationID != *org)) {
+		return sql.ErrNoRows
+	}
+	k.RevokedAt = &at
+	m.keys[id] = k
+	return nil
+}
+
+func copyAPIKey(k APIKey) *APIKey {
+	k.Scopes = slices.Clone(k.Scopes)
+	return &k
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// MemoryReminderDB — ReminderRepository в памяти процесса с той же
+// семантикой, что и ReminderDB. Видимость подписок для Claim берётся из
+// Subscriptions. Подходит для тестов планировщика напоминаний.
+type MemoryReminderDB struct {
+	Subscriptions SubscriptionRepository
+
+	mu       sync.Mutex
+	claimed  map[reminderKey]bool
+	contacts map[contactKey]string
+}
+
+type reminderKey struct {
+	subscriptionID int
+	chargeDate     time.Time
+	channel        string
+}
+
+type contactKey struct {
+	organizationID int
+	userID         [16]byte
+}
+
+func NewMemoryReminderDB(subs SubscriptionRepository) *MemoryReminderDB {
+	return &MemoryReminderDB{
+		Subscriptions: subs,
+		claimed:       make(map[reminderKey]bool),
+		contacts:      make(map[contactKey]string),
+	}
+}
+
+//********************************************************************//
+//  							 REMINDERS							  //
+//********************************************************************//
+
+func (m *MemoryReminderDB) Claim(ctx context.Context, subscriptionID int, chargeDate time.Time, channel string) (bool, error) {
+	if _, err := m.Subscriptions.Get(ctx, subscriptionID); errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := reminderKey{subscriptionID, truncateDay(chargeDate), channel}
+	if m.claimed[key] {
+		return false, nil
+	}
+	m.claimed[key] = true
+	return true, nil
+}
+
+func (m *MemoryReminderDB) Release(ctx context.Context, subscriptionID int, chargeDate time.Time, channel string) error {
+	if _, err := m.Subscriptions.Get(ctx, subscriptionID); errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.claimed, reminderKey{subscriptionID, truncateDay(chargeDate), channel})
+	return nil
+}
+
+//********************************************************************//
+//  							 CONTACTS							  //
+//********************************************************************//
+
+func (m *MemoryReminderDB) ContactEmail(ctx context.Context, uid uuid.UUID) (string, error) {
+	org, err := insertOrganization(ctx, 0)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.contacts[contactKey{org, uid.UUID}], nil
+}
+
+func (m *MemoryReminderDB) SetContactEmail(ctx context.Context, uid uuid.UUID, email string) error {
+	org, err := insertOrganization(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.contacts[contactKey{org, uid.UUID}] = email
+	return nil
+}
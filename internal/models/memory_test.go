@@ -0,0 +1,13 @@
+package models_test
+
+import (
+	"subscription-service/internal/models"
+	"subscription-service/internal/models/modelstest"
+	"testing"
+)
+
+func TestMemorySubscriptionDB(t *testing.T) {
+	modelstest.TestSubscriptionRepository(t, func(t *testing.T) models.SubscriptionRepository {
+		return models.NewMemorySubscriptionDB()
+	})
+}
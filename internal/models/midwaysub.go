@@ -14,6 +14,8 @@ type MidwaySub struct {
 	UserID      uuid.UUID `json:"user_id"`
 	StartDate   string    `json:"start_date"`
 	EndDate     string    `json:"end_date,omitempty"`
+	CategoryID  *int      `json:"category_id,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
 }
 
 func (mid MidwaySub) FromMidwaySub() (Subscription, error) {
@@ -40,6 +42,8 @@ func (mid MidwaySub) FromMidwaySub() (Subscription, error) {
 		UserID:      mid.UserID,
 		StartDate:   start,
 		EndDate:     endPtr,
+		CategoryID:  mid.CategoryID,
+		Tags:        NormalizeTags(mid.Tags),
 	}
 
 	return sub, nil
@@ -49,7 +53,7 @@ func (m MidwaySub) PrintFields() {
 	fmt.Printf("ID: %d\n", m.ID)
 	fmt.Printf("ServiceName: %s\n", m.ServiceName)
 	fmt.Printf("Price: %d\n", m.Price)
-	fmt.Printf("UserID: %s\n", m.UserID)
+	fmt.Printf("UserID: %s\n", m.UserID.UUID)
 	fmt.Printf("StartDate: %s\n", m.StartDate)
 	fmt.Printf("EndDate: %s\n", m.EndDate)
 }
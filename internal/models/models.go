@@ -3,11 +3,31 @@ package models
 import "database/sql"
 
 type Models struct {
-	Subscriptions SubscriptionDB
+	Subscriptions SubscriptionRepository
+	Reminders     ReminderRepository
+	Budgets       BudgetDB
+	PriceChanges  PriceChangeDB
+	Schema        SchemaDB
+	RateLimits    RateLimitDB
+	APIKeys       APIKeyRepository
+	Services      ServiceDB
+	Categories    CategoryDB
+	Organizations OrganizationDB
+	CostCenters   CostCenterDB
 }
 
-func NewModels(db *sql.DB) Models {
+func NewModels(db *sql.DB, tables Tables) Models {
 	return Models{
-		Subscriptions: SubscriptionDB{DB: db},
+		Subscriptions: &SubscriptionDB{DB: db, Tables: tables},
+		Reminders:     &ReminderDB{DB: db, Tables: tables},
+		Budgets:       BudgetDB{DB: db, Tables: tables},
+		PriceChanges:  PriceChangeDB{DB: db, Tables: tables},
+		Schema:        SchemaDB{DB: db, Tables: tables},
+		RateLimits:    RateLimitDB{DB: db, Tables: tables},
+		APIKeys:       &APIKeyDB{DB: db, Tables: tables},
+		Services:      ServiceDB{DB: db, Tables: tables},
+		Categories:    CategoryDB{DB: db, Tables: tables},
+		Organizations: OrganizationDB{DB: db, Tables: tables},
+		CostCenters:   CostCenterDB{DB: db, Tables: tables},
 	}
 }
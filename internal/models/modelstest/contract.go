@@ -0,0 +1,554 @@
+// Package modelstest содержит общий контрактный набор проверок для
+// реализаций models.SubscriptionRepository.
+//
+// Реализация проверяется из своего теста:
+//
+//	func TestMemorySubscriptionDB(t *testing.T) {
+//		modelstest.TestSubscriptionRepository(t, func(t *testing.T) models.SubscriptionRepository {
+//			return models.NewMemorySubscriptionDB()
+//		})
+//	}
+//
+// newRepo вызывается для каждой проверки и должен возвращать пустое хранилище
+// (для Postgres — с очищенной таблицей подписок). Проверки работают от
+// имени организации models.DefaultOrganizationID, которую создают миграции,
+// а изоляцию проверяют во второй организации OtherOrganizationID — её
+// должна завести реализация, где организации — внешний ключ.
+// Реализации в памяти проверяет internal/models/memory_test.go, Postgres —
+// internal/models/postgres_test.go, если задана переменная
+// SUBSCRIPTIONS_TEST_POSTGRES_DSN.
+package modelstest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"subscription-service/internal/models"
+	"testing"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// OtherOrganizationID — вторая организация для проверок изоляции.
+const OtherOrganizationID = models.DefaultOrganizationID + 1
+
+const (
+	userA = "11111111-1111-1111-1111-111111111111"
+	userB = "22222222-2222-2222-2222-222222222222"
+	userC = "ffffffff-ffff-ffff-ffff-ffffffffffff"
+)
+
+func TestSubscriptionRepository(t *testing.T, newRepo func(t *testing.T) models.SubscriptionRepository) {
+	t.Run("InsertAndGet", func(t *testing.T) {
+		repo := newRepo(t)
+		end := month(t, "12-2025")
+		sub := newSub(t, "Netflix", 500, userA, "01-2025", &end)
+
+		if err := repo.Insert(tenantCtx(t), sub); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+		if sub.ID <= 0 {
+			t.Fatalf("Insert did not assign an ID, got %d", sub.ID)
+		}
+
+		got, err := repo.Get(tenantCtx(t), sub.ID)
+		if err != nil {
+			t.Fatalf("Get(%d): %v", sub.ID, err)
+		}
+		assertEqualSub(t, got, sub)
+		if got.OrganizationID != models.DefaultOrganizationID {
+			t.Fatalf("Insert: want organization %d, got %d", models.DefaultOrganizationID, got.OrganizationID)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.Get(tenantCtx(t), 987654); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Get of missing record: want sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("Tenancy", func(t *testing.T) {
+		repo := newRepo(t)
+		sub := newSub(t, "Netflix", 500, userA, "01-2025", nil)
+		mustInsert(t, repo, sub)
+
+		// Другая организация не видит подписку и не может её изменить.
+		other := models.WithOrganization(t.Context(), models.DefaultOrganizationID+1000)
+		if _, err := repo.Get(other, sub.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Get from another organization: want sql.ErrNoRows, got %v", err)
+		}
+		all, err := repo.GetAll(other)
+		if err != nil {
+			t.Fatalf("GetAll from another organization: %v", err)
+		}
+		assertCount(t, "GetAll from another organization", all, 0)
+		if err := repo.Update(other, *sub); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Update from another organization: want sql.ErrNoRows, got %v", err)
+		}
+		if err := repo.SetMembers(other, sub.ID, nil); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("SetMembers from another organization: want sql.ErrNoRows, got %v", err)
+		}
+		if err := repo.DeleteByUserID(other, parseUUID(t, userA)); err != nil {
+			t.Fatalf("DeleteByUserID from another organization: %v", err)
+		}
+		if err := repo.Delete(other, sub.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Delete from another organization: want sql.ErrNoRows, got %v", err)
+		}
+		if _, err := repo.Get(tenantCtx(t), sub.ID); err != nil {
+			t.Fatalf("Get after foreign changes: %v", err)
+		}
+
+		// Без арендатора хранилище не отвечает, а со всеми — видит всё.
+		if _, err := repo.GetAll(t.Context()); !errors.Is(err, models.ErrNoOrganization) {
+			t.Fatalf("GetAll without organization: want ErrNoOrganization, got %v", err)
+		}
+		if err := repo.Insert(models.WithAllOrganizations(t.Context()), newSub(t, "Spotify", 300, userA, "01-2025", nil)); !errors.Is(err, models.ErrNoOrganization) {
+			t.Fatalf("Insert for all organizations without organization_id: want ErrNoOrganization, got %v", err)
+		}
+		all, err = repo.GetAll(models.WithAllOrganizations(t.Context()))
+		if err != nil {
+			t.Fatalf("GetAll for all organizations: %v", err)
+		}
+		assertCount(t, "GetAll for all organizations", all, 1)
+	})
+
+	t.Run("UniqueServiceUser", func(t *testing.T) {
+		repo := newRepo(t)
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userA, "01-2025", nil))
+
+		err := repo.Insert(tenantCtx(t), newSub(t, "Netflix", 600, userA, "02-2025", nil))
+		if !errors.Is(err, models.ErrDuplicateSubscription) {
+			t.Fatalf("duplicate Insert: want ErrDuplicateSubscription, got %v", err)
+		}
+
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userB, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userA, "01-2025", nil))
+
+		// Та же пара в другой организации — другая подписка, и обновление
+		// чужой записи в неё тоже не упирается.
+		other := models.WithOrganization(t.Context(), OtherOrganizationID)
+		foreign := newSub(t, "Netflix", 700, userA, "03-2025", nil)
+		if err := repo.Insert(other, foreign); err != nil {
+			t.Fatalf("Insert of the same pair in another organization: %v", err)
+		}
+		if foreign.OrganizationID != OtherOrganizationID {
+			t.Fatalf("Insert in another organization: want organization %d, got %d", OtherOrganizationID, foreign.OrganizationID)
+		}
+		spotify := newSub(t, "Spotify", 300, userA, "01-2025", nil)
+		if err := repo.Insert(other, spotify); err != nil {
+			t.Fatalf("Insert Spotify in another organization: %v", err)
+		}
+		spotify.ServiceName = "Netflix"
+		if err := repo.Update(other, *spotify); !errors.Is(err, models.ErrDuplicateSubscription) {
+			t.Fatalf("Update into a pair taken in the same organization: want ErrDuplicateSubscription, got %v", err)
+		}
+	})
+
+	t.Run("Ordering", func(t *testing.T) {
+		repo := newRepo(t)
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userC, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userB, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userA, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Apple", 200, userA, "01-2025", nil))
+
+		all, err := repo.GetAll(tenantCtx(t))
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+
+		want := []struct{ user, service string }{
+			{userA, "Apple"},
+			{userA, "Spotify"},
+			{userB, "Netflix"},
+			{userC, "Spotify"},
+		}
+		if len(all) != len(want) {
+			t.Fatalf("GetAll: want %d records, got %d", len(want), len(all))
+		}
+		for i, w := range want {
+			if all[i].UserID.UUID != parseUUID(t, w.user).UUID || all[i].ServiceName != w.service {
+				t.Errorf("GetAll[%d]: want %s/%s, got %s/%s", i, w.user, w.service, all[i].UserID.UUID, all[i].ServiceName)
+			}
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		repo := newRepo(t)
+		ended := month(t, "03-2025")
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userA, "01-2025", &ended))
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userA, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userB, "01-2025", nil))
+
+		byUser, err := repo.GetByUserID(tenantCtx(t), parseUUID(t, userA))
+		if err != nil {
+			t.Fatalf("GetByUserID: %v", err)
+		}
+		assertCount(t, "GetByUserID", byUser, 2)
+
+		byService, err := repo.GetByUserSubscription(tenantCtx(t), "Netflix")
+		if err != nil {
+			t.Fatalf("GetByUserSubscription: %v", err)
+		}
+		assertCount(t, "GetByUserSubscription", byService, 2)
+
+		active, err := repo.GetActive(tenantCtx(t), month(t, "06-2025"))
+		if err != nil {
+			t.Fatalf("GetActive: %v", err)
+		}
+		assertCount(t, "GetActive", active, 2)
+
+		active, err = repo.GetActive(tenantCtx(t), ended)
+		if err != nil {
+			t.Fatalf("GetActive: %v", err)
+		}
+		assertCount(t, "GetActive on end_date", active, 3)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		repo := newRepo(t)
+		netflix := newSub(t, "Netflix", 500, userA, "01-2025", nil)
+		netflix.Tags = []string{" Video ", "family", "video"}
+		mustInsert(t, repo, netflix)
+		spotify := newSub(t, "Spotify", 300, userA, "01-2025", nil)
+		spotify.Tags = []string{"music", "family"}
+		mustInsert(t, repo, spotify)
+		mustInsert(t, repo, newSub(t, "Apple", 200, userB, "01-2025", nil))
+
+		got, err := repo.Get(tenantCtx(t), netflix.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if want := []string{"family", "video"}; !slices.Equal(got.Tags, want) {
+			t.Fatalf("tags are not normalized: want %v, got %v", want, got.Tags)
+		}
+
+		family, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{Tags: []string{"Family"}})
+		if err != nil {
+			t.Fatalf("Find by tag: %v", err)
+		}
+		assertCount(t, "Find by tag", family, 2)
+
+		both, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{UserID: userA, Tags: []string{"family", "music"}})
+		if err != nil {
+			t.Fatalf("Find by user and tags: %v", err)
+		}
+		assertCount(t, "Find by user and tags", both, 1)
+
+		all, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{})
+		if err != nil {
+			t.Fatalf("Find without filter: %v", err)
+		}
+		assertCount(t, "Find without filter", all, 3)
+	})
+
+	t.Run("BatchFilters", func(t *testing.T) {
+		repo := newRepo(t)
+		shared := newSub(t, "Yandex Plus", 400, userC, "01-2025", nil)
+		mustInsert(t, repo, shared)
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userA, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userB, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Apple", 200, userC, "01-2025", nil))
+
+		members := []models.SubscriptionMember{
+			{UserID: parseUUID(t, userC), Weight: 1},
+			{UserID: parseUUID(t, userB), Weight: 1},
+		}
+		if err := repo.SetMembers(tenantCtx(t), shared.ID, members); err != nil {
+			t.Fatalf("SetMembers: %v", err)
+		}
+
+		byUsers, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{UserIDs: []string{userA, userB}})
+		if err != nil {
+			t.Fatalf("Find by users: %v", err)
+		}
+		assertCount(t, "Find by users with members", byUsers, 3)
+
+		byServices, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{ServiceNames: []string{"Netflix", "Apple", "Missing"}})
+		if err != nil {
+			t.Fatalf("Find by services: %v", err)
+		}
+		assertCount(t, "Find by services", byServices, 2)
+
+		both, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{UserIDs: []string{userC}, ServiceNames: []string{"Apple", "Spotify"}})
+		if err != nil {
+			t.Fatalf("Find by users and services: %v", err)
+		}
+		assertCount(t, "Find by users and services", both, 1)
+
+		if _, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{UserIDs: []string{"not-a-uuid"}}); err == nil {
+			t.Fatal("Find by invalid user id: want error, got nil")
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		repo := newRepo(t)
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userC, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userB, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Apple", 200, userA, "01-2025", nil))
+
+		first, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{Limit: 2})
+		if err != nil {
+			t.Fatalf("Find with limit: %v", err)
+		}
+		assertCount(t, "Find with limit", first, 2)
+		if first[0].ServiceName != "Apple" || first[1].ServiceName != "Netflix" {
+			t.Fatalf("Find with limit must keep order: got %s, %s", first[0].ServiceName, first[1].ServiceName)
+		}
+	})
+
+	t.Run("UserIDs", func(t *testing.T) {
+		repo := newRepo(t)
+		shared := newSub(t, "Yandex Plus", 400, userC, "01-2025", nil)
+		mustInsert(t, repo, shared)
+		mustInsert(t, repo, newSub(t, "Apple", 200, userC, "01-2025", nil))
+		members := []models.SubscriptionMember{
+			{UserID: parseUUID(t, userC), Weight: 1},
+			{UserID: parseUUID(t, userA), Weight: 1},
+		}
+		if err := repo.SetMembers(tenantCtx(t), shared.ID, members); err != nil {
+			t.Fatalf("SetMembers: %v", err)
+		}
+
+		uids, err := repo.UserIDs(tenantCtx(t), 0)
+		if err != nil {
+			t.Fatalf("UserIDs: %v", err)
+		}
+		if len(uids) != 2 || uids[0].UUID != parseUUID(t, userA).UUID || uids[1].UUID != parseUUID(t, userC).UUID {
+			t.Fatalf("UserIDs: want payer and member once each in UUID order, got %v", uids)
+		}
+
+		uids, err = repo.UserIDs(tenantCtx(t), 1)
+		if err != nil {
+			t.Fatalf("UserIDs with limit: %v", err)
+		}
+		if len(uids) != 1 || uids[0].UUID != parseUUID(t, userA).UUID {
+			t.Fatalf("UserIDs with limit: want only %s, got %v", userA, uids)
+		}
+
+		other := models.WithOrganization(t.Context(), models.DefaultOrganizationID+1000)
+		uids, err = repo.UserIDs(other, 0)
+		if err != nil {
+			t.Fatalf("UserIDs from another organization: %v", err)
+		}
+		if len(uids) != 0 {
+			t.Fatalf("UserIDs from another organization: want none, got %v", uids)
+		}
+	})
+
+	t.Run("Members", func(t *testing.T) {
+		repo := newRepo(t)
+		sub := newSub(t, "Yandex Plus", 400, userA, "01-2025", nil)
+		mustInsert(t, repo, sub)
+
+		members := []models.SubscriptionMember{
+			{UserID: parseUUID(t, userA), Weight: 1},
+			{UserID: parseUUID(t, userB), Weight: 3},
+		}
+		if err := repo.SetMembers(tenantCtx(t), sub.ID, members); err != nil {
+			t.Fatalf("SetMembers: %v", err)
+		}
+		if err := repo.SetMembers(tenantCtx(t), 987654, members); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("SetMembers of missing record: want sql.ErrNoRows, got %v", err)
+		}
+
+		sub.Price = 800
+		if err := repo.Update(tenantCtx(t), *sub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := repo.Get(tenantCtx(t), sub.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if len(got.Members) != 2 {
+			t.Fatalf("Update must keep members: want 2, got %d", len(got.Members))
+		}
+
+		shared, err := repo.Find(tenantCtx(t), models.SubscriptionFilter{UserID: userB})
+		if err != nil {
+			t.Fatalf("Find by member: %v", err)
+		}
+		assertCount(t, "Find by member", shared, 1)
+
+		from, to := month(t, "01-2025"), month(t, "03-2025")
+		_, total, err := repo.GetSummary(tenantCtx(t), from, to, models.SubscriptionFilter{UserID: userB})
+		if err != nil {
+			t.Fatalf("GetSummary by member: %v", err)
+		}
+		if want := 3 * 800 * 3 / 4; total != want {
+			t.Fatalf("GetSummary by member: want share %d, got %d", want, total)
+		}
+		_, total, err = repo.GetSummary(tenantCtx(t), from, to, models.SubscriptionFilter{})
+		if err != nil {
+			t.Fatalf("GetSummary: %v", err)
+		}
+		if want := 3 * 800; total != want {
+			t.Fatalf("GetSummary without user: want full cost %d, got %d", want, total)
+		}
+
+		if err := repo.SetMembers(tenantCtx(t), sub.ID, nil); err != nil {
+			t.Fatalf("SetMembers to none: %v", err)
+		}
+		shared, err = repo.Find(tenantCtx(t), models.SubscriptionFilter{UserID: userB})
+		if err != nil {
+			t.Fatalf("Find by former member: %v", err)
+		}
+		assertCount(t, "Find by former member", shared, 0)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		sub := newSub(t, "Netflix", 500, userA, "01-2025", nil)
+		mustInsert(t, repo, sub)
+		other := newSub(t, "Spotify", 300, userA, "01-2025", nil)
+		mustInsert(t, repo, other)
+
+		end := month(t, "06-2025")
+		sub.Price = 650
+		sub.EndDate = &end
+		if err := repo.Update(tenantCtx(t), *sub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := repo.Get(tenantCtx(t), sub.ID)
+		if err != nil {
+			t.Fatalf("Get after Update: %v", err)
+		}
+		assertEqualSub(t, got, sub)
+
+		other.ServiceName = "Netflix"
+		if err := repo.Update(tenantCtx(t), *other); !errors.Is(err, models.ErrDuplicateSubscription) {
+			t.Fatalf("conflicting Update: want ErrDuplicateSubscription, got %v", err)
+		}
+
+		missing := *sub
+		missing.ID = 987654
+		if err := repo.Update(tenantCtx(t), missing); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Update of missing record: want sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		sub := newSub(t, "Netflix", 500, userA, "01-2025", nil)
+		mustInsert(t, repo, sub)
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userA, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userB, "01-2025", nil))
+		mustInsert(t, repo, newSub(t, "Apple", 200, userC, "01-2025", nil))
+
+		if err := repo.Delete(tenantCtx(t), sub.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := repo.Delete(tenantCtx(t), sub.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("second Delete: want sql.ErrNoRows, got %v", err)
+		}
+
+		if err := repo.DeleteByServiceName(tenantCtx(t), "Spotify"); err != nil {
+			t.Fatalf("DeleteByServiceName: %v", err)
+		}
+		if err := repo.DeleteByUserID(tenantCtx(t), parseUUID(t, userC)); err != nil {
+			t.Fatalf("DeleteByUserID: %v", err)
+		}
+		if err := repo.DeleteByUserID(tenantCtx(t), parseUUID(t, userC)); err != nil {
+			t.Fatalf("DeleteByUserID without records: %v", err)
+		}
+
+		all, err := repo.GetAll(tenantCtx(t))
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		assertCount(t, "GetAll after deletes", all, 0)
+	})
+
+	t.Run("GetSummary", func(t *testing.T) {
+		repo := newRepo(t)
+		end := month(t, "03-2025")
+		mustInsert(t, repo, newSub(t, "Netflix", 500, userA, "01-2025", &end))
+		mustInsert(t, repo, newSub(t, "Spotify", 300, userA, "02-2025", nil))
+		mustInsert(t, repo, newSub(t, "Netflix", 400, userB, "06-2025", nil))
+
+		from, to := month(t, "01-2025"), month(t, "04-2025")
+
+		subs, total, err := repo.GetSummary(tenantCtx(t), from, to, models.SubscriptionFilter{})
+		if err != nil {
+			t.Fatalf("GetSummary: %v", err)
+		}
+		if total != 3*500+3*300 || len(subs) != 2 {
+			t.Fatalf("GetSummary: want total %d over 2 records, got %d over %d", 3*500+3*300, total, len(subs))
+		}
+
+		_, total, err = repo.GetSummary(tenantCtx(t), from, month(t, "12-2025"), models.SubscriptionFilter{UserID: userB, ServiceName: "Netflix"})
+		if err != nil {
+			t.Fatalf("GetSummary by user and service: %v", err)
+		}
+		if total != 7*400 {
+			t.Fatalf("GetSummary by user and service: want total %d, got %d", 7*400, total)
+		}
+
+		if _, _, err := repo.GetSummary(tenantCtx(t), from, to, models.SubscriptionFilter{UserID: "not-a-uuid"}); err == nil {
+			t.Fatal("GetSummary with invalid user_id: want error, got nil")
+		}
+	})
+}
+
+// tenantCtx — контекст проверки от имени организации по умолчанию.
+func tenantCtx(t *testing.T) context.Context {
+	return models.WithOrganization(t.Context(), models.DefaultOrganizationID)
+}
+
+func newSub(t *testing.T, service string, price int, user, start string, end *time.Time) *models.Subscription {
+	t.Helper()
+	return &models.Subscription{
+		ServiceName: service,
+		Price:       price,
+		UserID:      parseUUID(t, user),
+		StartDate:   month(t, start),
+		EndDate:     end,
+	}
+}
+
+func mustInsert(t *testing.T, repo models.SubscriptionRepository, sub *models.Subscription) {
+	t.Helper()
+	if err := repo.Insert(tenantCtx(t), sub); err != nil {
+		t.Fatalf("Insert %s for %s: %v", sub.ServiceName, sub.UserID.UUID, err)
+	}
+}
+
+func month(t *testing.T, s string) time.Time {
+	t.Helper()
+	m, err := time.Parse("01-2006", s)
+	if err != nil {
+		t.Fatalf("parse month %q: %v", s, err)
+	}
+	return m
+}
+
+func parseUUID(t *testing.T, s string) uuid.UUID {
+	t.Helper()
+	var id uuid.UUID
+	if err := id.Scan(s); err != nil {
+		t.Fatalf("parse uuid %q: %v", s, err)
+	}
+	return id
+}
+
+func assertCount(t *testing.T, what string, subs []*models.Subscription, want int) {
+	t.Helper()
+	if len(subs) != want {
+		t.Fatalf("%s: want %d records, got %d", what, want, len(subs))
+	}
+}
+
+func assertEqualSub(t *testing.T, got, want *models.Subscription) {
+	t.Helper()
+	if got.ID != want.ID || got.ServiceName != want.ServiceName || got.Price != want.Price || got.UserID.UUID != want.UserID.UUID {
+		t.Fatalf("record mismatch: want %+v, got %+v", want, got)
+	}
+	if !got.StartDate.Equal(want.StartDate) {
+		t.Fatalf("start_date mismatch: want %s, got %s", want.StartDate, got.StartDate)
+	}
+	if (got.EndDate == nil) != (want.EndDate == nil) || (got.EndDate != nil && !got.EndDate.Equal(*want.EndDate)) {
+		t.Fatalf("end_date mismatch: want %v, got %v", want.EndDate, got.EndDate)
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// ErrDuplicateOrganization — организация с таким slug уже есть.
+var ErrDuplicateOrganization = errors.New("organization with this slug already exists")
+
+var organizationSlug = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Organization — арендатор сервиса: клиентская компания со своими
+// подписками и API-ключами.
+type Organization struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidOrganizationSlug проверяет slug: строчные латинские буквы, цифры и
+// дефис, не длиннее 63 символов.
+func ValidOrganizationSlug(slug string) error {
+	if !organizationSlug.MatchString(slug) {
+		return errors.New("slug must be 1-63 lowercase letters, digits or dashes and start with a letter or digit")
+	}
+	return nil
+}
+
+type OrganizationDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+func (m *OrganizationDB) GetAll(ctx context.Context) ([]*Organization, error) {
+	query := `SELECT id, slug, name, created_at FROM {organizations} ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, m.Tables.sql(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizations := []*Organization{}
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Slug, &o.Name, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
+
+func (m *OrganizationDB) Get(ctx context.Context, id int) (*Organization, error) {
+	query := `SELECT id, slug, name, created_at FROM {organizations} WHERE id = $1`
+
+	var o Organization
+	err := m.DB.QueryRowContext(ctx, m.Tables.sql(query), id).Scan(&o.ID, &o.Slug, &o.Name, &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+//********************************************************************//
+//  							 INSERT								  //
+//********************************************************************//
+
+func (m *OrganizationDB) Insert(ctx context.Context, o *Organization) error {
+	query := `INSERT INTO {organizations} (slug, name) VALUES ($1, $2) RETURNING id, created_at`
+
+	err := uniqueViolation(m.DB.QueryRowContext(ctx, m.Tables.sql(query), o.Slug, o.Name).Scan(&o.ID, &o.CreatedAt))
+	if errors.Is(err, ErrDuplicateSubscription) {
+		return ErrDuplicateOrganization
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"database/sql"
+	"os"
+	"subscription-service/internal/config"
+	"subscription-service/internal/migrations"
+	"subscription-service/internal/models"
+	"subscription-service/internal/models/modelstest"
+	"testing"
+
+	_ "github.com/jackc/pgx/v4/stdlib"
+)
+
+// postgresDSNEnv — переменная со строкой подключения к Postgres для
+// контрактных проверок SubscriptionDB. Без неё проверки пропускаются.
+// Таблицы создаются миграциями в отдельной схеме contract_test.
+const postgresDSNEnv = "SUBSCRIPTIONS_TEST_POSTGRES_DSN"
+
+func TestSubscriptionDB(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.Default().DB
+	cfg.Schema = "contract_test"
+	if err := migrations.Up(t.Context(), db, cfg); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	tables := migrations.Tables(cfg)
+
+	// Вторая организация для проверок изоляции; RLS на organizations нет.
+	_, err = db.ExecContext(t.Context(), `INSERT INTO `+tables.Table("organizations")+` (id, slug, name)
+              VALUES ($1, 'contract-other', 'Contract other') ON CONFLICT (id) DO NOTHING`, modelstest.OtherOrganizationID)
+	if err != nil {
+		t.Fatalf("create organization %d: %v", modelstest.OtherOrganizationID, err)
+	}
+
+	modelstest.TestSubscriptionRepository(t, func(t *testing.T) models.SubscriptionRepository {
+		// TRUNCATE не проходит через политики RLS и очищает подписки всех
+		// организаций вместе с зависимыми таблицами.
+		if _, err := db.ExecContext(t.Context(), `TRUNCATE `+tables.Table("subscriptions")+` CASCADE`); err != nil {
+			t.Fatalf("truncate subscriptions: %v", err)
+		}
+		return &models.SubscriptionDB{DB: db, Tables: tables}
+	})
+}
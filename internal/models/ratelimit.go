@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit — параметры token bucket: ведро на Burst токенов, которое
+// пополняется со скоростью Rate токенов в секунду.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitResult — состояние ведра после попытки взять токен.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter — через сколько появится следующий токен, если запрос отклонён.
+	RetryAfter time.Duration
+	// Reset — через сколько ведро снова будет полным.
+	Reset time.Duration
+}
+
+// RateLimitStore хранит ведра по ключу вызывающего. Take атомарно
+// пополняет ведро на момент now и забирает один токен, если он есть.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// bucketResult считает ответ по числу токенов после попытки.
+func bucketResult(allowed bool, tokens float64, limit RateLimit) RateLimitResult {
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Max(s, 0) * float64(time.Second))
+}
+
+//********************************************************************//
+//  							 MEMORY								  //
+//********************************************************************//
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryRateLimitStore держит ведра в памяти процесса: лимиты действуют
+// в пределах одной реплики.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+func (m *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+math.Max(elapsed, 0)*limit.Rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketResult(allowed, b.tokens, limit), nil
+}
+
+// sweep раз в минуту выбрасывает ведра, которые не трогали час: к этому
+// времени они гарантированно полные и ничем не отличаются от новых.
+func (m *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) > time.Hour {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+//********************************************************************//
+//  							 POSTGRES							  //
+//********************************************************************//
+
+// RateLimitDB хранит ведра в Postgres, так что лимит общий для всех реплик.
+type RateLimitDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+// Take пополняет и списывает ведро одним UPSERT: строка блокируется на
+// время оператора, поэтому параллельные реплики не теряют списания.
+func (m *RateLimitDB) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	query := `INSERT INTO {rate_limits} AS rl (key, tokens, allowed, updated_at)
+              VALUES ($1, $2::float8 - 1, TRUE, $4::timestamptz)
+              ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+                  SELECT CASE WHEN refill.tokens >= 1 THEN refill.tokens - 1 ELSE refill.tokens END,
+                         refill.tokens >= 1,
+                         $4::timestamptz
+                  FROM (SELECT LEAST($2::float8, rl.tokens + GREATEST(EXTRACT(EPOCH FROM ($4::timestamptz - rl.updated_at)), 0) * $3::float8) AS tokens) AS refill
+              )
+              RETURNING tokens, allowed`
+
+	var tokens float64
+	var allowed bool
+	err := m.DB.QueryRowContext(ctx, m.Tables.sql(query), key, limit.Burst, limit.Rate, now).Scan(&tokens, &allowed)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return bucketResult(allowed, tokens, limit), nil
+}
+
+// Prune удаляет ведра, которые не трогали с before.
+func (m *RateLimitDB) Prune(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM {rate_limits} WHERE updated_at < $1`
+
+	res, err := m.DB.ExecContext(ctx, m.Tables.sql(query), before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
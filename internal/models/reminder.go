@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// ReminderRepository — журнал отправленных напоминаний и адреса
+// пользователей для них. Claim и Release работают только с подписками
+// арендатора из ctx.
+type ReminderRepository interface {
+	Claim(ctx context.Context, subscriptionID int, chargeDate time.Time, channel string) (bool, error)
+	Release(ctx context.Context, subscriptionID int, chargeDate time.Time, channel string) error
+	ContactEmail(ctx context.Context, uid uuid.UUID) (string, error)
+	SetContactEmail(ctx context.Context, uid uuid.UUID, email string) error
+}
+
+type ReminderDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+type Reminder struct {
+	ID             int       `json:"id"`
+	SubscriptionID int       `json:"subscription_id"`
+	ChargeDate     time.Time `json:"charge_date"`
+	Channel        string    `json:"channel"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// Claim записывает напоминание до отправки. Возвращает false, если
+// напоминание по этому каналу за эту дату списания уже было отправлено
+// или подписка не видна арендатору из ctx.
+func (m *ReminderDB) Claim(ctx context.Context, subscriptionID int, chargeDate time.Time, channel string) (bool, error) {
+	query := `INSERT INTO {reminders} (subscription_id, organization_id, charge_date, channel)
+              SELECT s.id, s.organization_id, $2, $3 FROM {subscriptions} s
+              WHERE s.id = $1 AND ` + tenantScope + `
+              ON CONFLICT (subscription_id, charge_date, channel) DO NOTHING
+              RETURNING id`
+
+	claimed := false
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		var id int
+		err := tx.QueryRowContext(ctx, m.Tables.sql(query), subscriptionID, chargeDate, channel).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+// Release удаляет запись о напоминании, если отправить его не удалось.
+func (m *ReminderDB) Release(ctx context.Context, subscriptionID int, chargeDate time.Time, channel string) error {
+	query := `DELETE FROM {reminders} r
+              WHERE r.subscription_id = $1 AND r.charge_date = $2 AND r.channel = $3
+                AND {tenant_visible}(r.organization_id)`
+
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, m.Tables.sql(query), subscriptionID, chargeDate, channel)
+		return err
+	})
+}
+
+// ContactEmail возвращает адрес пользователя в организации арендатора из
+// ctx. Арендатор должен быть одной организацией: у одного user_id в
+// разных организациях свои адреса.
+func (m *ReminderDB) ContactEmail(ctx context.Context, uid uuid.UUID) (string, error) {
+	query := `SELECT c.email FROM {user_contacts} c
+              WHERE c.organization_id = $1 AND c.user_id = $2 AND {tenant_visible}(c.organization_id)`
+
+	org, err := insertOrganization(ctx, 0)
+	if err != nil {
+		return "", err
+	}
+
+	var email string
+	err = inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, m.Tables.sql(query), org, uid).Scan(&email)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+// SetContactEmail сохраняет адрес для напоминаний пользователя в
+// организации арендатора из ctx.
+func (m *ReminderDB) SetContactEmail(ctx context.Context, uid uuid.UUID, email string) error {
+	query := `INSERT INTO {user_contacts} (organization_id, user_id, email) VALUES ($1, $2, $3)
+              ON CONFLICT (organization_id, user_id) DO UPDATE SET email = EXCLUDED.email`
+
+	org, err := insertOrganization(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, m.Tables.sql(query), org, uid, email)
+		return err
+	})
+}
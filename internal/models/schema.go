@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion — версия миграций, под которую собран этот бинарник.
+// Увеличивается вместе с каждой новой миграцией.
+const SchemaVersion = 14
+
+type SchemaDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+// Version возвращает применённую версию миграций из таблицы golang-migrate.
+func (m *SchemaDB) Version(ctx context.Context) (uint, bool, error) {
+	query := `SELECT version, dirty FROM ` + m.Tables.Migrations + ` LIMIT 1`
+
+	var version uint
+	var dirty bool
+	err := m.DB.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, err
+	}
+	return version, dirty, nil
+}
+
+func (m *SchemaDB) Ping(ctx context.Context) error {
+	return m.DB.PingContext(ctx)
+}
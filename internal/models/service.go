@@ -0,0 +1,261 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgtype"
+)
+
+// ErrDuplicateService — каноническое имя или один из псевдонимов уже
+// принадлежит другому сервису.
+var ErrDuplicateService = errors.New("service name or alias already belongs to another service")
+
+// Service — запись каталога сервисов. Подписки ссылаются на сервис по
+// каноническому имени Name; Aliases — написания, которые к нему сводятся.
+type Service struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases"`
+	Category     string   `json:"category"`
+	DefaultPrice *int     `json:"default_price,omitempty"`
+	Website      string   `json:"website,omitempty"`
+}
+
+// NormalizeServiceName приводит имя к виду, по которому ищутся псевдонимы:
+// нижний регистр, без пробелов по краям и без повторных пробелов внутри.
+func NormalizeServiceName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ServiceCategories сопоставляет нормализованные имена и псевдонимы
+// сервисов их категориям.
+type ServiceCategories map[string]string
+
+// Of возвращает категорию сервиса из каталога. Для сервиса, которого нет в
+// каталоге, категорией считается само имя — так бюджеты, заведённые до
+// каталога на имя сервиса, продолжают работать.
+func (sc ServiceCategories) Of(serviceName string) string {
+	key := NormalizeServiceName(serviceName)
+	if category, ok := sc[key]; ok {
+		return category
+	}
+	return key
+}
+
+type ServiceDB struct {
+	DB     *sql.DB
+	Tables Tables
+}
+
+//********************************************************************//
+//  							 READ								  //
+//********************************************************************//
+
+const serviceColumns = `s.id, s.name, s.category, s.default_price, s.website,
+                        COALESCE(ARRAY(SELECT a.alias FROM {service_aliases} a WHERE a.service_id = s.id ORDER BY a.alias), '{}')`
+
+func (m *ServiceDB) GetAll(ctx context.Context) ([]*Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM {services} s ORDER BY s.name`
+
+	rows, err := m.DB.QueryContext(ctx, m.Tables.sql(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	services := []*Service{}
+	for rows.Next() {
+		s, err := scanService(rows)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (m *ServiceDB) Get(ctx context.Context, id int) (*Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM {services} s WHERE s.id = $1`
+
+	return scanService(m.DB.QueryRowContext(ctx, m.Tables.sql(query), id))
+}
+
+// Resolve находит сервис по каноническому имени или псевдониму в любом
+// регистре и с любыми пробелами. Неизвестное имя — sql.ErrNoRows.
+func (m *ServiceDB) Resolve(ctx context.Context, name string) (*Service, error) {
+	query := `SELECT ` + serviceColumns + `
+              FROM {services} s
+              JOIN {service_aliases} al ON al.service_id = s.id
+              WHERE al.alias = $1`
+
+	return scanService(m.DB.QueryRowContext(ctx, m.Tables.sql(query), NormalizeServiceName(name)))
+}
+
+// Categories возвращает категории всех сервисов каталога по их
+// нормализованным именам и псевдонимам. Сервис без категории, как и
+// сервис вне каталога, считается категорией из одного себя.
+func (m *ServiceDB) Categories(ctx context.Context) (ServiceCategories, error) {
+	query := `SELECT a.alias, COALESCE(NULLIF(s.category, ''), lower(s.name))
+              FROM {service_aliases} a JOIN {services} s ON s.id = a.service_id`
+
+	rows, err := m.DB.QueryContext(ctx, m.Tables.sql(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := ServiceCategories{}
+	for rows.Next() {
+		var alias, category string
+		if err := rows.Scan(&alias, &category); err != nil {
+			return nil, err
+		}
+		categories[alias] = category
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+//********************************************************************//
+//  							 INSERT								  //
+//********************************************************************//
+
+// Insert добавляет сервис вместе с псевдонимами. Нормализованное
+// каноническое имя всегда становится одним из псевдонимов.
+func (m *ServiceDB) Insert(ctx context.Context, s *Service) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO {services} (name, category, default_price, website) VALUES ($1, $2, $3, $4) RETURNING id`
+	err = tx.QueryRowContext(ctx, m.Tables.sql(query), s.Name, s.Category, s.DefaultPrice, s.Website).Scan(&s.ID)
+	if err != nil {
+		return serviceUniqueViolation(err)
+	}
+
+	if err := m.setAliases(ctx, tx, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+//********************************************************************//
+//  							 UPDATE								  //
+//********************************************************************//
+
+// Update меняет сервис и заменяет его псевдонимы. При переименовании
+// подписки всех организаций переводятся на новое имя, а старое остаётся
+// псевдонимом.
+func (m *ServiceDB) Update(ctx context.Context, s *Service) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldName string
+	query := `SELECT name FROM {services} WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, m.Tables.sql(query), s.ID).Scan(&oldName); err != nil {
+		return err
+	}
+
+	query = `UPDATE {services} SET name = $1, category = $2, default_price = $3, website = $4 WHERE id = $5`
+	if _, err := tx.ExecContext(ctx, m.Tables.sql(query), s.Name, s.Category, s.DefaultPrice, s.Website, s.ID); err != nil {
+		return serviceUniqueViolation(err)
+	}
+
+	if oldName != s.Name {
+		// Каталог общий для всех организаций, и переименование касается
+		// подписок каждой из них.
+		if err := setTenant(WithAllOrganizations(ctx), tx); err != nil {
+			return err
+		}
+		query = `UPDATE {subscriptions} SET service_name = $1 WHERE service_name = $2`
+		if _, err := tx.ExecContext(ctx, m.Tables.sql(query), s.Name, oldName); err != nil {
+			return uniqueViolation(err)
+		}
+		s.Aliases = append(s.Aliases, oldName)
+	}
+
+	query = `DELETE FROM {service_aliases} WHERE service_id = $1`
+	if _, err := tx.ExecContext(ctx, m.Tables.sql(query), s.ID); err != nil {
+		return err
+	}
+	if err := m.setAliases(ctx, tx, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *ServiceDB) setAliases(ctx context.Context, tx *sql.Tx, s *Service) error {
+	aliases := []string{NormalizeServiceName(s.Name)}
+	for _, a := range s.Aliases {
+		if a = NormalizeServiceName(a); a != "" && !slices.Contains(aliases, a) {
+			aliases = append(aliases, a)
+		}
+	}
+	slices.Sort(aliases)
+
+	query := `INSERT INTO {service_aliases} (alias, service_id) VALUES ($1, $2)`
+	for _, a := range aliases {
+		if _, err := tx.ExecContext(ctx, m.Tables.sql(query), a, s.ID); err != nil {
+			return serviceUniqueViolation(err)
+		}
+	}
+	s.Aliases = aliases
+	return nil
+}
+
+//********************************************************************//
+//  							 DELETE								  //
+//********************************************************************//
+
+// Delete удаляет сервис из каталога. Подписки остаются со своим именем
+// и просто перестают сводиться к каталогу.
+func (m *ServiceDB) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM {services} WHERE id = $1`
+
+	res, err := m.DB.ExecContext(ctx, m.Tables.sql(query), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanService(row rowScanner) (*Service, error) {
+	var s Service
+	var aliases pgtype.TextArray
+	err := row.Scan(&s.ID, &s.Name, &s.Category, &s.DefaultPrice, &s.Website, &aliases)
+	if err != nil {
+		return nil, err
+	}
+	if err := aliases.AssignTo(&s.Aliases); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func serviceUniqueViolation(err error) error {
+	if errors.Is(uniqueViolation(err), ErrDuplicateSubscription) {
+		return ErrDuplicateService
+	}
+	return err
+}
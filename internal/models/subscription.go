@@ -3,14 +3,94 @@ package models
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
 	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var tracer = otel.Tracer("subscription-service/internal/models")
+
+// ErrDuplicateSubscription — у пользователя уже есть подписка на этот
+// сервис в этой организации (ограничение unique_service_user).
+var ErrDuplicateSubscription = errors.New("subscription for this service and user already exists")
+
+// ErrUnknownCategory — category_id подписки не найден среди категорий.
+var ErrUnknownCategory = errors.New("category does not exist")
+
+// SubscriptionRepository — хранилище подписок. Отсутствующие записи
+// обозначаются sql.ErrNoRows, списки упорядочены по user_id, service_name.
+// Каждый метод видит только подписки арендатора из ctx (WithOrganization);
+// без арендатора возвращается ErrNoOrganization.
+type SubscriptionRepository interface {
+	GetAll(ctx context.Context) ([]*Subscription, error)
+	Get(ctx context.Context, id int) (*Subscription, error)
+	GetByUserID(ctx context.Context, uid uuid.UUID) ([]*Subscription, error)
+	GetByUserSubscription(ctx context.Context, serviceName string) ([]*Subscription, error)
+	GetActive(ctx context.Context, at time.Time) ([]*Subscription, error)
+	Insert(ctx context.Context, sub *Subscription) error
+	Delete(ctx context.Context, id int) error
+	DeleteByUserID(ctx context.Context, uid uuid.UUID) error
+	DeleteByServiceName(ctx context.Context, serviceName string) error
+	Update(ctx context.Context, upd Subscription) error
+	Find(ctx context.Context, filter SubscriptionFilter) ([]*Subscription, error)
+	SetMembers(ctx context.Context, id int, members []SubscriptionMember) error
+	GetSummary(ctx context.Context, from, to time.Time, filter SubscriptionFilter) ([]*SubscriptionWithCost, int, error)
+	UserIDs(ctx context.Context, limit int) ([]uuid.UUID, error)
+}
+
+// SubscriptionFilter отбирает подписки для Find и GetSummary. Пустые поля
+// не ограничивают выборку; из Tags подписка должна иметь все. UserID
+// совпадает и с плательщиком, и с участником совместной подписки.
+// UserIDs и ServiceNames отбирают подписки любого из перечисленных
+// пользователей или сервисов — так данные для нескольких из них
+// загружаются одним запросом. Limit оставляет не больше Limit первых по
+// порядку подписок; 0 — без ограничения.
+type SubscriptionFilter struct {
+	UserID       string
+	ServiceName  string
+	Tags         []string
+	UserIDs      []string
+	ServiceNames []string
+	Limit        int
+}
+
+// user разбирает UserID; nil, если фильтр по пользователю не задан.
+func (f SubscriptionFilter) user() (*uuid.UUID, error) {
+	if f.UserID == "" {
+		return nil, nil
+	}
+	var uid uuid.UUID
+	if err := uid.Scan(f.UserID); err != nil {
+		return nil, fmt.Errorf("invalid user_id: %w", err)
+	}
+	return &uid, nil
+}
+
+// users разбирает UserIDs.
+func (f SubscriptionFilter) users() ([]uuid.UUID, error) {
+	uids := make([]uuid.UUID, 0, len(f.UserIDs))
+	for _, id := range f.UserIDs {
+		var uid uuid.UUID
+		if err := uid.Scan(id); err != nil {
+			return nil, fmt.Errorf("invalid user_id %q: %w", id, err)
+		}
+		uids = append(uids, uid)
+	}
+	return uids, nil
+}
+
 type SubscriptionDB struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Tables Tables
 }
 type Subscription struct {
 	ID          int        `json:"id"`
@@ -19,301 +99,463 @@ type Subscription struct {
 	UserID      uuid.UUID  `json:"user_id"`
 	StartDate   time.Time  `json:"start_date"`
 	EndDate     *time.Time `json:"end_date,omitempty"`
+	CategoryID  *int       `json:"category_id,omitempty"`
+	Tags        []string   `json:"tags"`
+	// Members — участники совместной подписки; пусто, если платит и
+	// пользуется один UserID. Меняются только через SetMembers.
+	Members []SubscriptionMember `json:"members,omitempty"`
+	// OrganizationID задаётся арендатором из контекста при Insert и
+	// после этого не меняется.
+	OrganizationID int `json:"organization_id"`
 }
 type SubscriptionWithCost struct {
 	Subscription
 	DateFrom time.Time `json:"date_from"`
 	DateTo   time.Time `json:"date_to"`
 	Cost     int       `json:"сost"`
+	// FullCost — стоимость подписки целиком, если в Cost только доля
+	// пользователя из фильтра сводки.
+	FullCost int `json:"full_cost,omitempty"`
 }
 
 //********************************************************************//
 //  							 READ								  //
 //********************************************************************//
 
-func (m *SubscriptionDB) GetAll() ([]*Subscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	query := `SELECT * FROM infosub.subscriptions  ORDER BY user_id, service_name`
-
-	rows, err := m.DB.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
+// subscriptionColumns — столбцы подписки s в порядке scanSubscription.
+const subscriptionColumns = `s.id, s.service_name, s.price, s.user_id, s.start_date, s.end_date, s.category_id, s.tags, s.organization_id,
+                             COALESCE((SELECT json_agg(json_build_object('user_id', sm.user_id, 'weight', sm.weight) ORDER BY sm.user_id)
+                                       FROM {subscription_members} sm WHERE sm.subscription_id = s.id), '[]')`
 
-	defer rows.Close()
+func (m *SubscriptionDB) GetAll(ctx context.Context) ([]*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM {subscriptions} s WHERE ` + tenantScope + ` ORDER BY s.user_id, s.service_name`
 
-	subscriptions := []*Subscription{}
-	for rows.Next() {
-		var sub Subscription
-		err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate)
-		if err != nil {
-			return nil, err
-		}
+	return m.query(ctx, query)
+}
 
-		subscriptions = append(subscriptions, &sub)
-	}
+func (m *SubscriptionDB) Get(ctx context.Context, id int) (*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM {subscriptions} s WHERE s.id = $1 AND ` + tenantScope
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return subscriptions, nil
+	var sub *Subscription
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		var err error
+		sub, err = scanSubscription(tx.QueryRowContext(ctx, m.Tables.sql(query), id))
+		return err
+	})
+	return sub, err
 }
 
-func (m *SubscriptionDB) Get(id int) (*Subscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (m *SubscriptionDB) GetByUserID(ctx context.Context, uid uuid.UUID) ([]*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM {subscriptions} s WHERE s.user_id = $1 AND ` + tenantScope + ` ORDER BY s.user_id, s.service_name`
 
-	query := `SELECT * FROM infosub.subscriptions WHERE id = $1  ORDER BY user_id, service_name`
+	return m.query(ctx, query, uid)
+}
 
-	var sub Subscription
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate)
-	if err != nil {
-		return nil, err
-	}
+func (m *SubscriptionDB) GetByUserSubscription(ctx context.Context, serviceName string) ([]*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM {subscriptions} s WHERE s.service_name = $1 AND ` + tenantScope + ` ORDER BY s.user_id, s.service_name`
 
-	return &sub, nil
+	return m.query(ctx, query, serviceName)
 }
 
-func (m *SubscriptionDB) GetByUserID(uid uuid.UUID) ([]*Subscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (m *SubscriptionDB) GetActive(ctx context.Context, at time.Time) ([]*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM {subscriptions} s WHERE (s.end_date IS NULL OR s.end_date >= $1) AND ` + tenantScope + ` ORDER BY s.user_id, s.service_name`
 
-	query := `SELECT * FROM infosub.subscriptions WHERE user_id = $1  ORDER BY user_id, service_name`
+	return m.query(ctx, query, at)
+}
 
-	rows, err := m.DB.QueryContext(ctx, query, uid)
+func (m *SubscriptionDB) Find(ctx context.Context, filter SubscriptionFilter) ([]*Subscription, error) {
+	query, args, err := subQuery(filter)
 	if err != nil {
 		return nil, err
 	}
-
-	defer rows.Close()
-
-	subscriptions := []*Subscription{}
-	for rows.Next() {
-		var sub Subscription
-		err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate)
-		if err != nil {
-			return nil, err
-		}
-
-		subscriptions = append(subscriptions, &sub)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return subscriptions, nil
+	return m.query(ctx, query, args...)
 }
 
-func (m *SubscriptionDB) GetByUserSubscription(serviceName string) ([]*Subscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	//log.Println(serviceName)
-
-	query := `SELECT id, service_name, price, user_id, start_date, end_date
-              FROM infosub.subscriptions
-              WHERE service_name = $1 
-              ORDER BY user_id, service_name`
-
-	rows, err := m.DB.QueryContext(ctx, query, serviceName)
-	if err != nil {
-		return nil, err
+// UserIDs возвращает плательщиков и участников подписок арендатора по
+// возрастанию UUID, не больше limit; 0 — всех.
+func (m *SubscriptionDB) UserIDs(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	query := `SELECT u.user_id FROM (
+                  SELECT s.user_id FROM {subscriptions} s WHERE ` + tenantScope + `
+                  UNION
+                  SELECT sm.user_id FROM {subscription_members} sm
+                  JOIN {subscriptions} s ON s.id = sm.subscription_id
+                  WHERE ` + tenantScope + `
+              ) u ORDER BY u.user_id LIMIT $1`
+
+	// LIMIT NULL в Postgres — без ограничения.
+	var rowLimit any
+	if limit > 0 {
+		rowLimit = limit
 	}
-	defer rows.Close()
 
-	subscriptions := []*Subscription{}
-	for rows.Next() {
-		var sub Subscription
-		var endDate sql.NullTime
-
-		err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &endDate)
+	uids := []uuid.UUID{}
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, m.Tables.sql(query), rowLimit)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		if endDate.Valid {
-			sub.EndDate = &endDate.Time
-		} else {
-			sub.EndDate = nil
+		defer rows.Close()
+
+		for rows.Next() {
+			var uid uuid.UUID
+			if err := rows.Scan(&uid); err != nil {
+				return err
+			}
+			uids = append(uids, uid)
 		}
-
-		subscriptions = append(subscriptions, &sub)
-	}
-
-	if err := rows.Err(); err != nil {
+		return rows.Err()
+	})
+	if err != nil {
 		return nil, err
 	}
-	return subscriptions, nil
+	return uids, nil
 }
 
 //********************************************************************//
 //  							 CREATE								  //
 //********************************************************************//
 
-func (m *SubscriptionDB) Insert(sub *Subscription) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// Insert добавляет подписку в организацию арендатора из ctx. При доступе
+// ко всем организациям она должна быть указана в sub.OrganizationID.
+func (m *SubscriptionDB) Insert(ctx context.Context, sub *Subscription) error {
+	query := `INSERT INTO {subscriptions} (service_name, price, user_id, start_date, end_date, category_id, tags, organization_id)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
 
-	query := `INSERT INTO infosub.subscriptions (service_name, price, user_id, start_date, end_date) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	org, err := insertOrganization(ctx, sub.OrganizationID)
+	if err != nil {
+		return err
+	}
+	sub.Tags = NormalizeTags(sub.Tags)
+	var tags pgtype.TextArray
+	if err := tags.Set(sub.Tags); err != nil {
+		return err
+	}
 
-	return m.DB.QueryRowContext(ctx, query, sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate).Scan(&sub.ID)
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, m.Tables.sql(query), sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate, sub.CategoryID, tags, org).Scan(&sub.ID)
+		if err != nil {
+			return constraintViolation(err, m.Tables)
+		}
+		sub.OrganizationID = org
+		return m.insertMembers(ctx, tx, sub.ID, sub.Members)
+	})
 }
 
 //********************************************************************//
 //  							 DELETE								  //
 //********************************************************************//
 
-func (m *SubscriptionDB) Delete(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := m.Get(id)
-	if err != nil {
-		return err
-	}
-
-	query := `DELETE FROM infosub.subscriptions WHERE id = $1`
-	_, err = m.DB.ExecContext(ctx, query, id)
-	return err
+func (m *SubscriptionDB) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM {subscriptions} s WHERE s.id = $1 AND ` + tenantScope
+	return m.exec(ctx, true, query, id)
 }
 
-func (m *SubscriptionDB) DeleteByUserID(uid uuid.UUID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	query := `DELETE FROM infosub.subscriptions WHERE user_id = $1`
-	_, err := m.DB.ExecContext(ctx, query, uid)
-	return err
+func (m *SubscriptionDB) DeleteByUserID(ctx context.Context, uid uuid.UUID) error {
+	query := `DELETE FROM {subscriptions} s WHERE s.user_id = $1 AND ` + tenantScope
+	return m.exec(ctx, false, query, uid)
 }
 
-func (m *SubscriptionDB) DeleteByServiceName(serviceName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	query := `DELETE FROM infosub.subscriptions WHERE service_name = $1`
-	_, err := m.DB.ExecContext(ctx, query, serviceName)
-	return err
+func (m *SubscriptionDB) DeleteByServiceName(ctx context.Context, serviceName string) error {
+	query := `DELETE FROM {subscriptions} s WHERE s.service_name = $1 AND ` + tenantScope
+	return m.exec(ctx, false, query, serviceName)
 }
 
 //********************************************************************//
 //  							 UPDATE								  //
 //********************************************************************//
 
-func (m *SubscriptionDB) Update(upd Subscription) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := m.Get(upd.ID)
-	if err != nil {
+func (m *SubscriptionDB) Update(ctx context.Context, upd Subscription) error {
+	var tags pgtype.TextArray
+	if err := tags.Set(NormalizeTags(upd.Tags)); err != nil {
 		return err
 	}
 
-	query := `UPDATE infosub.subscriptions SET service_name = $1, price = $2,user_id = $3, start_date = $4, end_date = $5 WHERE id = $6`
-	_, err = m.DB.ExecContext(
-		ctx, query,
+	query := `UPDATE {subscriptions} s
+              SET service_name = $1, price = $2, user_id = $3, start_date = $4, end_date = $5, category_id = $6, tags = $7
+              WHERE s.id = $8 AND ` + tenantScope
+	err := m.exec(
+		ctx, true, query,
 		upd.ServiceName,
 		upd.Price,
 		upd.UserID,
 		upd.StartDate,
 		upd.EndDate,
+		upd.CategoryID,
+		tags,
 		upd.ID,
 	)
-	return err
+	return constraintViolation(err, m.Tables)
+}
+
+// SetMembers заменяет участников совместной подписки. Пустой список
+// возвращает всю стоимость плательщику.
+func (m *SubscriptionDB) SetMembers(ctx context.Context, id int, members []SubscriptionMember) error {
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		var locked int
+		query := `SELECT s.id FROM {subscriptions} s WHERE s.id = $1 AND ` + tenantScope + ` FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, m.Tables.sql(query), id).Scan(&locked); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM {subscription_members} WHERE subscription_id = $1`
+		if _, err := tx.ExecContext(ctx, m.Tables.sql(query), id); err != nil {
+			return err
+		}
+		return m.insertMembers(ctx, tx, id, members)
+	})
+}
+
+func (m *SubscriptionDB) insertMembers(ctx context.Context, tx *sql.Tx, id int, members []SubscriptionMember) error {
+	query := `INSERT INTO {subscription_members} (subscription_id, user_id, weight) VALUES ($1, $2, $3)`
+	for _, member := range members {
+		if _, err := tx.ExecContext(ctx, m.Tables.sql(query), id, member.UserID, member.Weight); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 //********************************************************************//
 //  							 FILTER								  //
 //********************************************************************//
 
-func (m *SubscriptionDB) GetSummary(from, to time.Time, userID, serviceName string) ([]*SubscriptionWithCost, int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	query, args, err := subQuery(userID, serviceName)
+func (m *SubscriptionDB) GetSummary(ctx context.Context, from, to time.Time, filter SubscriptionFilter) ([]*SubscriptionWithCost, int, error) {
+	subscriptions, err := m.Find(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	uid, err := filter.user()
 	if err != nil {
 		return nil, 0, err
 	}
-	defer rows.Close()
-
-	subCosts := []*SubscriptionWithCost{}
-	totalcost := 0
 
-	for rows.Next() {
-		var sub Subscription
-		var cost int
+	subCosts, totalcost := Summarize(ctx, subscriptions, from, to, uid)
+	return subCosts, totalcost, nil
+}
 
-		err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate)
-		if err != nil {
-			return nil, 0, err
-		}
+// Summarize считает стоимость каждой подписки за период [from, to].
+// Если задан uid, совместные подписки учитываются долей этого
+// пользователя. Вынесено отдельно, чтобы время расчёта было видно в
+// трассировке отдельно от времени запроса к БД, и чтобы сводку можно было
+// посчитать по уже загруженным подпискам.
+func Summarize(ctx context.Context, subs []*Subscription, from, to time.Time, uid *uuid.UUID) ([]*SubscriptionWithCost, int) {
+	_, span := tracer.Start(ctx, "GetSummary.cost")
+	defer span.End()
 
-		dateFrom := maxTime(sub.StartDate, from)
-		dateTo := minTimePtr(sub.EndDate, to)
+	subCosts := []*SubscriptionWithCost{}
+	totalcost := 0
 
-		if dateFrom.After(dateTo) {
+	for _, sub := range subs {
+		dateFrom, dateTo, cost, ok := overlapCost(sub, from, to)
+		if !ok {
 			continue
 		}
 
-		cost = monthsDiff(dateFrom, dateTo) * sub.Price
-		totalcost += cost
-
 		subCost := SubscriptionWithCost{
-			Subscription: sub,
+			Subscription: *sub,
 			DateFrom:     dateFrom,
 			DateTo:       dateTo,
 			Cost:         cost,
 		}
+		if uid != nil && len(sub.Members) > 0 {
+			subCost.Cost = sub.ShareOf(cost, *uid)
+			subCost.FullCost = cost
+		}
+		totalcost += subCost.Cost
 
 		subCosts = append(subCosts, &subCost)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, 0, err
-	}
-	return subCosts, totalcost, nil
+	span.SetAttributes(
+		attribute.Int("subscriptions.scanned", len(subs)),
+		attribute.Int("subscriptions.matched", len(subCosts)),
+		attribute.Int("summary.total_cost", totalcost),
+	)
+	return subCosts, totalcost
 }
 
 //********************************************************************//
 //  							 HELPERS							  //
 //********************************************************************//
 
-func subQuery(userID, serviceName string) (string, []interface{}, error) {
-	var query string
+// subQuery строит выборку подписок по фильтру.
+func subQuery(filter SubscriptionFilter) (string, []interface{}, error) {
+	conds := []string{tenantScope}
 	var args []interface{}
 
-	switch {
-	case userID != "" && serviceName != "":
-		query = `SELECT * FROM infosub.subscriptions WHERE user_id = $1 AND service_name = $2 ORDER BY user_id, service_name`
-		var parsedUUID uuid.UUID
-		if err := parsedUUID.Scan(userID); err != nil {
-			return "", nil, fmt.Errorf("invalid user_id: %w", err)
+	uid, err := filter.user()
+	if err != nil {
+		return "", nil, err
+	}
+	if uid != nil {
+		args = append(args, *uid)
+		conds = append(conds, fmt.Sprintf(`(s.user_id = $%[1]d OR EXISTS (
+                  SELECT 1 FROM {subscription_members} sm WHERE sm.subscription_id = s.id AND sm.user_id = $%[1]d))`, len(args)))
+	}
+	if filter.ServiceName != "" {
+		args = append(args, filter.ServiceName)
+		conds = append(conds, fmt.Sprintf("s.service_name = $%d", len(args)))
+	}
+	uids, err := filter.users()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(uids) > 0 {
+		var arr pgtype.TextArray
+		if err := arr.Set(filter.UserIDs); err != nil {
+			return "", nil, err
+		}
+		args = append(args, arr)
+		conds = append(conds, fmt.Sprintf(`(s.user_id = ANY($%[1]d::uuid[]) OR EXISTS (
+                  SELECT 1 FROM {subscription_members} sm WHERE sm.subscription_id = s.id AND sm.user_id = ANY($%[1]d::uuid[])))`, len(args)))
+	}
+	if len(filter.ServiceNames) > 0 {
+		var arr pgtype.TextArray
+		if err := arr.Set(filter.ServiceNames); err != nil {
+			return "", nil, err
 		}
-		args = append(args, parsedUUID, serviceName)
+		args = append(args, arr)
+		conds = append(conds, fmt.Sprintf("s.service_name = ANY($%d)", len(args)))
+	}
+	if tags := NormalizeTags(filter.Tags); len(tags) > 0 {
+		var arr pgtype.TextArray
+		if err := arr.Set(tags); err != nil {
+			return "", nil, err
+		}
+		args = append(args, arr)
+		conds = append(conds, fmt.Sprintf("s.tags @> $%d", len(args)))
+	}
 
-	case userID == "" && serviceName != "":
-		query = `SELECT * FROM infosub.subscriptions WHERE service_name = $1 ORDER BY user_id, service_name`
-		args = append(args, serviceName)
+	query := `SELECT ` + subscriptionColumns + ` FROM {subscriptions} s WHERE ` + strings.Join(conds, " AND ")
+	query += ` ORDER BY s.user_id, s.service_name`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args, nil
+}
 
-	case userID != "" && serviceName == "":
-		query = `SELECT * FROM infosub.subscriptions WHERE user_id = $1 ORDER BY user_id, service_name`
-		var parsedUUID uuid.UUID
-		if err := parsedUUID.Scan(userID); err != nil {
-			return "", nil, fmt.Errorf("invalid user_id: %w", err)
+func (m *SubscriptionDB) query(ctx context.Context, query string, args ...any) ([]*Subscription, error) {
+	subscriptions := []*Subscription{}
+	err := inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, m.Tables.sql(query), args...)
+		if err != nil {
+			return err
 		}
-		args = append(args, parsedUUID)
+		defer rows.Close()
+
+		for rows.Next() {
+			sub, err := scanSubscription(rows)
+			if err != nil {
+				return err
+			}
+			subscriptions = append(subscriptions, sub)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
 
-	default:
-		query = `SELECT * FROM infosub.subscriptions ORDER BY user_id, service_name`
+// exec выполняет изменение от имени арендатора из ctx. С single запрос
+// должен затронуть строку, иначе — sql.ErrNoRows.
+func (m *SubscriptionDB) exec(ctx context.Context, single bool, query string, args ...any) error {
+	return inTenant(ctx, m.DB, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, m.Tables.sql(query), args...)
+		if err != nil {
+			return err
+		}
+		if !single {
+			return nil
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+func scanSubscription(row rowScanner) (*Subscription, error) {
+	var sub Subscription
+	var tags pgtype.TextArray
+	var members []byte
+	err := row.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CategoryID, &tags, &sub.OrganizationID, &members)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(members, &sub.Members); err != nil {
+		return nil, err
 	}
+	if len(sub.Members) == 0 {
+		sub.Members = nil
+	}
+	if err := tags.AssignTo(&sub.Tags); err != nil {
+		return nil, err
+	}
+	if sub.Tags == nil {
+		sub.Tags = []string{}
+	}
+	return &sub, nil
+}
 
-	return query, args, nil
+// NormalizeTags приводит теги к нижнему регистру без лишних пробелов,
+// убирает пустые и повторы и сортирует.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, t := range tags {
+		if t = strings.ToLower(strings.Join(strings.Fields(t), " ")); t != "" && !slices.Contains(normalized, t) {
+			normalized = append(normalized, t)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized
+}
+
+// overlapCost возвращает пересечение подписки с периодом [from, to]
+// и её стоимость за это пересечение помесячно.
+func overlapCost(sub *Subscription, from, to time.Time) (time.Time, time.Time, int, bool) {
+	dateFrom := maxTime(sub.StartDate, from)
+	dateTo := minTimePtr(sub.EndDate, to)
+
+	if dateFrom.After(dateTo) {
+		return time.Time{}, time.Time{}, 0, false
+	}
+
+	return dateFrom, dateTo, monthsDiff(dateFrom, dateTo) * sub.Price, true
+}
+
+func uniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateSubscription
+	}
+	return err
+}
+
+// categoryForeignKeys — внешние ключи на таблицу категорий. Имена —
+// те, что Postgres выдаёт по умолчанию, без префикса таблиц.
+var categoryForeignKeys = []string{"subscriptions_category_id_fkey", "categories_parent_id_fkey"}
+
+// constraintViolation дополнительно к uniqueViolation переводит нарушение
+// внешнего ключа на категорию в ErrUnknownCategory. Остальные внешние
+// ключи (например, на организацию) возвращаются как есть.
+func constraintViolation(err error, tables Tables) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		for _, name := range categoryForeignKeys {
+			if pgErr.ConstraintName == tables.Ident(name) {
+				return ErrUnknownCategory
+			}
+		}
+	}
+	return uniqueViolation(err)
 }
 
 func maxTime(a, b time.Time) time.Time {
@@ -338,3 +580,39 @@ func monthsDiff(from, to time.Time) int {
 	months := int(to.Month()) - int(from.Month())
 	return years*12 + months + 1
 }
+
+// NextChargeDate возвращает ближайшую дату списания не раньше from.
+// Списание происходит ежемесячно в день start_date; если день отсутствует
+// в месяце, берётся последний день месяца.
+func (s Subscription) NextChargeDate(from time.Time) (time.Time, bool) {
+	from = truncateDay(from)
+	start := truncateDay(s.StartDate)
+
+	charge := start
+	if start.Before(from) {
+		k := monthsDiff(start, from) - 1
+		charge = addMonthsClamped(start, k)
+		if charge.Before(from) {
+			charge = addMonthsClamped(start, k+1)
+		}
+	}
+
+	if s.EndDate != nil && charge.After(truncateDay(*s.EndDate)) {
+		return time.Time{}, false
+	}
+	return charge, true
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
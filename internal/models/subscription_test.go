@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func day(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatalf("parse date %q: %v", s, err)
+	}
+	return d
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	tests := []struct {
+		from   string
+		months int
+		want   string
+	}{
+		{"2025-01-15", 1, "2025-02-15"},
+		{"2025-01-31", 1, "2025-02-28"},
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2025-01-31", 2, "2025-03-31"},
+		{"2025-01-31", 3, "2025-04-30"},
+		{"2025-03-31", -1, "2025-02-28"},
+		{"2025-12-31", 2, "2026-02-28"},
+		{"2025-05-31", 0, "2025-05-31"},
+	}
+	for _, tt := range tests {
+		if got := addMonthsClamped(day(t, tt.from), tt.months); !got.Equal(day(t, tt.want)) {
+			t.Errorf("addMonthsClamped(%s, %d): want %s, got %s", tt.from, tt.months, tt.want, got.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestNextChargeDate(t *testing.T) {
+	end := day(t, "2025-04-15")
+	tests := []struct {
+		name  string
+		start string
+		end   *time.Time
+		from  string
+		want  string
+		ok    bool
+	}{
+		{"before start", "2025-03-10", nil, "2025-01-05", "2025-03-10", true},
+		{"on start", "2025-03-10", nil, "2025-03-10", "2025-03-10", true},
+		{"later this month", "2025-01-10", nil, "2025-03-05", "2025-03-10", true},
+		{"next month", "2025-01-10", nil, "2025-03-11", "2025-04-10", true},
+		{"clamped to february", "2025-01-31", nil, "2025-02-10", "2025-02-28", true},
+		{"clamped to leap february", "2024-01-31", nil, "2024-02-10", "2024-02-29", true},
+		{"on clamped day", "2025-01-31", nil, "2025-02-28", "2025-02-28", true},
+		{"day restored after february", "2025-01-31", nil, "2025-03-01", "2025-03-31", true},
+		{"clamped to 30 days", "2025-01-31", nil, "2025-04-01", "2025-04-30", true},
+		{"across year", "2024-12-31", nil, "2025-01-01", "2025-01-31", true},
+		{"before end", "2025-01-10", &end, "2025-04-01", "2025-04-10", true},
+		{"after end", "2025-01-20", &end, "2025-04-01", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{StartDate: day(t, tt.start), EndDate: tt.end}
+			got, ok := sub.NextChargeDate(day(t, tt.from))
+			if ok != tt.ok {
+				t.Fatalf("NextChargeDate(%s): want ok=%v, got %v (%s)", tt.from, tt.ok, ok, got.Format(time.DateOnly))
+			}
+			if ok && !got.Equal(day(t, tt.want)) {
+				t.Fatalf("NextChargeDate(%s): want %s, got %s", tt.from, tt.want, got.Format(time.DateOnly))
+			}
+		})
+	}
+}
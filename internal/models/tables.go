@@ -0,0 +1,33 @@
+package models
+
+import "regexp"
+
+// Tables описывает, где лежат таблицы сервиса: схема и префикс имён.
+// Значения должны быть проверенными идентификаторами в нижнем регистре —
+// они подставляются в SQL без кавычек.
+type Tables struct {
+	Schema string
+	Prefix string
+	// Migrations — полное имя таблицы версий golang-migrate.
+	Migrations string
+}
+
+// Ident возвращает имя объекта (таблицы, ограничения) с префиксом.
+func (t Tables) Ident(name string) string {
+	return t.Prefix + name
+}
+
+// Table возвращает полное имя таблицы со схемой и префиксом.
+func (t Tables) Table(name string) string {
+	return t.Schema + "." + t.Ident(name)
+}
+
+var tableRef = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// sql подставляет полные имена таблиц в запрос: {subscriptions}
+// превращается в schema.prefix_subscriptions.
+func (t Tables) sql(query string) string {
+	return tableRef.ReplaceAllStringFunc(query, func(ref string) string {
+		return t.Table(ref[1 : len(ref)-1])
+	})
+}
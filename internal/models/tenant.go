@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+// ErrNoOrganization — в контексте не задан арендатор. Запросы к данным
+// подписок без арендатора не выполняются, а не возвращают всё подряд.
+var ErrNoOrganization = errors.New("organization is not set for the request")
+
+// DefaultOrganizationID — организация, в которую перенесены подписки,
+// существовавшие до появления арендаторов.
+const DefaultOrganizationID = 1
+
+type organizationKey struct{}
+
+// organizationScope — арендатор запроса: одна организация или, для
+// платформенных ключей и фоновых задач, все сразу.
+type organizationScope struct {
+	id  int
+	all bool
+}
+
+// WithOrganization ограничивает запросы к подпискам из ctx организацией id.
+func WithOrganization(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, organizationKey{}, organizationScope{id: id})
+}
+
+// WithAllOrganizations снимает ограничение по арендатору. Только для
+// платформенных ключей и фоновых задач, которые обходят все организации.
+func WithAllOrganizations(ctx context.Context) context.Context {
+	return context.WithValue(ctx, organizationKey{}, organizationScope{all: true})
+}
+
+// OrganizationFrom возвращает арендатора из ctx. all — доступны все
+// организации, id при этом 0. Без арендатора — ErrNoOrganization.
+func OrganizationFrom(ctx context.Context) (id int, all bool, err error) {
+	scope, ok := ctx.Value(organizationKey{}).(organizationScope)
+	if !ok || (!scope.all && scope.id <= 0) {
+		return 0, false, ErrNoOrganization
+	}
+	return scope.id, scope.all, nil
+}
+
+// tenantScope — условие видимости подписки s для арендатора транзакции.
+// Дублирует политику RLS, чтобы изоляция держалась и под ролью, которая
+// обходит RLS (суперпользователь, BYPASSRLS).
+const tenantScope = `{tenant_visible}(s.organization_id)`
+
+// inTenant выполняет fn в транзакции, для которой задан арендатор из ctx:
+// на неё опираются и политики RLS, и условие tenantScope.
+func inTenant(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setTenant задаёт арендатора уже открытой транзакции. Настройка живёт до
+// конца транзакции и не переходит на следующий запрос из пула.
+func setTenant(ctx context.Context, tx *sql.Tx) error {
+	id, all, err := OrganizationFrom(ctx)
+	if err != nil {
+		return err
+	}
+	setting := strconv.Itoa(id)
+	if all {
+		setting = "*"
+	}
+	_, err = tx.ExecContext(ctx, `SELECT set_config('app.organization_id', $1, true)`, setting)
+	return err
+}
+
+// insertOrganization выбирает организацию новой подписки: арендатора из
+// ctx, а при доступе ко всем — явно указанную в подписке.
+func insertOrganization(ctx context.Context, requested int) (int, error) {
+	id, all, err := OrganizationFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !all {
+		return id, nil
+	}
+	if requested <= 0 {
+		return 0, ErrNoOrganization
+	}
+	return requested, nil
+}
@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"subscription-service/internal/models"
+)
+
+// ErrNoRecipient возвращается каналом, которому некуда доставить напоминание.
+var ErrNoRecipient = errors.New("notify: no recipient for reminder")
+
+type Reminder struct {
+	Subscription models.Subscription `json:"subscription"`
+	ChargeDate   time.Time           `json:"charge_date"`
+	DaysLeft     int                 `json:"days_left"`
+	Email        string              `json:"email,omitempty"`
+}
+
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, r Reminder) error
+}
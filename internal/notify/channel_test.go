@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+)
+
+func testReminder(t *testing.T, email string) Reminder {
+	t.Helper()
+	sub := models.Subscription{ID: 7, ServiceName: "Netflix", Price: 500}
+	if err := sub.UserID.Set("60601fee-2bf1-4721-ae6f-7636e79a0cba"); err != nil {
+		t.Fatal(err)
+	}
+	return Reminder{
+		Subscription: sub,
+		ChargeDate:   time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC),
+		DaysLeft:     3,
+		Email:        email,
+	}
+}
+
+func TestWebhookChannel(t *testing.T) {
+	var got Reminder
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type: want application/json, got %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookChannel(srv.URL).Send(t.Context(), testReminder(t, "")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.Subscription.ID != 7 || got.DaysLeft != 3 || !got.ChargeDate.Equal(testReminder(t, "").ChargeDate) {
+		t.Fatalf("payload: unexpected reminder %+v", got)
+	}
+}
+
+func TestWebhookChannelRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := NewWebhookChannel(srv.URL).Send(t.Context(), testReminder(t, ""))
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("Send: want status error, got %v", err)
+	}
+}
+
+func TestSMTPChannel(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	type mail struct {
+		from, to, data string
+	}
+	received := make(chan mail, 1)
+	go serveSMTP(t, lis, func(from, to, data string) { received <- mail{from, to, data} })
+
+	addr := lis.Addr().(*net.TCPAddr)
+	ch := &SMTPChannel{Host: "127.0.0.1", Port: addr.Port, From: "billing@example.com"}
+	if err := ch.Send(t.Context(), testReminder(t, "user@example.com")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	select {
+	case m := <-received:
+		if m.from != "<billing@example.com>" || m.to != "<user@example.com>" {
+			t.Fatalf("envelope: want billing@example.com -> user@example.com, got %s -> %s", m.from, m.to)
+		}
+		for _, want := range []string{"To: user@example.com", "Netflix", "28.02.2025", "500 руб."} {
+			if !strings.Contains(m.data, want) {
+				t.Errorf("message does not contain %q:\n%s", want, m.data)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP server did not receive the message")
+	}
+}
+
+func TestSMTPChannelNoRecipient(t *testing.T) {
+	ch := &SMTPChannel{Host: "127.0.0.1", Port: 1, From: "billing@example.com"}
+	if err := ch.Send(t.Context(), testReminder(t, "")); !errors.Is(err, ErrNoRecipient) {
+		t.Fatalf("Send without email: want ErrNoRecipient, got %v", err)
+	}
+}
+
+func TestBuildMessageHeaders(t *testing.T) {
+	r := testReminder(t, "user@example.com")
+	r.Subscription.ServiceName = "Кино\r\nBcc: victim@example.com"
+
+	msg := string(buildMessage("billing@example.com", r))
+	header, _, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("message has no header terminator:\n%s", msg)
+	}
+
+	var subject string
+	for _, line := range strings.Split(header, "\r\n") {
+		if strings.HasPrefix(strings.ToLower(line), "bcc:") {
+			t.Fatalf("service name injected a header:\n%s", header)
+		}
+		if v, ok := strings.CutPrefix(line, "Subject: "); ok {
+			subject = v
+		}
+	}
+	for _, c := range subject {
+		if c > 127 {
+			t.Fatalf("Subject must be ASCII, got %q", subject)
+		}
+	}
+
+	decoded, err := new(mime.WordDecoder).DecodeHeader(subject)
+	if err != nil {
+		t.Fatalf("decode Subject %q: %v", subject, err)
+	}
+	if want := "Подписка Кино Bcc: victim@example.com продлится 28.02.2025"; decoded != want {
+		t.Fatalf("Subject: want %q, got %q", want, decoded)
+	}
+}
+
+// serveSMTP принимает одно письмо по минимальному подмножеству SMTP,
+// которого достаточно net/smtp без TLS и авторизации.
+func serveSMTP(t *testing.T, lis net.Listener, deliver func(from, to, data string)) {
+	conn, err := lis.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var from, to string
+	reply("220 localhost ESMTP test")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch verb := strings.ToUpper(strings.Fields(cmd + " ")[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			from = strings.TrimPrefix(cmd[len("MAIL FROM:"):], " ")
+			reply("250 OK")
+		case "RCPT":
+			to = strings.TrimPrefix(cmd[len("RCPT TO:"):], " ")
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					t.Errorf("read DATA: %v", err)
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			deliver(from, to, data.String())
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"errors"
+	"subscription-service/internal/config"
+	"subscription-service/internal/models"
+)
+
+var ErrNoChannels = errors.New("no notification channels configured: set notifier.smtp.host and/or notifier.webhook_url")
+
+// NewScheduler собирает планировщик по секции notifier конфигурации.
+func NewScheduler(m models.Models, cfg config.NotifierConfig) (*Scheduler, error) {
+	var channels []Channel
+	if cfg.SMTP.Host != "" {
+		channels = append(channels, &SMTPChannel{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.User,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		})
+	}
+	if cfg.WebhookURL != "" {
+		channels = append(channels, NewWebhookChannel(cfg.WebhookURL))
+	}
+	if len(channels) == 0 {
+		return nil, ErrNoChannels
+	}
+
+	return &Scheduler{
+		Models:    m,
+		Channels:  channels,
+		DaysAhead: cfg.DaysAhead,
+		Interval:  cfg.Interval,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"subscription-service/internal/models"
+)
+
+type Scheduler struct {
+	Models    models.Models
+	Channels  []Channel
+	DaysAhead int
+	Interval  time.Duration
+	Now       func() time.Time
+}
+
+// Run проверяет подписки сразу при старте, а затем раз в Interval,
+// пока не будет отменён ctx.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil {
+			slog.Error("notifier pass failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce делает один проход по активным подпискам всех организаций.
+// Проход не может длиться дольше Interval, чтобы не наложиться на
+// следующий.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(models.WithAllOrganizations(ctx), s.Interval)
+	defer cancel()
+
+	today := s.now()
+
+	subs, err := s.Models.Subscriptions.GetActive(ctx, today)
+	if err != nil {
+		return err
+	}
+
+	horizon := today.AddDate(0, 0, s.DaysAhead)
+	for _, sub := range subs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		charge, ok := sub.NextChargeDate(today)
+		if !ok || charge.After(horizon) {
+			continue
+		}
+
+		// Адрес пользователя свой в каждой организации.
+		email, err := s.Models.Reminders.ContactEmail(models.WithOrganization(ctx, sub.OrganizationID), sub.UserID)
+		if err != nil {
+			return err
+		}
+
+		r := Reminder{
+			Subscription: *sub,
+			ChargeDate:   charge,
+			DaysLeft:     int(charge.Sub(today).Hours() / 24),
+			Email:        email,
+		}
+		for _, ch := range s.Channels {
+			s.deliver(ctx, ch, r)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) deliver(ctx context.Context, ch Channel, r Reminder) {
+	subID := r.Subscription.ID
+
+	claimed, err := s.Models.Reminders.Claim(ctx, subID, r.ChargeDate, ch.Name())
+	if err != nil {
+		slog.Error("claim reminder", "subscription_id", subID, "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	err = ch.Send(ctx, r)
+	if err == nil {
+		return
+	}
+
+	if !errors.Is(err, ErrNoRecipient) {
+		slog.Error("send reminder", "channel", ch.Name(), "subscription_id", subID, "error", err)
+	}
+	// Освобождаем запись даже при отменённом ctx, иначе напоминание
+	// больше никогда не будет отправлено.
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	if err := s.Models.Reminders.Release(releaseCtx, subID, r.ChargeDate, ch.Name()); err != nil {
+		slog.Error("release reminder", "subscription_id", subID, "error", err)
+	}
+}
+
+func (s *Scheduler) now() time.Time {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	t := now()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
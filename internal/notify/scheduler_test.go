@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+)
+
+// recordingChannel запоминает доставленные напоминания; пока fail не
+// пуст, Send возвращает его.
+type recordingChannel struct {
+	mu   sync.Mutex
+	sent []Reminder
+	fail error
+}
+
+func (ch *recordingChannel) Name() string {
+	return "recording"
+}
+
+func (ch *recordingChannel) Send(ctx context.Context, r Reminder) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.fail != nil {
+		return ch.fail
+	}
+	ch.sent = append(ch.sent, r)
+	return nil
+}
+
+func (ch *recordingChannel) chargeDates() []string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	dates := []string{}
+	for _, r := range ch.sent {
+		dates = append(dates, r.ChargeDate.Format(time.DateOnly))
+	}
+	return dates
+}
+
+func newTestScheduler(t *testing.T, ch Channel) (*Scheduler, *time.Time) {
+	t.Helper()
+	subs := models.NewMemorySubscriptionDB()
+	reminders := models.NewMemoryReminderDB(subs)
+
+	sub := testReminder(t, "").Subscription
+	sub.ID = 0
+	sub.StartDate = time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)
+	ctx := models.WithOrganization(t.Context(), models.DefaultOrganizationID)
+	if err := subs.Insert(ctx, &sub); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := reminders.SetContactEmail(ctx, sub.UserID, "user@example.com"); err != nil {
+		t.Fatalf("SetContactEmail: %v", err)
+	}
+
+	today := new(time.Time)
+	return &Scheduler{
+		Models:    models.Models{Subscriptions: subs, Reminders: reminders},
+		Channels:  []Channel{ch},
+		DaysAhead: 3,
+		Interval:  time.Minute,
+		Now:       func() time.Time { return *today },
+	}, today
+}
+
+func TestSchedulerSendsOncePerChargeDate(t *testing.T) {
+	ch := &recordingChannel{}
+	s, today := newTestScheduler(t, ch)
+
+	// Списание 28.02 (31-е число, зажатое к концу февраля) попадает в
+	// горизонт с 25.02 по 28.02 включительно: каждый из этих проходов, в
+	// том числе повторный в тот же день, видит ту же дату списания.
+	for _, day := range []string{"2025-02-24", "2025-02-25", "2025-02-25", "2025-02-27", "2025-02-28", "2025-03-01", "2025-03-28"} {
+		*today, _ = time.Parse(time.DateOnly, day)
+		if err := s.RunOnce(t.Context()); err != nil {
+			t.Fatalf("RunOnce on %s: %v", day, err)
+		}
+	}
+
+	got := ch.chargeDates()
+	want := []string{"2025-02-28", "2025-03-31"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("reminders: want one per charge date %v, got %v", want, got)
+	}
+	if email := ch.sent[0].Email; email != "user@example.com" {
+		t.Fatalf("email: want user@example.com, got %q", email)
+	}
+}
+
+func TestSchedulerRetriesFailedDelivery(t *testing.T) {
+	ch := &recordingChannel{fail: errors.New("smtp is down")}
+	s, today := newTestScheduler(t, ch)
+	*today = time.Date(2025, time.February, 26, 0, 0, 0, 0, time.UTC)
+
+	if err := s.RunOnce(t.Context()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := ch.chargeDates(); len(got) != 0 {
+		t.Fatalf("failed delivery must not be recorded, got %v", got)
+	}
+
+	// Неудачная отправка освобождает запись, и следующий проход
+	// повторяет её — один раз.
+	ch.fail = nil
+	for range 2 {
+		if err := s.RunOnce(t.Context()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+	if got := ch.chargeDates(); len(got) != 1 || got[0] != "2025-02-28" {
+		t.Fatalf("retry: want a single reminder for 2025-02-28, got %v", got)
+	}
+}
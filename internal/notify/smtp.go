@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+type SMTPChannel struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (ch *SMTPChannel) Name() string {
+	return "email"
+}
+
+func (ch *SMTPChannel) Send(ctx context.Context, r Reminder) error {
+	if r.Email == "" {
+		return ErrNoRecipient
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Без логина работаем без авторизации — так удобно для локальных
+	// SMTP-заглушек вроде MailHog.
+	var auth smtp.Auth
+	if ch.Username != "" {
+		auth = smtp.PlainAuth("", ch.Username, ch.Password, ch.Host)
+	}
+
+	addr := net.JoinHostPort(ch.Host, strconv.Itoa(ch.Port))
+	return smtp.SendMail(addr, auth, ch.From, []string{r.Email}, buildMessage(ch.From, r))
+}
+
+// buildMessage собирает письмо. Тема кодируется по RFC 2047: заголовок
+// допускает только ASCII. Переводы строк из значений заголовков
+// убираются, иначе название сервиса могло бы дописать свои заголовки.
+func buildMessage(from string, r Reminder) []byte {
+	sub := r.Subscription
+	subject := fmt.Sprintf("Подписка %s продлится %s", sub.ServiceName, r.ChargeDate.Format("02.01.2006"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(r.Email))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(subject)))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	fmt.Fprintf(&b, "Через %d дн. (%s) подписка %s будет продлена за %d руб.\r\n",
+		r.DaysLeft, r.ChargeDate.Format("02.01.2006"), sub.ServiceName, sub.Price)
+	return []byte(b.String())
+}
+
+var headerNewlines = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+func headerValue(s string) string {
+	return headerNewlines.Replace(s)
+}
@@ -0,0 +1,121 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// service — строка каталога, из которого генератор выбирает подписки.
+type service struct {
+	name  string
+	price int
+}
+
+var catalog = []service{
+	{"Yandex Plus", 399},
+	{"Kinopoisk", 299},
+	{"Okko", 399},
+	{"ivi", 399},
+	{"VK Music", 199},
+	{"Spotify Premium", 299},
+	{"Apple Music", 199},
+	{"YouTube Premium", 299},
+	{"Netflix", 699},
+	{"Amazon Prime", 350},
+	{"iCloud+", 149},
+	{"Google One", 139},
+	{"Telegram Premium", 299},
+	{"Dropbox Plus", 990},
+	{"ChatGPT Plus", 1990},
+}
+
+// GenerateOptions управляет синтетическим генератором. Один и тот же Seed
+// и Now дают один и тот же набор.
+type GenerateOptions struct {
+	Users  int
+	Months int
+	Seed   uint64
+	Now    time.Time
+}
+
+// Generate создаёт пользователей с правдоподобной историей: от одной до
+// шести подписок, начатых в пределах Months месяцев, часть из них уже
+// закончилась, у части менялась цена, у части пользователей есть бюджет.
+func Generate(opts GenerateOptions) Fixture {
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	now := time.Date(opts.Now.Year(), opts.Now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	f := Fixture{Users: make([]User, 0, opts.Users)}
+	for i := 0; i < opts.Users; i++ {
+		u := User{
+			ID:    randomUUID(rng),
+			Email: fmt.Sprintf("user%d@example.com", i+1),
+		}
+
+		monthly := 0
+		for _, idx := range rng.Perm(len(catalog))[:1+rng.IntN(6)] {
+			svc := catalog[idx]
+			start := now.AddDate(0, -rng.IntN(opts.Months+1), 0)
+			price := jitter(rng, svc.price)
+
+			sub := SubscriptionSeed{
+				ServiceName: svc.name,
+				Price:       price,
+				StartDate:   Date{start},
+			}
+
+			// Около трети подписок уже отменены: последний день месяца
+			// где-то между стартом и текущим месяцем.
+			lastMonth := now
+			if elapsed := monthsBetween(start, now); elapsed > 0 && rng.IntN(3) == 0 {
+				lastMonth = start.AddDate(0, rng.IntN(elapsed), 0)
+				sub.EndDate = &Date{lastMonth.AddDate(0, 1, -1)}
+			}
+
+			// У каждой пятой подписки цена повышалась, пока она была активна.
+			if active := monthsBetween(start, lastMonth); active > 0 && rng.IntN(5) == 0 {
+				sub.PriceChanges = []PriceChange{{
+					EffectiveDate: Date{start.AddDate(0, 1+rng.IntN(active), 0)},
+					Price:         roundTo(price*(110+rng.IntN(11))/100, 10) - 1,
+				}}
+			}
+
+			if sub.EndDate == nil {
+				monthly += price
+			}
+			u.Subscriptions = append(u.Subscriptions, sub)
+		}
+
+		if monthly > 0 && rng.IntN(5) < 2 {
+			u.Budgets = []Budget{{MonthlyLimit: roundTo(monthly*6/5, 100)}}
+		}
+
+		f.Users = append(f.Users, u)
+	}
+	return f
+}
+
+// jitter отклоняет цену каталога на ±10% и приводит её к виду 299/349.
+func jitter(rng *rand.Rand, price int) int {
+	p := price * (90 + rng.IntN(21)) / 100
+	return max(roundTo(p, 50)-1, 49)
+}
+
+func roundTo(n, step int) int {
+	return (n + step/2) / step * step
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+func randomUUID(rng *rand.Rand) string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(rng.UintN(256))
+	}
+	b[6] = b[6]&0x0f | 0x40 // версия 4
+	b[8] = b[8]&0x3f | 0x80 // вариант RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,203 @@
+// Package seed наполняет базу тестовыми данными: именованными наборами
+// fixtures из YAML/JSON или синтетическими пользователями. Схему он не
+// трогает — это работа миграций.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"subscription-service/internal/models"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures/*.yaml
+var fixtures embed.FS
+
+// Fixture — набор данных, сгруппированный по пользователям.
+type Fixture struct {
+	Users []User `yaml:"users" json:"users"`
+}
+
+type User struct {
+	ID            string             `yaml:"id" json:"id"`
+	Email         string             `yaml:"email,omitempty" json:"email,omitempty"`
+	Budgets       []Budget           `yaml:"budgets,omitempty" json:"budgets,omitempty"`
+	Subscriptions []SubscriptionSeed `yaml:"subscriptions" json:"subscriptions"`
+}
+
+type Budget struct {
+	Category     string `yaml:"category,omitempty" json:"category,omitempty"`
+	MonthlyLimit int    `yaml:"monthly_limit" json:"monthly_limit"`
+}
+
+type SubscriptionSeed struct {
+	ServiceName  string        `yaml:"service_name" json:"service_name"`
+	Price        int           `yaml:"price" json:"price"`
+	StartDate    Date          `yaml:"start_date" json:"start_date"`
+	EndDate      *Date         `yaml:"end_date,omitempty" json:"end_date,omitempty"`
+	Tags         []string      `yaml:"tags,omitempty" json:"tags,omitempty"`
+	PriceChanges []PriceChange `yaml:"price_changes,omitempty" json:"price_changes,omitempty"`
+}
+
+type PriceChange struct {
+	EffectiveDate Date `yaml:"effective_date" json:"effective_date"`
+	Price         int  `yaml:"price" json:"price"`
+}
+
+// Date — дата в формате 2006-01-02 и в YAML, и в JSON.
+type Date struct {
+	time.Time
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.Format(time.DateOnly)), nil
+}
+
+func (d *Date) UnmarshalText(text []byte) error {
+	t, err := time.Parse(time.DateOnly, string(text))
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", text)
+	}
+	d.Time = t
+	return nil
+}
+
+// Names перечисляет встроенные наборы fixtures.
+func Names() ([]string, error) {
+	entries, err := fs.ReadDir(fixtures, "fixtures")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), path.Ext(e.Name())))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load загружает набор по имени встроенного fixture или по пути к файлу
+// .yaml, .yml или .json. Неизвестные поля — ошибка, а не молчаливый пропуск.
+func Load(nameOrPath string) (Fixture, error) {
+	var f Fixture
+
+	ext := filepath.Ext(nameOrPath)
+	var data []byte
+	var err error
+	if ext == "" {
+		data, err = fixtures.ReadFile("fixtures/" + nameOrPath + ".yaml")
+		if errors.Is(err, fs.ErrNotExist) {
+			names, _ := Names()
+			return f, fmt.Errorf("unknown fixture set %q, available: %s", nameOrPath, strings.Join(names, ", "))
+		}
+		ext = ".yaml"
+	} else {
+		data, err = os.ReadFile(nameOrPath)
+	}
+	if err != nil {
+		return f, err
+	}
+
+	switch ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	default:
+		return f, fmt.Errorf("unsupported fixture format %q", ext)
+	}
+	if err != nil {
+		return f, fmt.Errorf("parse fixture %s: %w", nameOrPath, err)
+	}
+	return f, nil
+}
+
+// Stats — итог загрузки.
+type Stats struct {
+	Users         int
+	Subscriptions int
+	Skipped       int
+	PriceChanges  int
+	Budgets       int
+}
+
+// Apply записывает набор в базу. Подписки, которые уже есть у
+// пользователя, пропускаются вместе со сменами цены, так что повторный
+// запуск безопасен.
+func Apply(ctx context.Context, m models.Models, f Fixture) (Stats, error) {
+	var stats Stats
+
+	for _, u := range f.Users {
+		var uid uuid.UUID
+		if err := uid.Scan(u.ID); err != nil {
+			return stats, fmt.Errorf("user %q: invalid id: %w", u.ID, err)
+		}
+		stats.Users++
+
+		if u.Email != "" {
+			if err := m.Reminders.SetContactEmail(ctx, uid, u.Email); err != nil {
+				return stats, fmt.Errorf("user %s: contact: %w", u.ID, err)
+			}
+		}
+
+		for _, b := range u.Budgets {
+			budget := &models.Budget{UserID: uid, Category: b.Category, MonthlyLimit: b.MonthlyLimit}
+			if err := m.Budgets.Upsert(ctx, budget); err != nil {
+				return stats, fmt.Errorf("user %s: budget %q: %w", u.ID, b.Category, err)
+			}
+			stats.Budgets++
+		}
+
+		for _, s := range u.Subscriptions {
+			sub := &models.Subscription{
+				ServiceName: s.ServiceName,
+				Price:       s.Price,
+				UserID:      uid,
+				StartDate:   s.StartDate.Time,
+				Tags:        s.Tags,
+			}
+			if s.EndDate != nil {
+				end := s.EndDate.Time
+				sub.EndDate = &end
+			}
+
+			err := m.Subscriptions.Insert(ctx, sub)
+			if errors.Is(err, models.ErrDuplicateSubscription) {
+				stats.Skipped++
+				continue
+			}
+			if err != nil {
+				return stats, fmt.Errorf("user %s: subscription %q: %w", u.ID, s.ServiceName, err)
+			}
+			stats.Subscriptions++
+
+			for _, pc := range s.PriceChanges {
+				change := &models.PriceChange{SubscriptionID: sub.ID, EffectiveDate: pc.EffectiveDate.Time, Price: pc.Price}
+				if err := m.PriceChanges.Upsert(ctx, change); err != nil {
+					return stats, fmt.Errorf("user %s: price change for %q: %w", u.ID, s.ServiceName, err)
+				}
+				stats.PriceChanges++
+			}
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"subscription-service/internal/models"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// subscriptions оборачивает SubscriptionRepository и создаёт спан на каждый
+// вызов с именем операции и числом возвращённых строк.
+type subscriptions struct {
+	next   models.SubscriptionRepository
+	tracer trace.Tracer
+}
+
+func InstrumentSubscriptions(next models.SubscriptionRepository) models.SubscriptionRepository {
+	return &subscriptions{next: next, tracer: otel.Tracer(instrumentationName)}
+}
+
+func (r *subscriptions) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "SubscriptionRepository."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(method),
+		),
+	)
+}
+
+// end завершает спан; указатели позволяют вызывать его через defer.
+func end(span trace.Span, rows *int, err *error) {
+	if rows != nil {
+		span.SetAttributes(semconv.DBResponseReturnedRows(*rows))
+	}
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+func (r *subscriptions) GetAll(ctx context.Context) (subs []*models.Subscription, err error) {
+	ctx, span := r.start(ctx, "GetAll")
+	var rows int
+	defer end(span, &rows, &err)
+
+	subs, err = r.next.GetAll(ctx)
+	rows = len(subs)
+	return subs, err
+}
+
+func (r *subscriptions) Get(ctx context.Context, id int) (sub *models.Subscription, err error) {
+	ctx, span := r.start(ctx, "Get")
+	var rows int
+	defer end(span, &rows, &err)
+
+	sub, err = r.next.Get(ctx, id)
+	if sub != nil {
+		rows = 1
+	}
+	return sub, err
+}
+
+func (r *subscriptions) GetByUserID(ctx context.Context, uid uuid.UUID) (subs []*models.Subscription, err error) {
+	ctx, span := r.start(ctx, "GetByUserID")
+	var rows int
+	defer end(span, &rows, &err)
+
+	subs, err = r.next.GetByUserID(ctx, uid)
+	rows = len(subs)
+	return subs, err
+}
+
+func (r *subscriptions) GetByUserSubscription(ctx context.Context, serviceName string) (subs []*models.Subscription, err error) {
+	ctx, span := r.start(ctx, "GetByUserSubscription")
+	var rows int
+	defer end(span, &rows, &err)
+
+	subs, err = r.next.GetByUserSubscription(ctx, serviceName)
+	rows = len(subs)
+	return subs, err
+}
+
+func (r *subscriptions) GetActive(ctx context.Context, at time.Time) (subs []*models.Subscription, err error) {
+	ctx, span := r.start(ctx, "GetActive")
+	var rows int
+	defer end(span, &rows, &err)
+
+	subs, err = r.next.GetActive(ctx, at)
+	rows = len(subs)
+	return subs, err
+}
+
+func (r *subscriptions) Find(ctx context.Context, filter models.SubscriptionFilter) (subs []*models.Subscription, err error) {
+	ctx, span := r.start(ctx, "Find")
+	var rows int
+	defer end(span, &rows, &err)
+
+	subs, err = r.next.Find(ctx, filter)
+	rows = len(subs)
+	return subs, err
+}
+
+func (r *subscriptions) UserIDs(ctx context.Context, limit int) (uids []uuid.UUID, err error) {
+	ctx, span := r.start(ctx, "UserIDs")
+	var rows int
+	defer end(span, &rows, &err)
+
+	uids, err = r.next.UserIDs(ctx, limit)
+	rows = len(uids)
+	return uids, err
+}
+
+func (r *subscriptions) Insert(ctx context.Context, sub *models.Subscription) (err error) {
+	ctx, span := r.start(ctx, "Insert")
+	defer end(span, nil, &err)
+
+	return r.next.Insert(ctx, sub)
+}
+
+func (r *subscriptions) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := r.start(ctx, "Delete")
+	defer end(span, nil, &err)
+
+	return r.next.Delete(ctx, id)
+}
+
+func (r *subscriptions) DeleteByUserID(ctx context.Context, uid uuid.UUID) (err error) {
+	ctx, span := r.start(ctx, "DeleteByUserID")
+	defer end(span, nil, &err)
+
+	return r.next.DeleteByUserID(ctx, uid)
+}
+
+func (r *subscriptions) DeleteByServiceName(ctx context.Context, serviceName string) (err error) {
+	ctx, span := r.start(ctx, "DeleteByServiceName")
+	defer end(span, nil, &err)
+
+	return r.next.DeleteByServiceName(ctx, serviceName)
+}
+
+func (r *subscriptions) SetMembers(ctx context.Context, id int, members []models.SubscriptionMember) (err error) {
+	ctx, span := r.start(ctx, "SetMembers")
+	defer end(span, nil, &err)
+
+	return r.next.SetMembers(ctx, id, members)
+}
+
+func (r *subscriptions) Update(ctx context.Context, upd models.Subscription) (err error) {
+	ctx, span := r.start(ctx, "Update")
+	defer end(span, nil, &err)
+
+	return r.next.Update(ctx, upd)
+}
+
+func (r *subscriptions) GetSummary(ctx context.Context, from, to time.Time, filter models.SubscriptionFilter) (subs []*models.SubscriptionWithCost, total int, err error) {
+	ctx, span := r.start(ctx, "GetSummary")
+	var rows int
+	defer end(span, &rows, &err)
+
+	subs, total, err = r.next.GetSummary(ctx, from, to, filter)
+	rows = len(subs)
+	return subs, total, err
+}
@@ -0,0 +1,5 @@
+package subscriptionv1
+
+// Стабы пересобираются после правки subscription.proto; нужны protoc,
+// protoc-gen-go и protoc-gen-go-grpc в PATH.
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative subscription/v1/subscription.proto